@@ -0,0 +1,84 @@
+// Команда replay проверяет журнал дуэли: прогоняет записанные события через правила движка
+// и сравнивает победителя с тем, что записано в gunfight, а затем сверяет паузы перед сигналом с сидами игры.
+//
+//	go run ./cmd/replay -id 42
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"wildwest/internal/repository/postgres"
+	"wildwest/internal/service"
+	"wildwest/pkg/postgresconn"
+	"wildwest/pkg/settings"
+)
+
+func main() {
+	gunfightID := flag.Int("id", 0, "gunfight ID")
+	flag.Parse()
+
+	if *gunfightID <= 0 {
+		log.Fatal("gunfight ID is required: replay -id <gunfight ID>")
+	}
+
+	var config settings.Config
+	if err := config.ReadConfig(); err != nil {
+		log.Fatal(err)
+	}
+
+	postgresClient, err := postgresconn.NewPostgresClient(&config)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx := context.Background()
+	gunfightRepo := postgres.NewGunfightRepository(postgresClient)
+
+	game, err := gunfightRepo.Get(ctx, *gunfightID)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	game.Players, err = gunfightRepo.GetPlayers(ctx, *gunfightID)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	events, err := gunfightRepo.GetEvents(ctx, *gunfightID)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	winnerID, err := service.ReplayDuel(game, events)
+	if err != nil {
+		log.Fatalf("gunfight %d: replay failed: %v", *gunfightID, err)
+	}
+
+	if winner(winnerID) != winner(game.WinnerID) {
+		fmt.Printf("gunfight %d: MISMATCH, recorded winner %s, replayed winner %s\n", *gunfightID, winner(game.WinnerID), winner(winnerID))
+		os.Exit(1)
+	}
+
+	fmt.Printf("gunfight %d: OK, winner %s, %d events\n", *gunfightID, winner(winnerID), len(events))
+
+	// У игр до проверяемых розыгрышей сида нет, для них проверяется только журнал
+	if game.ServerSeed == "" {
+		return
+	}
+	draws, err := service.VerifyDraws(game, events)
+	if err != nil {
+		fmt.Printf("gunfight %d: DRAWS MISMATCH: %v\n", *gunfightID, err)
+		os.Exit(1)
+	}
+	fmt.Printf("gunfight %d: OK, %d draws match the seeds\n", *gunfightID, len(draws))
+}
+
+func winner(userID *int) string {
+	if userID == nil {
+		return "none"
+	}
+	return fmt.Sprint(*userID)
+}
@@ -15,9 +15,9 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/gunfight/find": {
+        "/gunfight/bounties": {
             "get": {
-                "description": "Opens a websocket connection and waits to match with an opponent for a gunfight.",
+                "description": "Fetches the open bounties sorted by gold, the largest first. Several bounties on the same player are listed separately and are all collected by the player who beats the target.",
                 "consumes": [
                     "application/json"
                 ],
@@ -27,41 +27,42 @@ const docTemplate = `{
                 "tags": [
                     "gunfight"
                 ],
-                "summary": "Initiate gunfight search",
+                "summary": "Retrieve Wanted board",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "User ID",
-                        "name": "user-id",
+                        "type": "string",
+                        "description": "User data in encoded format containing user ID and other necessary information",
+                        "name": "X-User-Data",
                         "in": "header",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "WebSocket connection established, waiting for opponent.",
+                        "description": "Returns the open bounties.",
                         "schema": {
-                            "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/gunfight.BountyItem"
+                            }
                         }
                     },
                     "400": {
-                        "description": "Could not open websocket connection",
+                        "description": "Bad request - user data is required or invalid.",
                         "schema": {
                             "type": "string"
                         }
                     },
                     "500": {
-                        "description": "Internal server error",
+                        "description": "Internal server error - error getting the bounties.",
                         "schema": {
                             "type": "string"
                         }
                     }
                 }
-            }
-        },
-        "/horse": {
-            "get": {
-                "description": "Fetches the horse's data and calculates its speed based on the user ID provided in the context.",
+            },
+            "post": {
+                "description": "Posts a bounty on the target player, the gold is taken from the balance of the user right away. The player who beats the target in a gunfight collects the bounty when the winner is recorded, in a team gunfight it is split between the winners. Only gunfights against an opponent from a queue or ranked gunfights claim bounties: challenges, rematches and gunfights against bots do not. An unclaimed bounty expires after the bounty TTL and the gold returns to the user.",
                 "consumes": [
                     "application/json"
                 ],
@@ -69,9 +70,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "horse"
+                    "gunfight"
                 ],
-                "summary": "Retrieve horse by user ID",
+                "summary": "Post bounty",
                 "parameters": [
                     {
                         "type": "string",
@@ -79,23 +80,37 @@ const docTemplate = `{
                         "name": "X-User-Data",
                         "in": "header",
                         "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of the wanted player",
+                        "name": "target_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Bounty in gold, at least the minimum bounty",
+                        "name": "gold",
+                        "in": "query",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Returns the horse along with its calculated speed.",
+                        "description": "Returns the posted bounty.",
                         "schema": {
-                            "$ref": "#/definitions/horse.BaseResponse"
+                            "$ref": "#/definitions/gunfight.BountyItem"
                         }
                     },
                     "400": {
-                        "description": "Bad request - user_id is required or invalid.",
+                        "description": "Bad request - user data is required or invalid, or target_id or gold is missing.",
                         "schema": {
                             "type": "string"
                         }
                     },
-                    "404": {
-                        "description": "Not found - no horse found for the user ID.",
+                    "500": {
+                        "description": "Internal server error - bounty on yourself, bounty below the minimum, target not found or not enough gold.",
                         "schema": {
                             "type": "string"
                         }
@@ -103,9 +118,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/horse/finish": {
-            "post": {
-                "description": "Completes the race for the horse and updates its record and earnings according to the distance covered, with the user ID extracted from the context.",
+        "/gunfight/challenge": {
+            "get": {
+                "description": "Opens a websocket connection and sends a private duel challenge to the player with the given user ID or invite link. Challenge duels skip the matchmaking queue, have no stake and do not change the rating. Bots can not be challenged, and neither player may be locked out of matchmaking for leaving a gunfight, which is checked again when the challenge is accepted. Every message is a gunfight.Message envelope: the server sends challenge_sent (gunfight.Challenge), then matched, declined, expired, error or cancelled; the client may send a cancel command to withdraw the challenge.",
                 "consumes": [
                     "application/json"
                 ],
@@ -113,9 +128,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "horse"
+                    "gunfight"
                 ],
-                "summary": "Finish horse race",
+                "summary": "Challenge a player",
                 "parameters": [
                     {
                         "type": "string",
@@ -125,36 +140,116 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "description": "Race completion details including the distance covered.",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
+                        "type": "integer",
+                        "description": "ID of the challenged player",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Invite link of the challenged player",
+                        "name": "link",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "WebSocket connection established, waiting for the reply.",
                         "schema": {
-                            "$ref": "#/definitions/horse.GameRequest"
+                            "$ref": "#/definitions/gunfight.Message"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - user_id or link is required.",
+                        "schema": {
+                            "type": "string"
                         }
                     }
+                }
+            }
+        },
+        "/gunfight/challenges": {
+            "get": {
+                "description": "Opens a websocket connection that delivers pending and new duel challenges as challenge messages with a gunfight.Challenge payload. The client answers with accept or decline commands carrying a gunfight.ChallengeCommand payload; an accepted challenge is answered with a matched message, a declined one with a declined message.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "gunfight"
+                ],
+                "summary": "Listen for challenges",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User data in encoded format containing user ID and other necessary information",
+                        "name": "X-User-Data",
+                        "in": "header",
+                        "required": true
+                    }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Returns the results of the race finish with updated horse data and earnings.",
+                        "description": "WebSocket connection established, challenges follow.",
                         "schema": {
-                            "$ref": "#/definitions/horse.GameResponse"
+                            "$ref": "#/definitions/gunfight.Message"
                         }
                     },
                     "400": {
-                        "description": "Bad request - user data is required or invalid, or the request body is malformed.",
+                        "description": "Bad request - user data is required or invalid.",
                         "schema": {
                             "type": "string"
                         }
+                    }
+                }
+            }
+        },
+        "/gunfight/find": {
+            "get": {
+                "description": "Opens a websocket connection and waits to match with an opponent for a gunfight in the given queue. Every queue has its own stake, rating rules, minimum balance, search bands, timeout and house fee: casual games have no stake and do not change the rating. Every message is a gunfight.Message envelope: the server sends queued, searching (current rating band), queue (gunfight.QueueStatus, every few seconds while waiting), matched, timeout, error and cancelled messages, the client may send a cancel command. If bots are enabled and no player is found within the bot wait, the player is matched with a bot of matching strength: such a matched message has bot set, the game is free and unranked.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "gunfight"
+                ],
+                "summary": "Initiate gunfight search",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "user-id",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Matchmaking queue, one of the configured queues such as casual, ranked or high_stakes",
+                        "name": "queue",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "WebSocket connection established, waiting for opponent.",
+                        "schema": {
+                            "$ref": "#/definitions/gunfight.Message"
+                        }
                     },
-                    "404": {
-                        "description": "Not found - no horse found to finish race for the user ID.",
+                    "400": {
+                        "description": "Could not open websocket connection",
                         "schema": {
                             "type": "string"
                         }
                     },
                     "500": {
-                        "description": "Internal server error - error during the race finish process.",
+                        "description": "Internal server error",
                         "schema": {
                             "type": "string"
                         }
@@ -162,9 +257,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/horse/upgrade": {
+        "/gunfight/history": {
             "get": {
-                "description": "Increases the horse's level using the user ID extracted from the context.",
+                "description": "Fetches a page of finished gunfights, team gunfights included. Pass next_cursor from the previous page as cursor to get the next one.",
                 "consumes": [
                     "application/json"
                 ],
@@ -172,9 +267,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "horse"
+                    "gunfight"
                 ],
-                "summary": "Upgrade horse level",
+                "summary": "Retrieve gunfight history",
                 "parameters": [
                     {
                         "type": "string",
@@ -182,30 +277,35 @@ const docTemplate = `{
                         "name": "X-User-Data",
                         "in": "header",
                         "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of the last gunfight of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size, 20 by default and 100 at most",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Horse upgraded successfully with new level information.",
+                        "description": "Returns a page of the gunfight history.",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/gunfight.HistoryResponse"
                         }
                     },
                     "400": {
-                        "description": "Bad request - user data is required or invalid.",
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    "404": {
-                        "description": "Not found - no horse found to upgrade for the user ID.",
+                        "description": "Bad request - user data is required or invalid, or invalid cursor or limit.",
                         "schema": {
                             "type": "string"
                         }
                     },
                     "500": {
-                        "description": "Internal server error - error during the upgrade process.",
+                        "description": "Internal server error - error getting the history.",
                         "schema": {
                             "type": "string"
                         }
@@ -213,9 +313,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/money": {
+        "/gunfight/party": {
             "get": {
-                "description": "Fetches the money details associated with the provided user ID.",
+                "description": "Fetches the party of the user, null if the user is not in a party, and the invitations to other parties that have not expired yet.",
                 "consumes": [
                     "application/json"
                 ],
@@ -223,9 +323,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "money"
+                    "gunfight"
                 ],
-                "summary": "Retrieve money record",
+                "summary": "Retrieve party",
                 "parameters": [
                     {
                         "type": "string",
@@ -237,19 +337,19 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "Returns the money details for the specified user ID.",
+                        "description": "Returns the party and the invitations.",
                         "schema": {
-                            "$ref": "#/definitions/money.BaseResponse"
+                            "$ref": "#/definitions/gunfight.PartyResponse"
                         }
                     },
                     "400": {
-                        "description": "Bad request - user_id is required or invalid.",
+                        "description": "Bad request - user data is required or invalid.",
                         "schema": {
                             "type": "string"
                         }
                     },
-                    "404": {
-                        "description": "Not found - no money record found for the user ID.",
+                    "500": {
+                        "description": "Internal server error - error getting the party.",
                         "schema": {
                             "type": "string"
                         }
@@ -257,9 +357,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/user": {
-            "get": {
-                "description": "check user and his actual data.",
+        "/gunfight/party/accept": {
+            "post": {
+                "description": "Joins the party the user was invited to. The invitation is used up even if joining fails: the user is already in a party, the party is disbanded, full or searching for a gunfight.",
                 "consumes": [
                     "application/json"
                 ],
@@ -267,9 +367,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "gunfight"
                 ],
-                "summary": "Check user and his actual data",
+                "summary": "Accept party invite",
                 "parameters": [
                     {
                         "type": "string",
@@ -277,76 +377,2049 @@ const docTemplate = `{
                         "name": "X-User-Data",
                         "in": "header",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Party ID from the invitation",
+                        "name": "party_id",
+                        "in": "query",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Returns the user details.",
+                        "description": "Returns the party.",
                         "schema": {
-                            "$ref": "#/definitions/user.BaseResponse"
+                            "$ref": "#/definitions/gunfight.Party"
                         }
                     },
                     "400": {
-                        "description": "Bad request - invalid request body.",
+                        "description": "Bad request - user data is required or invalid, or party_id is missing.",
                         "schema": {
                             "type": "string"
                         }
                     },
                     "500": {
-                        "description": "Internal server error - failed to create or update user.",
+                        "description": "Internal server error - invitation not found or expired, or error joining the party.",
                         "schema": {
                             "type": "string"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "horse.BaseResponse": {
-            "description": "This is a horse model",
-            "type": "object",
-            "properties": {
-                "level": {
-                    "type": "integer",
-                    "x-order": "2",
-                    "example": 100
-                },
-                "distance": {
-                    "type": "integer",
-                    "x-order": "3",
-                    "example": 50
-                },
-                "speed": {
-                    "type": "integer",
-                    "x-order": "4",
-                    "example": 2000
-                }
-            }
         },
-        "horse.GameRequest": {
-            "type": "object",
-            "properties": {
-                "distance": {
-                    "type": "integer",
-                    "x-order": "1"
+        "/gunfight/party/find": {
+            "get": {
+                "description": "Opens a websocket connection and searches for another party of two in the given queue. The party leader runs the search: the stake of the queue is held from every member and the search band is built around the average party rating. Other party members open the same websocket before the leader and wait for the matched message. Every message is a gunfight.Message envelope: the server sends queued, searching (current rating band, leader only), matched (with teammates and opponents), timeout, error and cancelled messages, the client may send a cancel command. There are no bots in team gunfights. In the duel each team shares a health pool of 3 per player, the fastest shot of a team counts as the team's shot and a foul costs the whole team a hit.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "gunfight"
+                ],
+                "summary": "Initiate team gunfight search",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User data in encoded format containing user ID and other necessary information",
+                        "name": "X-User-Data",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Matchmaking queue, one of the configured queues such as casual, ranked or high_stakes",
+                        "name": "queue",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "WebSocket connection established, waiting for opponents.",
+                        "schema": {
+                            "$ref": "#/definitions/gunfight.Message"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - user data is required or invalid, or queue is missing.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
                 }
             }
         },
-        "horse.GameResponse": {
-            "type": "object",
-            "properties": {
-                "earned": {
-                    "type": "integer",
-                    "x-order": "1"
-                },
-                "record": {
-                    "type": "boolean",
-                    "x-order": "2"
-                },
-                "distance": {
-                    "type": "integer",
-                    "x-order": "3"
+        "/gunfight/party/invite": {
+            "post": {
+                "description": "Invites the player with the given user ID to the party of the user, the party is created with the user as the leader if the user is not in a party yet. Only the leader invites players, a party holds 2 players. The invitation expires after the party invite TTL.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "gunfight"
+                ],
+                "summary": "Invite to party",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User data in encoded format containing user ID and other necessary information",
+                        "name": "X-User-Data",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of the invited player",
+                        "name": "user_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns the party.",
+                        "schema": {
+                            "$ref": "#/definitions/gunfight.Party"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - user data is required or invalid, or user_id is missing.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error - user is not the leader, the party is full or error inviting the player.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/gunfight/party/leave": {
+            "post": {
+                "description": "Leaves the party of the user, the leader leaving disbands the party. A party can not be left while it is searching for a gunfight.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "gunfight"
+                ],
+                "summary": "Leave party",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User data in encoded format containing user ID and other necessary information",
+                        "name": "X-User-Data",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "The user left the party."
+                    },
+                    "400": {
+                        "description": "Bad request - user data is required or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error - user is not in a party, the party is searching or error leaving the party.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/gunfight/queue": {
+            "get": {
+                "description": "Fetches the number of players waiting in the queue, in the user's rating band and in total, and the estimated wait based on recent matches in this queue.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "gunfight"
+                ],
+                "summary": "Retrieve gunfight queue status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User data in encoded format containing user ID and other necessary information",
+                        "name": "X-User-Data",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Matchmaking queue, one of the configured queues",
+                        "name": "queue",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns the queue status.",
+                        "schema": {
+                            "$ref": "#/definitions/gunfight.QueueStatus"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - user data is required or invalid, or queue is missing.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error - unknown queue or error getting the queue status.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/gunfight/queues": {
+            "get": {
+                "description": "Fetches every matchmaking queue with its settings, the number of waiting players, counters of searches that ended with a match, a bot, a timeout or a cancel, and the median wait of recent matches.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "gunfight"
+                ],
+                "summary": "Retrieve gunfight queues",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User data in encoded format containing user ID and other necessary information",
+                        "name": "X-User-Data",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns the queues.",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/gunfight.QueueMetrics"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - user data is required or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error - error getting the queue metrics.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/gunfight/rating": {
+            "get": {
+                "description": "Fetches the Elo rating used for ranked matchmaking and the number of rated games.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "gunfight"
+                ],
+                "summary": "Retrieve gunfight rating",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User data in encoded format containing user ID and other necessary information",
+                        "name": "X-User-Data",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns the rating of the user.",
+                        "schema": {
+                            "$ref": "#/definitions/gunfight.RatingResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - user data is required or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error - error getting the rating.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/gunfight/stats": {
+            "get": {
+                "description": "Fetches wins, losses, win rate, current streak and the most faced opponent. Team gunfights count like in the history: a game is won when the team of the user wins, and the opponent of a team gunfight is the captain of the other team.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "gunfight"
+                ],
+                "summary": "Retrieve gunfight statistics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User data in encoded format containing user ID and other necessary information",
+                        "name": "X-User-Data",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns the statistics of the user.",
+                        "schema": {
+                            "$ref": "#/definitions/gunfight.StatsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - user data is required or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error - error getting the statistics.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/gunfight/tournaments": {
+            "get": {
+                "description": "Fetches the single-elimination tournaments that are open for registration or running, ordered by start date. Tournaments are scheduled automatically, registration closes at the start date.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "gunfight"
+                ],
+                "summary": "Retrieve gunfight tournaments",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User data in encoded format containing user ID and other necessary information",
+                        "name": "X-User-Data",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns the tournaments.",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/gunfight.TournamentItem"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - user data is required or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error - error getting the tournaments.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/gunfight/tournaments/{id}": {
+            "get": {
+                "description": "Fetches the tournament, its players with seeds and prizes, and the matches of every started round. Players are seeded by rating, a match without an opponent or whose opponent did not show up is a walkover.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "gunfight"
+                ],
+                "summary": "Retrieve tournament bracket",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User data in encoded format containing user ID and other necessary information",
+                        "name": "X-User-Data",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Tournament ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns the bracket.",
+                        "schema": {
+                            "$ref": "#/definitions/gunfight.BracketResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid tournament ID.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error - tournament not found or error getting the bracket.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/gunfight/tournaments/{id}/register": {
+            "post": {
+                "description": "Registers the user for the tournament and deducts the entry fee in gold. Registration is open until the start date while there are places left.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "gunfight"
+                ],
+                "summary": "Register for tournament",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User data in encoded format containing user ID and other necessary information",
+                        "name": "X-User-Data",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Tournament ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "The user is registered."
+                    },
+                    "400": {
+                        "description": "Bad request - invalid tournament ID.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error - registration is closed, no places left, not enough gold or the user is already registered.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/gunfight/tournaments/{id}/unregister": {
+            "post": {
+                "description": "Withdraws the user from the tournament before the start date and refunds the entry fee.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "gunfight"
+                ],
+                "summary": "Unregister from tournament",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User data in encoded format containing user ID and other necessary information",
+                        "name": "X-User-Data",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Tournament ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "The user is unregistered."
+                    },
+                    "400": {
+                        "description": "Bad request - invalid tournament ID.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error - registration is closed, the user is not registered or error refunding the fee.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/gunfight/{id}/bets": {
+            "get": {
+                "description": "Fetches the spectator bets of the gunfight by side, whether bets are still accepted, the house fee and the bet of the user.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "gunfight"
+                ],
+                "summary": "Retrieve gunfight betting pool",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User data in encoded format containing user ID and other necessary information",
+                        "name": "X-User-Data",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Gunfight ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns the betting pool.",
+                        "schema": {
+                            "$ref": "#/definitions/gunfight.BetPoolResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid gunfight ID.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error - gunfight not found or error getting the bets.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Bets silver on a side of the gunfight, the silver is taken from the balance of the user right away. Bets are accepted until the first round starts, one bet per gunfight, and the players of the gunfight can not bet on it. The pool is pari-mutuel: when the gunfight gets a winner, bets on the winning side share the whole pool minus the house fee in proportion to the bets. A gunfight without a winner, a gunfight won because the opponent did not join or left, or a pool with bets on one side only refunds the bets. The sum of the bets of a user over the last 24 hours is limited.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "gunfight"
+                ],
+                "summary": "Bet on gunfight",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User data in encoded format containing user ID and other necessary information",
+                        "name": "X-User-Data",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Gunfight ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of the player, or of the team captain, the bet is on",
+                        "name": "side",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Bet in silver, within the bet limits",
+                        "name": "silver",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns the placed bet.",
+                        "schema": {
+                            "$ref": "#/definitions/gunfight.BetItem"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid gunfight ID, or side or silver is missing.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error - betting is closed, the user plays in the gunfight, already bet, the bet is out of limits or not enough silver.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/gunfight/{id}/fairness": {
+            "get": {
+                "description": "Fetches the commit-reveal data of a gunfight: the hash of the server seed published when the gunfight was created and the client seeds of the players. Once the gunfight is finished the server seed is revealed, the draw delay of every round is derived again and checked against the duel log. Any player can audit any gunfight.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "gunfight"
+                ],
+                "summary": "Audit gunfight randomness",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User data in encoded format containing user ID and other necessary information",
+                        "name": "X-User-Data",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Gunfight ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns the seeds and the re-derived draws.",
+                        "schema": {
+                            "$ref": "#/definitions/gunfight.FairnessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid gunfight ID.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error - gunfight not found, played before provably fair draws or error getting the duel log.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/gunfight/{id}/play": {
+            "get": {
+                "description": "Opens a websocket connection to the gunfight created by matchmaking. Every message is a gunfight.Message envelope: the client receives waiting, round, draw, result, finished and cancelled messages with a gunfight.DuelEvent payload until the duel ends and fires with a shoot command carrying a gunfight.DuelCommand payload once the draw message arrives. A shot before the draw is a foul and costs the shooter a hit, otherwise the faster reaction hits: reaction is measured by the server clock from sending the draw to receiving the shot minus the connection RTT, by at most 40 ms. The RTT is the smallest of the last websocket pings, a pong counts only if it echoes the payload of a ping. The client also receives spectators messages with the live spectator count. Draw delays are provably fair: waiting and round messages carry the hash of the server seed, the player mixes in the client_seed passed on join and the seed is revealed by the fairness endpoint once the gunfight ends. A player who lost the connection reconnects with the seq of the last received message and first gets the messages they missed, a new connection of the player replaces the previous one and closes it; a player who does not come back within the reconnect grace period forfeits.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "gunfight"
+                ],
+                "summary": "Play gunfight",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User data in encoded format containing user ID and other necessary information",
+                        "name": "X-User-Data",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Gunfight ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Seq of the last received message when reconnecting",
+                        "name": "seq",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Seed of the player mixed into the random draws, up to 64 characters; only taken before the duel starts",
+                        "name": "client_seed",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "WebSocket connection established, duel events follow.",
+                        "schema": {
+                            "$ref": "#/definitions/gunfight.Message"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid gunfight ID, seq or client seed, or user is not a participant.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/gunfight/{id}/rematch": {
+            "get": {
+                "description": "Opens a websocket connection to vote for a rematch of a finished gunfight within the rematch window. Every message is a gunfight.Message envelope: the server sends rematch with a gunfight.RematchOffer on connect and again when the opponent asks for a rematch. The client sends accept to ask for or agree to a rematch and decline to refuse it, without a payload. When both players accept, a new gunfight with the same stake is created right away without the matchmaking queue and both get matched, rematches never change the rating; otherwise both get declined or expired with a gunfight.RematchReply. Rematches are linked to the previous game in the history.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "gunfight"
+                ],
+                "summary": "Rematch gunfight",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User data in encoded format containing user ID and other necessary information",
+                        "name": "X-User-Data",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Gunfight ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "WebSocket connection established, rematch messages follow.",
+                        "schema": {
+                            "$ref": "#/definitions/gunfight.Message"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid gunfight ID.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/gunfight/{id}/replay": {
+            "get": {
+                "description": "Fetches every event of the duel with its server timestamp: events sent to the players, accepted shots with the connection RTT, forfeits and the moments the pause before each draw starts. Only participants of the gunfight can fetch it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "gunfight"
+                ],
+                "summary": "Retrieve gunfight replay",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User data in encoded format containing user ID and other necessary information",
+                        "name": "X-User-Data",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Gunfight ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns the duel log.",
+                        "schema": {
+                            "$ref": "#/definitions/gunfight.ReplayResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid gunfight ID.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error - user is not a participant or error getting the duel log.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/gunfight/{id}/watch": {
+            "get": {
+                "description": "Opens a read-only websocket connection to a running gunfight. Spectators receive the same gunfight.Message envelopes with a gunfight.DuelEvent payload as the players, delayed by the configured spectator delay, and spectators messages with the live spectator count. The number of spectators per gunfight is limited.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "gunfight"
+                ],
+                "summary": "Watch gunfight",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User data in encoded format containing user ID and other necessary information",
+                        "name": "X-User-Data",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Gunfight ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "WebSocket connection established, duel events follow.",
+                        "schema": {
+                            "$ref": "#/definitions/gunfight.Message"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid gunfight ID, gunfight is not running or the spectator limit is reached.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/horse": {
+            "get": {
+                "description": "Fetches the horse's data and calculates its speed based on the user ID provided in the context.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "horse"
+                ],
+                "summary": "Retrieve horse by user ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User data in encoded format containing user ID and other necessary information",
+                        "name": "X-User-Data",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns the horse along with its calculated speed.",
+                        "schema": {
+                            "$ref": "#/definitions/horse.BaseResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - user_id is required or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found - no horse found for the user ID.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/horse/finish": {
+            "post": {
+                "description": "Completes the race started with /horse/start and updates the horse's record and earnings according to the distance covered. The race session can be finished once and only before it expires; the accepted distance is capped at what the horse covers at its start speed per second in the time elapsed since the start.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "horse"
+                ],
+                "summary": "Finish horse race",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User data in encoded format containing user ID and other necessary information",
+                        "name": "X-User-Data",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Race completion details including the race ID and the distance covered.",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/horse.GameRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns the results of the race finish with updated horse data and earnings.",
+                        "schema": {
+                            "$ref": "#/definitions/horse.GameResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - user data is required or invalid, the request body is malformed or race_id is missing.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found - no horse found to finish race for the user ID.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error - race session expired, already finished or replaced, or error during the race finish process.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/horse/start": {
+            "post": {
+                "description": "Starts a race session on the server with the server time of the start and the current speed of the horse. The race is finished with the returned race ID before the session expires, starting a new race replaces the previous session.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "horse"
+                ],
+                "summary": "Start horse race",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User data in encoded format containing user ID and other necessary information",
+                        "name": "X-User-Data",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns the race session.",
+                        "schema": {
+                            "$ref": "#/definitions/horse.RaceResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - user data is required or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error - no horse found for the user ID or error starting the race.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/horse/upgrade": {
+            "get": {
+                "description": "Increases the horse's level using the user ID extracted from the context.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "horse"
+                ],
+                "summary": "Upgrade horse level",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User data in encoded format containing user ID and other necessary information",
+                        "name": "X-User-Data",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Horse upgraded successfully with new level information.",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - user data is required or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found - no horse found to upgrade for the user ID.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error - error during the upgrade process.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/money": {
+            "get": {
+                "description": "Fetches the money details associated with the provided user ID.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "money"
+                ],
+                "summary": "Retrieve money record",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User data in encoded format containing user ID and other necessary information",
+                        "name": "X-User-Data",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns the money details for the specified user ID.",
+                        "schema": {
+                            "$ref": "#/definitions/money.BaseResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - user_id is required or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found - no money record found for the user ID.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/user": {
+            "get": {
+                "description": "check user and his actual data.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Check user and his actual data",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User data in encoded format containing user ID and other necessary information",
+                        "name": "X-User-Data",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns the user details.",
+                        "schema": {
+                            "$ref": "#/definitions/user.BaseResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid request body.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error - failed to create or update user.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "gunfight.Band": {
+            "type": "object",
+            "properties": {
+                "max_rating": {
+                    "type": "integer",
+                    "example": 1050
+                },
+                "min_rating": {
+                    "type": "integer",
+                    "example": 950
+                },
+                "percent": {
+                    "type": "integer",
+                    "example": 5
+                }
+            }
+        },
+        "gunfight.BetItem": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer",
+                    "x-order": "1",
+                    "example": 1
+                },
+                "gunfight_id": {
+                    "type": "integer",
+                    "x-order": "2",
+                    "example": 1
+                },
+                "side": {
+                    "type": "integer",
+                    "x-order": "3",
+                    "example": 2
+                },
+                "silver": {
+                    "type": "integer",
+                    "x-order": "4",
+                    "example": 100
+                },
+                "status": {
+                    "type": "string",
+                    "x-order": "5",
+                    "example": "open"
+                },
+                "payout": {
+                    "type": "integer",
+                    "x-order": "6",
+                    "example": 0
+                },
+                "created_at": {
+                    "type": "string",
+                    "x-order": "7"
+                }
+            }
+        },
+        "gunfight.BetPoolResponse": {
+            "description": "Pari-mutuel pool of a gunfight: bets are accepted until the first round starts, the winning side shares the whole pool minus the house fee in proportion to the bets. Bet is the bet of the user, null if the user did not bet",
+            "type": "object",
+            "properties": {
+                "gunfight_id": {
+                    "type": "integer",
+                    "x-order": "1",
+                    "example": 1
+                },
+                "open": {
+                    "type": "boolean",
+                    "x-order": "2",
+                    "example": true
+                },
+                "house_fee": {
+                    "type": "integer",
+                    "x-order": "3",
+                    "example": 5
+                },
+                "total": {
+                    "type": "integer",
+                    "x-order": "4",
+                    "example": 500
+                },
+                "sides": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/gunfight.BetSide"
+                    },
+                    "x-order": "5"
+                },
+                "bet": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/gunfight.BetItem"
+                        }
+                    ],
+                    "x-order": "6"
+                }
+            }
+        },
+        "gunfight.BetSide": {
+            "type": "object",
+            "properties": {
+                "side": {
+                    "type": "integer",
+                    "x-order": "1",
+                    "example": 2
+                },
+                "silver": {
+                    "type": "integer",
+                    "x-order": "2",
+                    "example": 300
+                },
+                "bets": {
+                    "type": "integer",
+                    "x-order": "3",
+                    "example": 3
+                }
+            }
+        },
+        "gunfight.BountyItem": {
+            "description": "Gold posted on a target player: the player who beats the target in a gunfight collects it, an unclaimed bounty returns to the poster when it expires",
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer",
+                    "x-order": "1",
+                    "example": 1
+                },
+                "poster_id": {
+                    "type": "integer",
+                    "x-order": "2",
+                    "example": 1
+                },
+                "target_id": {
+                    "type": "integer",
+                    "x-order": "3",
+                    "example": 2
+                },
+                "gold": {
+                    "type": "integer",
+                    "x-order": "4",
+                    "example": 500
+                },
+                "created_at": {
+                    "type": "string",
+                    "x-order": "5"
+                },
+                "expires_at": {
+                    "type": "string",
+                    "x-order": "6"
+                }
+            }
+        },
+        "gunfight.BracketResponse": {
+            "type": "object",
+            "properties": {
+                "tournament": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/gunfight.TournamentItem"
+                        }
+                    ],
+                    "x-order": "1"
+                },
+                "players": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/gunfight.TournamentPlayerItem"
+                    },
+                    "x-order": "2"
+                },
+                "rounds": {
+                    "type": "array",
+                    "items": {
+                        "type": "array",
+                        "items": {
+                            "$ref": "#/definitions/gunfight.TournamentMatchItem"
+                        }
+                    },
+                    "x-order": "3"
+                }
+            }
+        },
+        "gunfight.ClientSeedItem": {
+            "type": "object",
+            "properties": {
+                "user_id": {
+                    "type": "integer",
+                    "x-order": "1",
+                    "example": 1
+                },
+                "seed": {
+                    "type": "string",
+                    "x-order": "2",
+                    "example": "lucky-horseshoe"
+                }
+            }
+        },
+        "gunfight.FairDraw": {
+            "type": "object",
+            "properties": {
+                "round": {
+                    "type": "integer",
+                    "x-order": "1",
+                    "example": 1
+                },
+                "delay": {
+                    "type": "integer",
+                    "x-order": "2",
+                    "example": 3412
+                },
+                "observed": {
+                    "type": "integer",
+                    "x-order": "3",
+                    "example": 3415
+                }
+            }
+        },
+        "gunfight.FairnessResponse": {
+            "description": "Commit-reveal data of a gunfight: seed_hash is published when the gunfight is created, server_seed is revealed once it is finished. The draw delay of a round is HMAC-SHA256 keyed by the server seed over the client seeds joined by \":\" in the order of client_seeds, then \":\" and the round number; the first 8 bytes of the MAC as a big-endian number modulo the delay range (3000000000 ns) plus the minimum delay (2 s). Draws are filled in only for finished gunfights",
+            "type": "object",
+            "properties": {
+                "gunfight_id": {
+                    "type": "integer",
+                    "x-order": "1",
+                    "example": 1
+                },
+                "seed_hash": {
+                    "type": "string",
+                    "x-order": "2",
+                    "example": "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+                },
+                "server_seed": {
+                    "type": "string",
+                    "x-order": "3",
+                    "example": "4d0f1c6e2b9a8f7e6d5c4b3a29180706f5e4d3c2b1a09f8e7d6c5b4a39281706"
+                },
+                "client_seeds": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/gunfight.ClientSeedItem"
+                    },
+                    "x-order": "4"
+                },
+                "draws": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/gunfight.FairDraw"
+                    },
+                    "x-order": "5"
+                },
+                "verified": {
+                    "type": "boolean",
+                    "x-order": "6",
+                    "example": true
+                },
+                "message": {
+                    "type": "string",
+                    "x-order": "7"
+                }
+            }
+        },
+        "gunfight.HistoryItem": {
+            "description": "Finished gunfight: result is win, loss or draw, rematch_of links a rematch to the previous game. In a team gunfight (team_size 2) opponent_id and winner_id are team captains",
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer",
+                    "x-order": "1",
+                    "example": 1
+                },
+                "start_date": {
+                    "type": "string",
+                    "x-order": "10"
+                },
+                "end_date": {
+                    "type": "string",
+                    "x-order": "11"
+                },
+                "opponent_id": {
+                    "type": "integer",
+                    "x-order": "2",
+                    "example": 2
+                },
+                "winner_id": {
+                    "type": "integer",
+                    "x-order": "3",
+                    "example": 2
+                },
+                "result": {
+                    "type": "string",
+                    "x-order": "4",
+                    "example": "loss"
+                },
+                "stake": {
+                    "type": "integer",
+                    "x-order": "5",
+                    "example": 100
+                },
+                "bot": {
+                    "type": "string",
+                    "x-order": "6",
+                    "example": "deputy"
+                },
+                "queue": {
+                    "type": "string",
+                    "x-order": "7",
+                    "example": "ranked"
+                },
+                "team_size": {
+                    "type": "integer",
+                    "x-order": "8",
+                    "example": 1
+                },
+                "rematch_of": {
+                    "type": "integer",
+                    "x-order": "9",
+                    "example": 1
+                }
+            }
+        },
+        "gunfight.HistoryResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/gunfight.HistoryItem"
+                    },
+                    "x-order": "1"
+                },
+                "next_cursor": {
+                    "type": "integer",
+                    "x-order": "2",
+                    "example": 1
+                }
+            }
+        },
+        "gunfight.Message": {
+            "description": "Versioned websocket envelope: type defines the payload, seq grows with every server message",
+            "type": "object",
+            "properties": {
+                "v": {
+                    "type": "integer",
+                    "x-order": "1",
+                    "example": 1
+                },
+                "type": {
+                    "type": "string",
+                    "x-order": "2",
+                    "example": "matched"
+                },
+                "seq": {
+                    "type": "integer",
+                    "x-order": "3",
+                    "example": 1
+                },
+                "payload": {
+                    "type": "object",
+                    "x-order": "4"
+                }
+            }
+        },
+        "gunfight.Party": {
+            "description": "Party for team gunfights, members include the leader",
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string",
+                    "x-order": "1",
+                    "example": "0b6f5a0e-8d0c-4f8e-a1c3-2f3d5c9e7b21"
+                },
+                "leader_id": {
+                    "type": "integer",
+                    "x-order": "2",
+                    "example": 1
+                },
+                "members": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    },
+                    "x-order": "3"
+                }
+            }
+        },
+        "gunfight.PartyInvite": {
+            "type": "object",
+            "properties": {
+                "party_id": {
+                    "type": "string",
+                    "x-order": "1",
+                    "example": "0b6f5a0e-8d0c-4f8e-a1c3-2f3d5c9e7b21"
+                },
+                "leader_id": {
+                    "type": "integer",
+                    "x-order": "2",
+                    "example": 1
+                },
+                "expires_at": {
+                    "type": "string",
+                    "x-order": "3"
+                }
+            }
+        },
+        "gunfight.PartyResponse": {
+            "type": "object",
+            "properties": {
+                "party": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/gunfight.Party"
+                        }
+                    ],
+                    "x-order": "1"
+                },
+                "invites": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/gunfight.PartyInvite"
+                    },
+                    "x-order": "2"
+                }
+            }
+        },
+        "gunfight.QueueMetrics": {
+            "description": "Matchmaking queue settings and search outcome counters",
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "x-order": "1",
+                    "example": "ranked"
+                },
+                "timeouts": {
+                    "type": "integer",
+                    "x-order": "10",
+                    "example": 7
+                },
+                "cancelled": {
+                    "type": "integer",
+                    "x-order": "11",
+                    "example": 9
+                },
+                "median_wait": {
+                    "type": "integer",
+                    "x-order": "12",
+                    "example": 20
+                },
+                "stake": {
+                    "type": "integer",
+                    "x-order": "2",
+                    "example": 100
+                },
+                "ranked": {
+                    "type": "boolean",
+                    "x-order": "3",
+                    "example": true
+                },
+                "min_balance": {
+                    "type": "integer",
+                    "x-order": "4",
+                    "example": 0
+                },
+                "house_fee": {
+                    "type": "integer",
+                    "x-order": "5",
+                    "example": 5
+                },
+                "timeout": {
+                    "type": "integer",
+                    "x-order": "6",
+                    "example": 60
+                },
+                "waiting": {
+                    "type": "integer",
+                    "x-order": "7",
+                    "example": 4
+                },
+                "matched": {
+                    "type": "integer",
+                    "x-order": "8",
+                    "example": 120
+                },
+                "bots": {
+                    "type": "integer",
+                    "x-order": "9",
+                    "example": 15
+                }
+            }
+        },
+        "gunfight.QueueStatus": {
+            "description": "Matchmaking load and the estimated wait in seconds",
+            "type": "object",
+            "properties": {
+                "queue": {
+                    "type": "string",
+                    "x-order": "1",
+                    "example": "ranked"
+                },
+                "stake": {
+                    "type": "integer",
+                    "x-order": "2",
+                    "example": 100
+                },
+                "band": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/gunfight.Band"
+                        }
+                    ],
+                    "x-order": "3"
+                },
+                "in_band": {
+                    "type": "integer",
+                    "x-order": "4",
+                    "example": 3
+                },
+                "total": {
+                    "type": "integer",
+                    "x-order": "5",
+                    "example": 12
+                },
+                "estimated_wait": {
+                    "type": "integer",
+                    "x-order": "6",
+                    "example": 20
+                }
+            }
+        },
+        "gunfight.RatingResponse": {
+            "description": "Elo rating of the player and the number of rated games",
+            "type": "object",
+            "properties": {
+                "rating": {
+                    "type": "integer",
+                    "x-order": "1",
+                    "example": 1000
+                },
+                "games": {
+                    "type": "integer",
+                    "x-order": "2",
+                    "example": 10
+                }
+            }
+        },
+        "gunfight.ReplayEvent": {
+            "description": "Duel log entry: a server event with its payload, an accepted shot or a forfeit, at is the server timestamp",
+            "type": "object",
+            "properties": {
+                "seq": {
+                    "type": "integer",
+                    "x-order": "1",
+                    "example": 3
+                },
+                "type": {
+                    "type": "string",
+                    "x-order": "2",
+                    "example": "draw"
+                },
+                "user_id": {
+                    "type": "integer",
+                    "x-order": "3",
+                    "example": 1
+                },
+                "round": {
+                    "type": "integer",
+                    "x-order": "4",
+                    "example": 1
+                },
+                "rtt": {
+                    "type": "integer",
+                    "x-order": "5",
+                    "example": 42000
+                },
+                "payload": {
+                    "type": "object",
+                    "x-order": "6"
+                },
+                "at": {
+                    "type": "string",
+                    "x-order": "7"
+                }
+            }
+        },
+        "gunfight.ReplayResponse": {
+            "type": "object",
+            "properties": {
+                "gunfight_id": {
+                    "type": "integer",
+                    "x-order": "1",
+                    "example": 1
+                },
+                "players": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    },
+                    "x-order": "2"
+                },
+                "winner_id": {
+                    "type": "integer",
+                    "x-order": "3",
+                    "example": 1
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/gunfight.ReplayEvent"
+                    },
+                    "x-order": "4"
+                }
+            }
+        },
+        "gunfight.StatsResponse": {
+            "description": "Results of finished gunfights, streak is positive for wins and negative for losses",
+            "type": "object",
+            "properties": {
+                "wins": {
+                    "type": "integer",
+                    "x-order": "1",
+                    "example": 10
+                },
+                "losses": {
+                    "type": "integer",
+                    "x-order": "2",
+                    "example": 5
+                },
+                "draws": {
+                    "type": "integer",
+                    "x-order": "3",
+                    "example": 1
+                },
+                "win_rate": {
+                    "type": "number",
+                    "x-order": "4",
+                    "example": 0.67
+                },
+                "streak": {
+                    "type": "integer",
+                    "x-order": "5",
+                    "example": 3
+                },
+                "most_faced_opponent_id": {
+                    "type": "integer",
+                    "x-order": "6",
+                    "example": 2
+                },
+                "most_faced_games": {
+                    "type": "integer",
+                    "x-order": "7",
+                    "example": 4
+                }
+            }
+        },
+        "gunfight.TournamentItem": {
+            "description": "Single-elimination tournament, status is registration, running, finished or cancelled",
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer",
+                    "x-order": "1",
+                    "example": 1
+                },
+                "end_date": {
+                    "type": "string",
+                    "x-order": "10"
+                },
+                "size": {
+                    "type": "integer",
+                    "x-order": "2",
+                    "example": 16
+                },
+                "entry_fee": {
+                    "type": "integer",
+                    "x-order": "3",
+                    "example": 100
+                },
+                "status": {
+                    "type": "string",
+                    "x-order": "4",
+                    "example": "registration"
+                },
+                "players": {
+                    "type": "integer",
+                    "x-order": "5",
+                    "example": 11
+                },
+                "pot": {
+                    "type": "integer",
+                    "x-order": "6",
+                    "example": 1100
+                },
+                "round": {
+                    "type": "integer",
+                    "x-order": "7",
+                    "example": 0
+                },
+                "winner_id": {
+                    "type": "integer",
+                    "x-order": "8",
+                    "example": 1
+                },
+                "start_date": {
+                    "type": "string",
+                    "x-order": "9"
+                }
+            }
+        },
+        "gunfight.TournamentMatchItem": {
+            "type": "object",
+            "properties": {
+                "position": {
+                    "type": "integer",
+                    "x-order": "1",
+                    "example": 0
+                },
+                "user_1_id": {
+                    "type": "integer",
+                    "x-order": "2",
+                    "example": 1
+                },
+                "user_2_id": {
+                    "type": "integer",
+                    "x-order": "3",
+                    "example": 16
+                },
+                "gunfight_id": {
+                    "type": "integer",
+                    "x-order": "4",
+                    "example": 42
+                },
+                "winner_id": {
+                    "type": "integer",
+                    "x-order": "5",
+                    "example": 1
+                },
+                "walkover": {
+                    "type": "boolean",
+                    "x-order": "6",
+                    "example": false
+                },
+                "decided": {
+                    "type": "boolean",
+                    "x-order": "7",
+                    "example": true
+                }
+            }
+        },
+        "gunfight.TournamentPlayerItem": {
+            "type": "object",
+            "properties": {
+                "user_id": {
+                    "type": "integer",
+                    "x-order": "1",
+                    "example": 1
+                },
+                "seed": {
+                    "type": "integer",
+                    "x-order": "2",
+                    "example": 1
+                },
+                "rating": {
+                    "type": "integer",
+                    "x-order": "3",
+                    "example": 1240
+                },
+                "prize": {
+                    "type": "integer",
+                    "x-order": "4",
+                    "example": 600
+                }
+            }
+        },
+        "horse.BaseResponse": {
+            "description": "This is a horse model",
+            "type": "object",
+            "properties": {
+                "level": {
+                    "type": "integer",
+                    "x-order": "2",
+                    "example": 100
+                },
+                "distance": {
+                    "type": "integer",
+                    "x-order": "3",
+                    "example": 50
+                },
+                "speed": {
+                    "type": "integer",
+                    "x-order": "4",
+                    "example": 2000
+                }
+            }
+        },
+        "horse.GameRequest": {
+            "type": "object",
+            "properties": {
+                "race_id": {
+                    "type": "string",
+                    "x-order": "1",
+                    "example": "5f1c6a8e-3c1a-4b7e-9a43-8d7f0b8f2c11"
+                },
+                "distance": {
+                    "type": "integer",
+                    "x-order": "2"
+                }
+            }
+        },
+        "horse.GameResponse": {
+            "type": "object",
+            "properties": {
+                "earned": {
+                    "type": "integer",
+                    "x-order": "1"
+                },
+                "record": {
+                    "type": "boolean",
+                    "x-order": "2"
+                },
+                "distance": {
+                    "type": "integer",
+                    "x-order": "3"
+                }
+            }
+        },
+        "horse.RaceResponse": {
+            "type": "object",
+            "properties": {
+                "race_id": {
+                    "type": "string",
+                    "x-order": "1",
+                    "example": "5f1c6a8e-3c1a-4b7e-9a43-8d7f0b8f2c11"
+                },
+                "speed": {
+                    "type": "integer",
+                    "x-order": "2",
+                    "example": 2000
+                },
+                "started_at": {
+                    "type": "string",
+                    "x-order": "3"
+                },
+                "expires_at": {
+                    "type": "string",
+                    "x-order": "4"
                 }
             }
         },
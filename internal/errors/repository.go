@@ -54,3 +54,18 @@ func TransactionStartError(contextData contextutils.ContextData, err error) erro
 func TransactionCommitError(contextData contextutils.ContextData, err error) error {
 	return NewRepoError(contextData, fmt.Sprintf("transaction commit failed: %v", err))
 }
+
+// InsufficientFundsError Ошибка недостаточного баланса
+func InsufficientFundsError(contextData contextutils.ContextData, entity string) error {
+	return NewRepoError(contextData, fmt.Sprintf("insufficient funds in %s", entity))
+}
+
+// CapacityExceededError Ошибка заполненной записи: например, в сетке турнира не осталось мест
+func CapacityExceededError(contextData contextutils.ContextData, entity string) error {
+	return NewRepoError(contextData, fmt.Sprintf("no places left in %s", entity))
+}
+
+// LimitExceededError Ошибка превышения лимита игрока, например суммы ставок за сутки
+func LimitExceededError(contextData contextutils.ContextData, entity string) error {
+	return NewRepoError(contextData, fmt.Sprintf("limit exceeded for %s", entity))
+}
@@ -2,20 +2,36 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
 	"net/http"
+	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
+	"wildwest/internal/model/gunfight"
 	"wildwest/internal/service"
+	"wildwest/pkg/contextutils"
 	"wildwest/pkg/logging"
+	"wildwest/pkg/wsconn"
 )
 
+var (
+	errSearchCancelled = errors.New("search cancelled by player")
+	errDuelLeft        = errors.New("player left the duel")
+	errPeerGone        = errors.New("player disconnected")
+)
+
+// maxClientSeedLength — длина колонки client_seed
+const maxClientSeedLength = 64
+
 type gunfightHandler struct {
-	gunfightService   service.GunfightService
-	logger            logging.Logger
-	upgrader          websocket.Upgrader
-	searchConnections sync.Map // Соединения для поиска игры
-	gameConnections   sync.Map // Соединения для игры
+	gunfightService service.GunfightService
+	logger          logging.Logger
+	upgrader        websocket.Upgrader
 }
 
 func NewGunfightHandler(gunfightService service.GunfightService, logger logging.Logger) *gunfightHandler {
@@ -31,12 +47,13 @@ func NewGunfightHandler(gunfightService service.GunfightService, logger logging.
 
 // FindGunfight initiates a search for an opponent in a gunfight
 // @Summary Initiate gunfight search
-// @Description Opens a websocket connection and waits to match with an opponent for a gunfight.
+// @Description Opens a websocket connection and waits to match with an opponent for a gunfight in the given queue. Every queue has its own stake, rating rules, minimum balance, search bands, timeout and house fee: casual games have no stake and do not change the rating. Every message is a gunfight.Message envelope: the server sends queued, searching (current rating band), queue (gunfight.QueueStatus, every few seconds while waiting), matched, timeout, error and cancelled messages, the client may send a cancel command. If bots are enabled and no player is found within the bot wait, the player is matched with a bot of matching strength: such a matched message has bot set, the game is free and unranked.
 // @Tags gunfight
 // @Accept json
 // @Produce json
 // @Param user-id header int true "User ID"
-// @Success 200 {object} string "WebSocket connection established, waiting for opponent."
+// @Param queue query string true "Matchmaking queue, one of the configured queues such as casual, ranked or high_stakes"
+// @Success 200 {object} gunfight.Message "WebSocket connection established, waiting for opponent."
 // @Failure 400 {object} string "Could not open websocket connection"
 // @Failure 500 {object} string "Internal server error"
 // @Router /gunfight/find [get]
@@ -48,80 +65,1362 @@ func (h *gunfightHandler) FindGunfight(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	queue := r.URL.Query().Get("queue")
+	if queue == "" {
+		http.Error(w, "queue is required", http.StatusBadRequest)
+		return
+	}
+
 	conn, err := h.upgradeConnection(w, r)
 	if err != nil {
 		h.logger.Error("Error upgrading connection: ", err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	defer conn.Close()
 
-	h.searchConnections.Store(userID, conn)
-	defer h.searchConnections.Delete(userID)
+	// Если игрок пропал, ставка возвращается; для уже созданной игры это ничего не меняет
+	ws := wsconn.New(conn, func() {
+		if err := h.gunfightService.RemovePlayerFromQueue(context.Background(), userID); err != nil {
+			h.logger.Error("Error removing player from queue: ", err)
+		}
+	})
+	defer ws.Close()
+
+	ctx, cancel := context.WithCancelCause(r.Context())
+	defer cancel(nil)
+
+	go func() {
+		for data := range ws.Messages() {
+			if message, err := gunfight.DecodeMessage(data); err == nil && message.Type == gunfight.CommandCancel {
+				cancel(errSearchCancelled)
+				return
+			}
+		}
+		cancel(errPeerGone)
+	}()
+
+	var seq int64
+	send := func(messageType string, payload interface{}) {
+		seq++
+		if err := h.writeMessage(ws, messageType, seq, payload); err != nil {
+			h.logger.Error("Error writing to websocket: ", err)
+		}
+	}
+
+	send(gunfight.MessageQueued, gunfight.QueuedPayload{Queue: queue})
+
+	result, err := h.gunfightService.FindGunfight(ctx, userID, queue, func(payload gunfight.SearchingPayload) {
+		send(gunfight.MessageSearching, payload)
+	}, func(status gunfight.QueueStatus) {
+		send(gunfight.MessageQueue, status)
+	})
+	switch {
+	case errors.Is(context.Cause(ctx), errSearchCancelled):
+		send(gunfight.MessageCancelled, gunfight.ErrorPayload{Message: "Search cancelled"})
+	case err != nil:
+		h.logger.Error("Error finding gunfight: ", err)
+		send(gunfight.MessageError, gunfight.ErrorPayload{Message: err.Error()})
+	case result.OpponentID != 0:
+		// Соперник получит уведомление о матче через Redis на том инстансе, где открыт его сокет
+		send(gunfight.MessageMatched, gunfight.Match{GunfightID: result.GunfightID, OpponentID: result.OpponentID, Bot: result.Bot})
+	default:
+		send(gunfight.MessageTimeout, gunfight.ErrorPayload{Message: result.Message})
+	}
+}
+
+// PlayGunfight joins a matched gunfight and runs the duel over a websocket
+// @Summary Play gunfight
+// @Description Opens a websocket connection to the gunfight created by matchmaking. Every message is a gunfight.Message envelope: the client receives waiting, round, draw, result, finished and cancelled messages with a gunfight.DuelEvent payload until the duel ends and fires with a shoot command carrying a gunfight.DuelCommand payload once the draw message arrives. A shot before the draw is a foul and costs the shooter a hit, otherwise the faster reaction hits: reaction is measured by the server clock from sending the draw to receiving the shot minus the connection RTT, by at most 40 ms. The RTT is the smallest of the last websocket pings, a pong counts only if it echoes the payload of a ping. The client also receives spectators messages with the live spectator count. Draw delays are provably fair: waiting and round messages carry the hash of the server seed, the player mixes in the client_seed passed on join and the seed is revealed by the fairness endpoint once the gunfight ends. A player who lost the connection reconnects with the seq of the last received message and first gets the messages they missed, a new connection of the player replaces the previous one and closes it; a player who does not come back within the reconnect grace period forfeits.
+// @Tags gunfight
+// @Accept json
+// @Produce json
+// @Param X-User-Data header string true "User data in encoded format containing user ID and other necessary information"
+// @Param id path int true "Gunfight ID"
+// @Param seq query int false "Seq of the last received message when reconnecting"
+// @Param client_seed query string false "Seed of the player mixed into the random draws, up to 64 characters; only taken before the duel starts"
+// @Success 200 {object} gunfight.Message "WebSocket connection established, duel events follow."
+// @Failure 400 {string} string "Bad request - invalid gunfight ID, seq or client seed, or user is not a participant."
+// @Router /gunfight/{id}/play [get]
+func (h *gunfightHandler) PlayGunfight(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.extractUserID(r)
+	if err != nil {
+		h.logger.Error("Error extracting user ID: ", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	gunfightID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid gunfight ID", http.StatusBadRequest)
+		return
+	}
 
 	ctx, cancel := context.WithCancel(r.Context())
 	defer cancel()
 
+	lastSeq, err := queryInt(r, "seq")
+	if err != nil {
+		http.Error(w, "seq must be a number", http.StatusBadRequest)
+		return
+	}
+
+	clientSeed := r.URL.Query().Get("client_seed")
+	if len(clientSeed) > maxClientSeedLength {
+		http.Error(w, fmt.Sprintf("client_seed must be at most %d characters", maxClientSeedLength), http.StatusBadRequest)
+		return
+	}
+
+	seat, err := h.gunfightService.JoinGunfight(ctx, gunfightID, userID, int64(lastSeq), clientSeed)
+	if err != nil {
+		h.logger.Error("Error joining gunfight: ", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	conn, err := h.upgradeConnection(w, r)
+	if err != nil {
+		h.logger.Error("Error upgrading connection: ", err)
+		return
+	}
+
+	ws := wsconn.New(conn, nil)
+	defer ws.Close()
+
 	go func() {
-		for {
-			if _, _, err := conn.NextReader(); err != nil {
-				h.logger.Error("Error reading from websocket: ", err)
-				conn.Close()
-				cancel()
-				break
+		defer cancel()
+		for data := range ws.Messages() {
+			if err := h.handleDuelCommand(seat, ws, data); err != nil {
+				if errors.Is(err, errDuelLeft) {
+					return
+				}
+				if err := h.writeMessage(ws, gunfight.MessageError, 0, gunfight.ErrorPayload{Message: err.Error()}); err != nil {
+					h.logger.Error("Error writing to websocket: ", err)
+				}
+			}
+		}
+	}()
+
+	for _, event := range seat.Missed {
+		if err := h.writeMessage(ws, event.Type, event.Seq, event); err != nil {
+			h.logger.Error("Error writing to websocket: ", err)
+			return
+		}
+	}
+
+	for {
+		select {
+		case event, ok := <-seat.Events:
+			if !ok {
+				return
+			}
+			if err := h.writeMessage(ws, event.Type, event.Seq, event); err != nil {
+				h.logger.Error("Error writing to websocket: ", err)
+				return
 			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// WatchGunfight streams a running gunfight to a spectator
+// @Summary Watch gunfight
+// @Description Opens a read-only websocket connection to a running gunfight. Spectators receive the same gunfight.Message envelopes with a gunfight.DuelEvent payload as the players, delayed by the configured spectator delay, and spectators messages with the live spectator count. The number of spectators per gunfight is limited.
+// @Tags gunfight
+// @Accept json
+// @Produce json
+// @Param X-User-Data header string true "User data in encoded format containing user ID and other necessary information"
+// @Param id path int true "Gunfight ID"
+// @Success 200 {object} gunfight.Message "WebSocket connection established, duel events follow."
+// @Failure 400 {string} string "Bad request - invalid gunfight ID, gunfight is not running or the spectator limit is reached."
+// @Router /gunfight/{id}/watch [get]
+func (h *gunfightHandler) WatchGunfight(w http.ResponseWriter, r *http.Request) {
+	if _, err := h.extractUserID(r); err != nil {
+		h.logger.Error("Error extracting user ID: ", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	gunfightID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid gunfight ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	events, err := h.gunfightService.WatchGunfight(ctx, gunfightID)
+	if err != nil {
+		h.logger.Error("Error watching gunfight: ", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	conn, err := h.upgradeConnection(w, r)
+	if err != nil {
+		h.logger.Error("Error upgrading connection: ", err)
+		return
+	}
+
+	ws := wsconn.New(conn, nil)
+	defer ws.Close()
+
+	// Зритель ничего не отправляет, сообщения читаются только чтобы заметить отключение
+	go func() {
+		defer cancel()
+		for range ws.Messages() {
 		}
 	}()
 
-	result, err := h.gunfightService.FindGunfight(ctx, userID)
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := h.writeMessage(ws, event.Type, event.Seq, event); err != nil {
+				h.logger.Error("Error writing to websocket: ", err)
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// ChallengeGunfight challenges another player to a private duel
+// @Summary Challenge a player
+// @Description Opens a websocket connection and sends a private duel challenge to the player with the given user ID or invite link. Challenge duels skip the matchmaking queue, have no stake and do not change the rating. Bots can not be challenged, and neither player may be locked out of matchmaking for leaving a gunfight, which is checked again when the challenge is accepted. Every message is a gunfight.Message envelope: the server sends challenge_sent (gunfight.Challenge), then matched, declined, expired, error or cancelled; the client may send a cancel command to withdraw the challenge.
+// @Tags gunfight
+// @Accept json
+// @Produce json
+// @Param X-User-Data header string true "User data in encoded format containing user ID and other necessary information"
+// @Param user_id query int false "ID of the challenged player"
+// @Param link query string false "Invite link of the challenged player"
+// @Success 200 {object} gunfight.Message "WebSocket connection established, waiting for the reply."
+// @Failure 400 {string} string "Bad request - user_id or link is required."
+// @Router /gunfight/challenge [get]
+func (h *gunfightHandler) ChallengeGunfight(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.extractUserID(r)
+	if err != nil {
+		h.logger.Error("Error extracting user ID: ", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	targetID, err := queryInt(r, "user_id")
 	if err != nil {
-		h.logger.Error("Error finding gunfight: ", err)
-		conn.WriteMessage(websocket.TextMessage, []byte(err.Error()))
+		http.Error(w, "user_id must be a number", http.StatusBadRequest)
+		return
+	}
+	target := gunfight.ChallengeTarget{UserID: targetID, Link: r.URL.Query().Get("link")}
+	if target.UserID == 0 && target.Link == "" {
+		http.Error(w, "user_id or link is required", http.StatusBadRequest)
+		return
+	}
+
+	conn, err := h.upgradeConnection(w, r)
+	if err != nil {
+		h.logger.Error("Error upgrading connection: ", err)
+		return
+	}
+
+	ws := wsconn.New(conn, nil)
+	defer ws.Close()
+
+	ctx, cancel := context.WithCancelCause(contextutils.NewContext(r, userID, "ChallengeGunfight"))
+	defer cancel(nil)
+
+	go func() {
+		for data := range ws.Messages() {
+			if message, err := gunfight.DecodeMessage(data); err == nil && message.Type == gunfight.CommandCancel {
+				cancel(errSearchCancelled)
+				return
+			}
+		}
+		cancel(errPeerGone)
+	}()
+
+	var seq int64
+	send := func(messageType string, payload interface{}) {
+		seq++
+		if err := h.writeMessage(ws, messageType, seq, payload); err != nil {
+			h.logger.Error("Error writing to websocket: ", err)
+		}
+	}
+
+	var challenge gunfight.Challenge
+	reply, err := h.gunfightService.Challenge(ctx, userID, target, func(sent gunfight.Challenge) {
+		challenge = sent
+		send(gunfight.MessageChallengeSent, sent)
+	})
+	switch {
+	case errors.Is(context.Cause(ctx), errSearchCancelled):
+		send(gunfight.MessageCancelled, gunfight.ErrorPayload{Message: "Challenge withdrawn"})
+	case err != nil:
+		h.logger.Error("Error challenging player: ", err)
+		send(gunfight.MessageError, gunfight.ErrorPayload{Message: err.Error()})
+	case reply.Status == gunfight.ChallengeAccepted:
+		send(gunfight.MessageMatched, gunfight.Match{GunfightID: reply.GunfightID, OpponentID: challenge.TargetID})
+	case reply.Status == gunfight.ChallengeDeclined:
+		send(gunfight.MessageDeclined, reply)
+	default:
+		send(gunfight.MessageExpired, reply)
+	}
+}
+
+// ListenChallenges streams duel challenges addressed to the user
+// @Summary Listen for challenges
+// @Description Opens a websocket connection that delivers pending and new duel challenges as challenge messages with a gunfight.Challenge payload. The client answers with accept or decline commands carrying a gunfight.ChallengeCommand payload; an accepted challenge is answered with a matched message, a declined one with a declined message.
+// @Tags gunfight
+// @Accept json
+// @Produce json
+// @Param X-User-Data header string true "User data in encoded format containing user ID and other necessary information"
+// @Success 200 {object} gunfight.Message "WebSocket connection established, challenges follow."
+// @Failure 400 {string} string "Bad request - user data is required or invalid."
+// @Router /gunfight/challenges [get]
+func (h *gunfightHandler) ListenChallenges(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.extractUserID(r)
+	if err != nil {
+		h.logger.Error("Error extracting user ID: ", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	conn, err := h.upgradeConnection(w, r)
+	if err != nil {
+		h.logger.Error("Error upgrading connection: ", err)
 		return
 	}
 
-	if result.OpponentID != 0 {
-		message := fmt.Sprintf("Gunfight ID: %s", result.Message)
-		h.sendMessageAndClose(conn, message, userID)
+	ws := wsconn.New(conn, nil)
+	defer ws.Close()
 
-		if opponentConn, ok := h.searchConnections.Load(result.OpponentID); ok {
-			h.sendMessageAndClose(opponentConn.(*websocket.Conn), message, result.OpponentID)
+	ctx, cancel := context.WithCancel(contextutils.NewContext(r, userID, "ListenChallenges"))
+	defer cancel()
+
+	// Сообщения отправляются и из подписки, и из обработчика команд
+	var seq atomic.Int64
+	send := func(messageType string, payload interface{}) {
+		if err := h.writeMessage(ws, messageType, seq.Add(1), payload); err != nil {
+			h.logger.Error("Error writing to websocket: ", err)
 		}
-	} else {
-		conn.WriteMessage(websocket.TextMessage, []byte(result.Message))
 	}
 
-	if ctx.Err() != nil {
-		h.gunfightService.RemovePlayerFromQueue(context.Background(), userID)
+	// Вызовы, показанные игроку, нужны, чтобы знать соперника при принятии
+	var challenges sync.Map
+
+	go func() {
+		defer cancel()
+		for data := range ws.Messages() {
+			if err := h.handleChallengeCommand(ctx, userID, data, &challenges, send); err != nil {
+				send(gunfight.MessageError, gunfight.ErrorPayload{Message: err.Error()})
+			}
+		}
+	}()
+
+	err = h.gunfightService.ListenChallenges(ctx, userID, func(challenge gunfight.Challenge) {
+		challenges.Store(challenge.ID, challenge)
+		send(gunfight.MessageChallenge, challenge)
+	})
+	if err != nil && ctx.Err() == nil {
+		h.logger.Error("Error listening for challenges: ", err)
+		send(gunfight.MessageError, gunfight.ErrorPayload{Message: err.Error()})
 	}
 }
 
-func (h *gunfightHandler) sendMessageAndClose(conn *websocket.Conn, message string, userID int) {
-	conn.WriteMessage(websocket.TextMessage, []byte(message))
-	conn.Close()
-	h.searchConnections.Delete(userID)
+// RematchGunfight votes for a rematch of a finished gunfight
+// @Summary Rematch gunfight
+// @Description Opens a websocket connection to vote for a rematch of a finished gunfight within the rematch window. Every message is a gunfight.Message envelope: the server sends rematch with a gunfight.RematchOffer on connect and again when the opponent asks for a rematch. The client sends accept to ask for or agree to a rematch and decline to refuse it, without a payload. When both players accept, a new gunfight with the same stake is created right away without the matchmaking queue and both get matched, rematches never change the rating; otherwise both get declined or expired with a gunfight.RematchReply. Rematches are linked to the previous game in the history.
+// @Tags gunfight
+// @Accept json
+// @Produce json
+// @Param X-User-Data header string true "User data in encoded format containing user ID and other necessary information"
+// @Param id path int true "Gunfight ID"
+// @Success 200 {object} gunfight.Message "WebSocket connection established, rematch messages follow."
+// @Failure 400 {string} string "Bad request - invalid gunfight ID."
+// @Router /gunfight/{id}/rematch [get]
+func (h *gunfightHandler) RematchGunfight(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.extractUserID(r)
+	if err != nil {
+		h.logger.Error("Error extracting user ID: ", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	gunfightID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid gunfight ID", http.StatusBadRequest)
+		return
+	}
+
+	conn, err := h.upgradeConnection(w, r)
+	if err != nil {
+		h.logger.Error("Error upgrading connection: ", err)
+		return
+	}
+
+	ws := wsconn.New(conn, nil)
+	defer ws.Close()
+
+	ctx, cancel := context.WithCancel(contextutils.NewContext(r, userID, "RematchGunfight"))
+	defer cancel()
+
+	// Сообщения отправляются и из голосования, и из обработчика команд
+	var seq atomic.Int64
+	send := func(messageType string, payload interface{}) {
+		if err := h.writeMessage(ws, messageType, seq.Add(1), payload); err != nil {
+			h.logger.Error("Error writing to websocket: ", err)
+		}
+	}
+
+	votes := make(chan bool)
+	go func() {
+		defer cancel()
+		for data := range ws.Messages() {
+			message, err := gunfight.DecodeMessage(data)
+			if err == nil && message.Type != gunfight.CommandAccept && message.Type != gunfight.CommandDecline {
+				err = fmt.Errorf("unknown command %q", message.Type)
+			}
+			if err != nil {
+				send(gunfight.MessageError, gunfight.ErrorPayload{Message: err.Error()})
+				continue
+			}
+
+			select {
+			case votes <- message.Type == gunfight.CommandAccept:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var offer gunfight.RematchOffer
+	reply, err := h.gunfightService.Rematch(ctx, gunfightID, userID, votes, func(update gunfight.RematchOffer) {
+		offer = update
+		send(gunfight.MessageRematch, update)
+	})
+	switch {
+	case err != nil && ctx.Err() != nil:
+	case err != nil:
+		h.logger.Error("Error voting for rematch: ", err)
+		send(gunfight.MessageError, gunfight.ErrorPayload{Message: err.Error()})
+	case reply.Status == gunfight.RematchAccepted:
+		send(gunfight.MessageMatched, gunfight.Match{GunfightID: reply.RematchID, OpponentID: offer.OpponentID})
+	case reply.Status == gunfight.RematchDeclined:
+		send(gunfight.MessageDeclined, reply)
+	default:
+		send(gunfight.MessageExpired, reply)
+	}
 }
 
-func (h *gunfightHandler) extractUserID(r *http.Request) (int, error) {
-	userData, ok := r.Context().Value("user").(map[string]interface{})
-	if !ok {
-		return 0, fmt.Errorf("user data is required")
+// GetQueueStatus retrieves the matchmaking load of a queue.
+// @Summary Retrieve gunfight queue status
+// @Description Fetches the number of players waiting in the queue, in the user's rating band and in total, and the estimated wait based on recent matches in this queue.
+// @Tags gunfight
+// @Accept json
+// @Produce json
+// @Param X-User-Data header string true "User data in encoded format containing user ID and other necessary information"
+// @Param queue query string true "Matchmaking queue, one of the configured queues"
+// @Success 200 {object} gunfight.QueueStatus "Returns the queue status."
+// @Failure 400 {string} string "Bad request - user data is required or invalid, or queue is missing."
+// @Failure 500 {string} string "Internal server error - unknown queue or error getting the queue status."
+// @Router /gunfight/queue [get]
+func (h *gunfightHandler) GetQueueStatus(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.extractUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	userID, ok := userData["id"].(float64)
-	if !ok {
-		return 0, fmt.Errorf("user ID is required and must be a number")
+	queue := r.URL.Query().Get("queue")
+	if queue == "" {
+		http.Error(w, "queue is required", http.StatusBadRequest)
+		return
 	}
 
-	return int(userID), nil
+	ctx := contextutils.NewContext(r, userID, "GetQueueStatus")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	status, err := h.gunfightService.QueueStatus(ctx, userID, queue)
+	if err != nil {
+		h.logger.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
 }
 
-func (h *gunfightHandler) upgradeConnection(w http.ResponseWriter, r *http.Request) (*websocket.Conn, error) {
-	conn, err := h.upgrader.Upgrade(w, r, nil)
+// GetQueueMetrics retrieves the matchmaking queues and their metrics.
+// @Summary Retrieve gunfight queues
+// @Description Fetches every matchmaking queue with its settings, the number of waiting players, counters of searches that ended with a match, a bot, a timeout or a cancel, and the median wait of recent matches.
+// @Tags gunfight
+// @Accept json
+// @Produce json
+// @Param X-User-Data header string true "User data in encoded format containing user ID and other necessary information"
+// @Success 200 {array} gunfight.QueueMetrics "Returns the queues."
+// @Failure 400 {string} string "Bad request - user data is required or invalid."
+// @Failure 500 {string} string "Internal server error - error getting the queue metrics."
+// @Router /gunfight/queues [get]
+func (h *gunfightHandler) GetQueueMetrics(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.extractUserID(r)
 	if err != nil {
-		http.Error(w, "Could not open websocket connection", http.StatusBadRequest)
-		h.logger.Error("Could not open websocket connection: ", err)
-		return nil, err
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
-	return conn, nil
+
+	ctx := contextutils.NewContext(r, userID, "GetQueueMetrics")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	metrics, err := h.gunfightService.GetQueueMetrics(ctx)
+	if err != nil {
+		h.logger.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(metrics)
+}
+
+// GetRating retrieves the gunfight rating of the user.
+// @Summary Retrieve gunfight rating
+// @Description Fetches the Elo rating used for ranked matchmaking and the number of rated games.
+// @Tags gunfight
+// @Accept json
+// @Produce json
+// @Param X-User-Data header string true "User data in encoded format containing user ID and other necessary information"
+// @Success 200 {object} gunfight.RatingResponse "Returns the rating of the user."
+// @Failure 400 {string} string "Bad request - user data is required or invalid."
+// @Failure 500 {string} string "Internal server error - error getting the rating."
+// @Router /gunfight/rating [get]
+func (h *gunfightHandler) GetRating(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.extractUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := contextutils.NewContext(r, userID, "GetRating")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rating, err := h.gunfightService.GetRating(ctx, userID)
+	if err != nil {
+		h.logger.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rating)
+}
+
+// GetHistory retrieves finished gunfights of the user, newest first.
+// @Summary Retrieve gunfight history
+// @Description Fetches a page of finished gunfights, team gunfights included. Pass next_cursor from the previous page as cursor to get the next one.
+// @Tags gunfight
+// @Accept json
+// @Produce json
+// @Param X-User-Data header string true "User data in encoded format containing user ID and other necessary information"
+// @Param cursor query int false "ID of the last gunfight of the previous page"
+// @Param limit query int false "Page size, 20 by default and 100 at most"
+// @Success 200 {object} gunfight.HistoryResponse "Returns a page of the gunfight history."
+// @Failure 400 {string} string "Bad request - user data is required or invalid, or invalid cursor or limit."
+// @Failure 500 {string} string "Internal server error - error getting the history."
+// @Router /gunfight/history [get]
+func (h *gunfightHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.extractUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cursor, err := queryInt(r, "cursor")
+	if err != nil {
+		http.Error(w, "cursor must be a number", http.StatusBadRequest)
+		return
+	}
+
+	limit, err := queryInt(r, "limit")
+	if err != nil {
+		http.Error(w, "limit must be a number", http.StatusBadRequest)
+		return
+	}
+
+	ctx := contextutils.NewContext(r, userID, "GetHistory")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	history, err := h.gunfightService.GetHistory(ctx, userID, cursor, limit)
+	if err != nil {
+		h.logger.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
+
+// GetReplay retrieves the full log of a gunfight.
+// @Summary Retrieve gunfight replay
+// @Description Fetches every event of the duel with its server timestamp: events sent to the players, accepted shots with the connection RTT, forfeits and the moments the pause before each draw starts. Only participants of the gunfight can fetch it.
+// @Tags gunfight
+// @Accept json
+// @Produce json
+// @Param X-User-Data header string true "User data in encoded format containing user ID and other necessary information"
+// @Param id path int true "Gunfight ID"
+// @Success 200 {object} gunfight.ReplayResponse "Returns the duel log."
+// @Failure 400 {string} string "Bad request - invalid gunfight ID."
+// @Failure 500 {string} string "Internal server error - user is not a participant or error getting the duel log."
+// @Router /gunfight/{id}/replay [get]
+func (h *gunfightHandler) GetReplay(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.extractUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	gunfightID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid gunfight ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx := contextutils.NewContext(r, userID, "GetReplay")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	replay, err := h.gunfightService.GetReplay(ctx, gunfightID, userID)
+	if err != nil {
+		h.logger.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(replay)
+}
+
+// GetFairness retrieves the data to audit the random draws of a gunfight.
+// @Summary Audit gunfight randomness
+// @Description Fetches the commit-reveal data of a gunfight: the hash of the server seed published when the gunfight was created and the client seeds of the players. Once the gunfight is finished the server seed is revealed, the draw delay of every round is derived again and checked against the duel log. Any player can audit any gunfight.
+// @Tags gunfight
+// @Accept json
+// @Produce json
+// @Param X-User-Data header string true "User data in encoded format containing user ID and other necessary information"
+// @Param id path int true "Gunfight ID"
+// @Success 200 {object} gunfight.FairnessResponse "Returns the seeds and the re-derived draws."
+// @Failure 400 {string} string "Bad request - invalid gunfight ID."
+// @Failure 500 {string} string "Internal server error - gunfight not found, played before provably fair draws or error getting the duel log."
+// @Router /gunfight/{id}/fairness [get]
+func (h *gunfightHandler) GetFairness(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.extractUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	gunfightID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid gunfight ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx := contextutils.NewContext(r, userID, "GetFairness")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	fairness, err := h.gunfightService.GetFairness(ctx, gunfightID)
+	if err != nil {
+		h.logger.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(fairness)
+}
+
+// GetStats retrieves gunfight statistics of the user.
+// @Summary Retrieve gunfight statistics
+// @Description Fetches wins, losses, win rate, current streak and the most faced opponent. Team gunfights count like in the history: a game is won when the team of the user wins, and the opponent of a team gunfight is the captain of the other team.
+// @Tags gunfight
+// @Accept json
+// @Produce json
+// @Param X-User-Data header string true "User data in encoded format containing user ID and other necessary information"
+// @Success 200 {object} gunfight.StatsResponse "Returns the statistics of the user."
+// @Failure 400 {string} string "Bad request - user data is required or invalid."
+// @Failure 500 {string} string "Internal server error - error getting the statistics."
+// @Router /gunfight/stats [get]
+func (h *gunfightHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.extractUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := contextutils.NewContext(r, userID, "GetStats")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	stats, err := h.gunfightService.GetStats(ctx, userID)
+	if err != nil {
+		h.logger.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+// FindTeamGunfight searches for a 2v2 team gunfight with the party of the user
+// @Summary Initiate team gunfight search
+// @Description Opens a websocket connection and searches for another party of two in the given queue. The party leader runs the search: the stake of the queue is held from every member and the search band is built around the average party rating. Other party members open the same websocket before the leader and wait for the matched message. Every message is a gunfight.Message envelope: the server sends queued, searching (current rating band, leader only), matched (with teammates and opponents), timeout, error and cancelled messages, the client may send a cancel command. There are no bots in team gunfights. In the duel each team shares a health pool of 3 per player, the fastest shot of a team counts as the team's shot and a foul costs the whole team a hit.
+// @Tags gunfight
+// @Accept json
+// @Produce json
+// @Param X-User-Data header string true "User data in encoded format containing user ID and other necessary information"
+// @Param queue query string true "Matchmaking queue, one of the configured queues such as casual, ranked or high_stakes"
+// @Success 200 {object} gunfight.Message "WebSocket connection established, waiting for opponents."
+// @Failure 400 {string} string "Bad request - user data is required or invalid, or queue is missing."
+// @Router /gunfight/party/find [get]
+func (h *gunfightHandler) FindTeamGunfight(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.extractUserID(r)
+	if err != nil {
+		h.logger.Error("Error extracting user ID: ", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	queue := r.URL.Query().Get("queue")
+	if queue == "" {
+		http.Error(w, "queue is required", http.StatusBadRequest)
+		return
+	}
+
+	conn, err := h.upgradeConnection(w, r)
+	if err != nil {
+		h.logger.Error("Error upgrading connection: ", err)
+		return
+	}
+
+	// Ставки группы возвращает сам поиск, когда отменяется ctx
+	ws := wsconn.New(conn, nil)
+	defer ws.Close()
+
+	ctx, cancel := context.WithCancelCause(contextutils.NewContext(r, userID, "FindTeamGunfight"))
+	defer cancel(nil)
+
+	go func() {
+		for data := range ws.Messages() {
+			if message, err := gunfight.DecodeMessage(data); err == nil && message.Type == gunfight.CommandCancel {
+				cancel(errSearchCancelled)
+				return
+			}
+		}
+		cancel(errPeerGone)
+	}()
+
+	var seq int64
+	send := func(messageType string, payload interface{}) {
+		seq++
+		if err := h.writeMessage(ws, messageType, seq, payload); err != nil {
+			h.logger.Error("Error writing to websocket: ", err)
+		}
+	}
+
+	send(gunfight.MessageQueued, gunfight.QueuedPayload{Queue: queue})
+
+	result, err := h.gunfightService.FindTeamGunfight(ctx, userID, queue, func(payload gunfight.SearchingPayload) {
+		send(gunfight.MessageSearching, payload)
+	})
+	switch {
+	case errors.Is(context.Cause(ctx), errSearchCancelled):
+		send(gunfight.MessageCancelled, gunfight.ErrorPayload{Message: "Search cancelled"})
+	case err != nil:
+		h.logger.Error("Error finding team gunfight: ", err)
+		send(gunfight.MessageError, gunfight.ErrorPayload{Message: err.Error()})
+	case result.GunfightID != 0:
+		send(gunfight.MessageMatched, gunfight.Match{
+			GunfightID: result.GunfightID,
+			OpponentID: result.OpponentID,
+			Teammates:  result.Teammates,
+			Opponents:  result.Opponents,
+		})
+	default:
+		send(gunfight.MessageTimeout, gunfight.ErrorPayload{Message: result.Message})
+	}
+}
+
+// InviteToParty invites a player to the party of the user.
+// @Summary Invite to party
+// @Description Invites the player with the given user ID to the party of the user, the party is created with the user as the leader if the user is not in a party yet. Only the leader invites players, a party holds 2 players. The invitation expires after the party invite TTL.
+// @Tags gunfight
+// @Accept json
+// @Produce json
+// @Param X-User-Data header string true "User data in encoded format containing user ID and other necessary information"
+// @Param user_id query int true "ID of the invited player"
+// @Success 200 {object} gunfight.Party "Returns the party."
+// @Failure 400 {string} string "Bad request - user data is required or invalid, or user_id is missing."
+// @Failure 500 {string} string "Internal server error - user is not the leader, the party is full or error inviting the player."
+// @Router /gunfight/party/invite [post]
+func (h *gunfightHandler) InviteToParty(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.extractUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	targetID, err := queryInt(r, "user_id")
+	if err != nil || targetID == 0 {
+		http.Error(w, "user_id is required and must be a number", http.StatusBadRequest)
+		return
+	}
+
+	ctx := contextutils.NewContext(r, userID, "InviteToParty")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	party, err := h.gunfightService.InviteToParty(ctx, userID, targetID)
+	if err != nil {
+		h.logger.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(party)
+}
+
+// AcceptPartyInvite accepts an invitation to a party.
+// @Summary Accept party invite
+// @Description Joins the party the user was invited to. The invitation is used up even if joining fails: the user is already in a party, the party is disbanded, full or searching for a gunfight.
+// @Tags gunfight
+// @Accept json
+// @Produce json
+// @Param X-User-Data header string true "User data in encoded format containing user ID and other necessary information"
+// @Param party_id query string true "Party ID from the invitation"
+// @Success 200 {object} gunfight.Party "Returns the party."
+// @Failure 400 {string} string "Bad request - user data is required or invalid, or party_id is missing."
+// @Failure 500 {string} string "Internal server error - invitation not found or expired, or error joining the party."
+// @Router /gunfight/party/accept [post]
+func (h *gunfightHandler) AcceptPartyInvite(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.extractUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	partyID := r.URL.Query().Get("party_id")
+	if partyID == "" {
+		http.Error(w, "party_id is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx := contextutils.NewContext(r, userID, "AcceptPartyInvite")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	party, err := h.gunfightService.AcceptPartyInvite(ctx, userID, partyID)
+	if err != nil {
+		h.logger.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(party)
+}
+
+// LeaveParty leaves the party of the user.
+// @Summary Leave party
+// @Description Leaves the party of the user, the leader leaving disbands the party. A party can not be left while it is searching for a gunfight.
+// @Tags gunfight
+// @Accept json
+// @Produce json
+// @Param X-User-Data header string true "User data in encoded format containing user ID and other necessary information"
+// @Success 204 "The user left the party."
+// @Failure 400 {string} string "Bad request - user data is required or invalid."
+// @Failure 500 {string} string "Internal server error - user is not in a party, the party is searching or error leaving the party."
+// @Router /gunfight/party/leave [post]
+func (h *gunfightHandler) LeaveParty(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.extractUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := contextutils.NewContext(r, userID, "LeaveParty")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := h.gunfightService.LeaveParty(ctx, userID); err != nil {
+		h.logger.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetParty retrieves the party of the user and the invitations they received.
+// @Summary Retrieve party
+// @Description Fetches the party of the user, null if the user is not in a party, and the invitations to other parties that have not expired yet.
+// @Tags gunfight
+// @Accept json
+// @Produce json
+// @Param X-User-Data header string true "User data in encoded format containing user ID and other necessary information"
+// @Success 200 {object} gunfight.PartyResponse "Returns the party and the invitations."
+// @Failure 400 {string} string "Bad request - user data is required or invalid."
+// @Failure 500 {string} string "Internal server error - error getting the party."
+// @Router /gunfight/party [get]
+func (h *gunfightHandler) GetParty(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.extractUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := contextutils.NewContext(r, userID, "GetParty")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	party, err := h.gunfightService.GetParty(ctx, userID)
+	if err != nil {
+		h.logger.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(party)
+}
+
+// PlaceBet places a spectator bet on a side of a gunfight.
+// @Summary Bet on gunfight
+// @Description Bets silver on a side of the gunfight, the silver is taken from the balance of the user right away. Bets are accepted until the first round starts, one bet per gunfight, and the players of the gunfight can not bet on it. The pool is pari-mutuel: when the gunfight gets a winner, bets on the winning side share the whole pool minus the house fee in proportion to the bets. A gunfight without a winner, a gunfight won because the opponent did not join or left, or a pool with bets on one side only refunds the bets. The sum of the bets of a user over the last 24 hours is limited.
+// @Tags gunfight
+// @Accept json
+// @Produce json
+// @Param X-User-Data header string true "User data in encoded format containing user ID and other necessary information"
+// @Param id path int true "Gunfight ID"
+// @Param side query int true "ID of the player, or of the team captain, the bet is on"
+// @Param silver query int true "Bet in silver, within the bet limits"
+// @Success 200 {object} gunfight.BetItem "Returns the placed bet."
+// @Failure 400 {string} string "Bad request - invalid gunfight ID, or side or silver is missing."
+// @Failure 500 {string} string "Internal server error - betting is closed, the user plays in the gunfight, already bet, the bet is out of limits or not enough silver."
+// @Router /gunfight/{id}/bets [post]
+func (h *gunfightHandler) PlaceBet(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.extractUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	gunfightID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid gunfight ID", http.StatusBadRequest)
+		return
+	}
+
+	side, err := queryInt(r, "side")
+	if err != nil || side == 0 {
+		http.Error(w, "side is required and must be a number", http.StatusBadRequest)
+		return
+	}
+
+	silver, err := queryInt(r, "silver")
+	if err != nil || silver <= 0 {
+		http.Error(w, "silver is required and must be a positive number", http.StatusBadRequest)
+		return
+	}
+
+	ctx := contextutils.NewContext(r, userID, "PlaceBet")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	bet, err := h.gunfightService.PlaceBet(ctx, userID, gunfightID, side, silver)
+	if err != nil {
+		h.logger.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bet)
+}
+
+// GetBetPool retrieves the betting pool of a gunfight.
+// @Summary Retrieve gunfight betting pool
+// @Description Fetches the spectator bets of the gunfight by side, whether bets are still accepted, the house fee and the bet of the user.
+// @Tags gunfight
+// @Accept json
+// @Produce json
+// @Param X-User-Data header string true "User data in encoded format containing user ID and other necessary information"
+// @Param id path int true "Gunfight ID"
+// @Success 200 {object} gunfight.BetPoolResponse "Returns the betting pool."
+// @Failure 400 {string} string "Bad request - invalid gunfight ID."
+// @Failure 500 {string} string "Internal server error - gunfight not found or error getting the bets."
+// @Router /gunfight/{id}/bets [get]
+func (h *gunfightHandler) GetBetPool(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.extractUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	gunfightID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid gunfight ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx := contextutils.NewContext(r, userID, "GetBetPool")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	pool, err := h.gunfightService.GetBetPool(ctx, userID, gunfightID)
+	if err != nil {
+		h.logger.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pool)
+}
+
+// PostBounty posts a gold bounty on another player.
+// @Summary Post bounty
+// @Description Posts a bounty on the target player, the gold is taken from the balance of the user right away. The player who beats the target in a gunfight collects the bounty when the winner is recorded, in a team gunfight it is split between the winners. Only gunfights against an opponent from a queue or ranked gunfights claim bounties: challenges, rematches and gunfights against bots do not. An unclaimed bounty expires after the bounty TTL and the gold returns to the user.
+// @Tags gunfight
+// @Accept json
+// @Produce json
+// @Param X-User-Data header string true "User data in encoded format containing user ID and other necessary information"
+// @Param target_id query int true "ID of the wanted player"
+// @Param gold query int true "Bounty in gold, at least the minimum bounty"
+// @Success 200 {object} gunfight.BountyItem "Returns the posted bounty."
+// @Failure 400 {string} string "Bad request - user data is required or invalid, or target_id or gold is missing."
+// @Failure 500 {string} string "Internal server error - bounty on yourself, bounty below the minimum, target not found or not enough gold."
+// @Router /gunfight/bounties [post]
+func (h *gunfightHandler) PostBounty(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.extractUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	targetID, err := queryInt(r, "target_id")
+	if err != nil || targetID == 0 {
+		http.Error(w, "target_id is required and must be a number", http.StatusBadRequest)
+		return
+	}
+
+	gold, err := queryInt(r, "gold")
+	if err != nil || gold <= 0 {
+		http.Error(w, "gold is required and must be a positive number", http.StatusBadRequest)
+		return
+	}
+
+	ctx := contextutils.NewContext(r, userID, "PostBounty")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	bounty, err := h.gunfightService.PostBounty(ctx, userID, targetID, gold)
+	if err != nil {
+		h.logger.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bounty)
+}
+
+// GetWanted retrieves the Wanted board.
+// @Summary Retrieve Wanted board
+// @Description Fetches the open bounties sorted by gold, the largest first. Several bounties on the same player are listed separately and are all collected by the player who beats the target.
+// @Tags gunfight
+// @Accept json
+// @Produce json
+// @Param X-User-Data header string true "User data in encoded format containing user ID and other necessary information"
+// @Success 200 {array} gunfight.BountyItem "Returns the open bounties."
+// @Failure 400 {string} string "Bad request - user data is required or invalid."
+// @Failure 500 {string} string "Internal server error - error getting the bounties."
+// @Router /gunfight/bounties [get]
+func (h *gunfightHandler) GetWanted(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.extractUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := contextutils.NewContext(r, userID, "GetWanted")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	bounties, err := h.gunfightService.GetWanted(ctx)
+	if err != nil {
+		h.logger.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bounties)
+}
+
+// GetTournaments retrieves the tournaments open for registration and the running ones.
+// @Summary Retrieve gunfight tournaments
+// @Description Fetches the single-elimination tournaments that are open for registration or running, ordered by start date. Tournaments are scheduled automatically, registration closes at the start date.
+// @Tags gunfight
+// @Accept json
+// @Produce json
+// @Param X-User-Data header string true "User data in encoded format containing user ID and other necessary information"
+// @Success 200 {array} gunfight.TournamentItem "Returns the tournaments."
+// @Failure 400 {string} string "Bad request - user data is required or invalid."
+// @Failure 500 {string} string "Internal server error - error getting the tournaments."
+// @Router /gunfight/tournaments [get]
+func (h *gunfightHandler) GetTournaments(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.extractUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := contextutils.NewContext(r, userID, "GetTournaments")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	tournaments, err := h.gunfightService.GetTournaments(ctx)
+	if err != nil {
+		h.logger.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tournaments)
+}
+
+// GetBracket retrieves the bracket of a tournament.
+// @Summary Retrieve tournament bracket
+// @Description Fetches the tournament, its players with seeds and prizes, and the matches of every started round. Players are seeded by rating, a match without an opponent or whose opponent did not show up is a walkover.
+// @Tags gunfight
+// @Accept json
+// @Produce json
+// @Param X-User-Data header string true "User data in encoded format containing user ID and other necessary information"
+// @Param id path int true "Tournament ID"
+// @Success 200 {object} gunfight.BracketResponse "Returns the bracket."
+// @Failure 400 {string} string "Bad request - invalid tournament ID."
+// @Failure 500 {string} string "Internal server error - tournament not found or error getting the bracket."
+// @Router /gunfight/tournaments/{id} [get]
+func (h *gunfightHandler) GetBracket(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.extractUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tournamentID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid tournament ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx := contextutils.NewContext(r, userID, "GetBracket")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	bracket, err := h.gunfightService.GetBracket(ctx, tournamentID)
+	if err != nil {
+		h.logger.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bracket)
+}
+
+// RegisterTournament registers the user for a tournament.
+// @Summary Register for tournament
+// @Description Registers the user for the tournament and deducts the entry fee in gold. Registration is open until the start date while there are places left.
+// @Tags gunfight
+// @Accept json
+// @Produce json
+// @Param X-User-Data header string true "User data in encoded format containing user ID and other necessary information"
+// @Param id path int true "Tournament ID"
+// @Success 204 "The user is registered."
+// @Failure 400 {string} string "Bad request - invalid tournament ID."
+// @Failure 500 {string} string "Internal server error - registration is closed, no places left, not enough gold or the user is already registered."
+// @Router /gunfight/tournaments/{id}/register [post]
+func (h *gunfightHandler) RegisterTournament(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.extractUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tournamentID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid tournament ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx := contextutils.NewContext(r, userID, "RegisterTournament")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := h.gunfightService.RegisterTournament(ctx, tournamentID, userID); err != nil {
+		h.logger.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnregisterTournament withdraws the user from a tournament.
+// @Summary Unregister from tournament
+// @Description Withdraws the user from the tournament before the start date and refunds the entry fee.
+// @Tags gunfight
+// @Accept json
+// @Produce json
+// @Param X-User-Data header string true "User data in encoded format containing user ID and other necessary information"
+// @Param id path int true "Tournament ID"
+// @Success 204 "The user is unregistered."
+// @Failure 400 {string} string "Bad request - invalid tournament ID."
+// @Failure 500 {string} string "Internal server error - registration is closed, the user is not registered or error refunding the fee."
+// @Router /gunfight/tournaments/{id}/unregister [post]
+func (h *gunfightHandler) UnregisterTournament(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.extractUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tournamentID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid tournament ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx := contextutils.NewContext(r, userID, "UnregisterTournament")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := h.gunfightService.UnregisterTournament(ctx, tournamentID, userID); err != nil {
+		h.logger.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *gunfightHandler) handleDuelCommand(seat *service.DuelSeat, ws *wsconn.Conn, data []byte) error {
+	message, err := gunfight.DecodeMessage(data)
+	if err != nil {
+		return err
+	}
+
+	switch message.Type {
+	case gunfight.CommandShoot:
+		var command gunfight.DuelCommand
+		if err := message.DecodePayload(&command); err != nil {
+			return err
+		}
+		seat.Send(command, ws.RTT())
+		return nil
+	case gunfight.CommandCancel:
+		return errDuelLeft
+	}
+	return fmt.Errorf("unknown command %q", message.Type)
+}
+
+func (h *gunfightHandler) handleChallengeCommand(ctx context.Context, userID int, data []byte, challenges *sync.Map, send func(string, interface{})) error {
+	message, err := gunfight.DecodeMessage(data)
+	if err != nil {
+		return err
+	}
+
+	if message.Type != gunfight.CommandAccept && message.Type != gunfight.CommandDecline {
+		return fmt.Errorf("unknown command %q", message.Type)
+	}
+
+	var command gunfight.ChallengeCommand
+	if err := message.DecodePayload(&command); err != nil {
+		return err
+	}
+
+	reply, err := h.gunfightService.RespondChallenge(ctx, userID, command.ChallengeID, message.Type == gunfight.CommandAccept)
+	challenge, _ := challenges.LoadAndDelete(command.ChallengeID)
+	if err != nil {
+		return err
+	}
+
+	if reply.Status != gunfight.ChallengeAccepted {
+		send(gunfight.MessageDeclined, reply)
+		return nil
+	}
+
+	match := gunfight.Match{GunfightID: reply.GunfightID}
+	if challenge, ok := challenge.(gunfight.Challenge); ok {
+		match.OpponentID = challenge.ChallengerID
+	}
+	send(gunfight.MessageMatched, match)
+	return nil
+}
+
+func (h *gunfightHandler) writeMessage(ws *wsconn.Conn, messageType string, seq int64, payload interface{}) error {
+	data, err := gunfight.EncodeMessage(messageType, seq, payload)
+	if err != nil {
+		return err
+	}
+	if !ws.Send(data) {
+		return fmt.Errorf("connection closed")
+	}
+	return nil
+}
+
+func (h *gunfightHandler) extractUserID(r *http.Request) (int, error) {
+	userData, ok := r.Context().Value("user").(map[string]interface{})
+	if !ok {
+		return 0, fmt.Errorf("user data is required")
+	}
+
+	userID, ok := userData["id"].(float64)
+	if !ok {
+		return 0, fmt.Errorf("user ID is required and must be a number")
+	}
+
+	return int(userID), nil
+}
+
+func (h *gunfightHandler) upgradeConnection(w http.ResponseWriter, r *http.Request) (*websocket.Conn, error) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		http.Error(w, "Could not open websocket connection", http.StatusBadRequest)
+		h.logger.Error("Could not open websocket connection: ", err)
+		return nil, err
+	}
+	return conn, nil
+}
+
+// queryInt reads an optional integer query parameter, a missing parameter is 0
+func queryInt(r *http.Request, name string) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
 }
@@ -4,11 +4,37 @@ import "net/http"
 
 type GunfightHandler interface {
 	FindGunfight(w http.ResponseWriter, r *http.Request)
+	PlayGunfight(w http.ResponseWriter, r *http.Request)
+	WatchGunfight(w http.ResponseWriter, r *http.Request)
+	GetReplay(w http.ResponseWriter, r *http.Request)
+	GetFairness(w http.ResponseWriter, r *http.Request)
+	RematchGunfight(w http.ResponseWriter, r *http.Request)
+	ChallengeGunfight(w http.ResponseWriter, r *http.Request)
+	ListenChallenges(w http.ResponseWriter, r *http.Request)
+	FindTeamGunfight(w http.ResponseWriter, r *http.Request)
+	InviteToParty(w http.ResponseWriter, r *http.Request)
+	AcceptPartyInvite(w http.ResponseWriter, r *http.Request)
+	LeaveParty(w http.ResponseWriter, r *http.Request)
+	GetParty(w http.ResponseWriter, r *http.Request)
+	GetQueueStatus(w http.ResponseWriter, r *http.Request)
+	GetQueueMetrics(w http.ResponseWriter, r *http.Request)
+	GetRating(w http.ResponseWriter, r *http.Request)
+	GetHistory(w http.ResponseWriter, r *http.Request)
+	GetStats(w http.ResponseWriter, r *http.Request)
+	PlaceBet(w http.ResponseWriter, r *http.Request)
+	GetBetPool(w http.ResponseWriter, r *http.Request)
+	PostBounty(w http.ResponseWriter, r *http.Request)
+	GetWanted(w http.ResponseWriter, r *http.Request)
+	GetTournaments(w http.ResponseWriter, r *http.Request)
+	GetBracket(w http.ResponseWriter, r *http.Request)
+	RegisterTournament(w http.ResponseWriter, r *http.Request)
+	UnregisterTournament(w http.ResponseWriter, r *http.Request)
 }
 
 type HorseHandler interface {
 	GetHorse(w http.ResponseWriter, r *http.Request)
 	UpgradeHorse(w http.ResponseWriter, r *http.Request)
+	StartRace(w http.ResponseWriter, r *http.Request)
 	GameHorse(w http.ResponseWriter, r *http.Request)
 }
 
@@ -103,18 +103,57 @@ func (h *horseHandler) UpgradeHorse(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// StartRace starts a horse race session for the user ID from the context.
+// @Summary Start horse race
+// @Description Starts a race session on the server with the server time of the start and the current speed of the horse. The race is finished with the returned race ID before the session expires, starting a new race replaces the previous session.
+// @Tags horse
+// @Accept json
+// @Produce json
+// @Param X-User-Data header string true "User data in encoded format containing user ID and other necessary information"
+// @Success 200 {object} horse.RaceResponse "Returns the race session."
+// @Failure 400 {string} string "Bad request - user data is required or invalid."
+// @Failure 500 {string} string "Internal server error - no horse found for the user ID or error starting the race."
+// @Router /horse/start [post]
+func (h *horseHandler) StartRace(w http.ResponseWriter, r *http.Request) {
+	userData, ok := r.Context().Value("user").(map[string]interface{})
+	if !ok {
+		http.Error(w, "User data is required", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := userData["id"].(float64)
+	if !ok {
+		http.Error(w, "User ID is required and must be a number", http.StatusBadRequest)
+		return
+	}
+
+	ctx := contextutils.NewContext(r, int(userID), "StartRace")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	race, err := h.service.StartRace(ctx, int(userID))
+	if err != nil {
+		h.logger.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(race)
+}
+
 // GameHorse finishes a horse race and updates the horse's record and earnings based on the distance covered and user ID from the context.
 // @Summary Finish horse race
-// @Description Completes the race for the horse and updates its record and earnings according to the distance covered, with the user ID extracted from the context.
+// @Description Completes the race started with /horse/start and updates the horse's record and earnings according to the distance covered. The race session can be finished once and only before it expires; the accepted distance is capped at what the horse covers at its start speed per second in the time elapsed since the start.
 // @Tags horse
 // @Accept json
 // @Produce json
 // @Param X-User-Data header string true "User data in encoded format containing user ID and other necessary information"
-// @Param body body horse.GameRequest true "Race completion details including the distance covered."
+// @Param body body horse.GameRequest true "Race completion details including the race ID and the distance covered."
 // @Success 200 {object} horse.GameResponse "Returns the results of the race finish with updated horse data and earnings."
-// @Failure 400 {string} string "Bad request - user data is required or invalid, or the request body is malformed."
+// @Failure 400 {string} string "Bad request - user data is required or invalid, the request body is malformed or race_id is missing."
 // @Failure 404 {string} string "Not found - no horse found to finish race for the user ID."
-// @Failure 500 {string} string "Internal server error - error during the race finish process."
+// @Failure 500 {string} string "Internal server error - race session expired, already finished or replaced, or error during the race finish process."
 // @Router /horse/finish [post]
 func (h *horseHandler) GameHorse(w http.ResponseWriter, r *http.Request) {
 	userData, ok := r.Context().Value("user").(map[string]interface{})
@@ -135,6 +174,10 @@ func (h *horseHandler) GameHorse(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid request body", http.StatusBadRequest)
 		return
 	}
+	if requestData.RaceID == "" {
+		http.Error(w, "race_id is required", http.StatusBadRequest)
+		return
+	}
 
 	ctx := contextutils.NewContext(r, int(userID), "GameHorse")
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
@@ -12,6 +12,163 @@ type Game struct {
 	User1ID   int       `gorm:"not null;column:user_1_id"`
 	User2ID   int       `gorm:"not null;column:user_2_id"`
 	WinnerID  *int      `gorm:"check:winner_id IS NULL OR winner_id = user_1_id OR winner_id = user_2_id"`
+	Stake     int       `gorm:"not null;default:0"`
+	Ranked    bool      `gorm:"not null;default:false"`
+	Bot       string    `gorm:"size:32;not null;default:''"`
+	Queue     string    `gorm:"size:32;not null;default:''"`
+	RematchOf *int      `gorm:"column:rematch_of"`
+	TeamSize  int       `gorm:"not null;default:1"`
 	StartDate time.Time `gorm:"default:CURRENT_TIMESTAMP"`
 	EndDate   *time.Time
+
+	// ServerSeed — секрет, из которого выводятся случайные паузы дуэли, раскрывается только после окончания игры;
+	// SeedHash публикуется сразу
+	ServerSeed string `gorm:"size:64;not null;default:''"`
+	SeedHash   string `gorm:"size:64;not null;default:''"`
+	// BetsClosed — ставки зрителей больше не принимаются: начался первый раунд
+	BetsClosed bool `gorm:"not null;default:false"`
+
+	// Players — состав команд, который записывается при создании игры; без него команды — user_1_id и user_2_id
+	Players []Player `gorm:"-"`
+	// Team — команда игрока, для которого игра загружена в историю
+	Team int `gorm:"->"`
+}
+
+// Player is a member of a gunfight team, team 1 is led by user_1_id and team 2 by user_2_id
+type Player struct {
+	GunfightID int    `gorm:"not null;column:gunfight_id"`
+	UserID     int    `gorm:"not null"`
+	Team       int    `gorm:"not null"`
+	ClientSeed string `gorm:"size:64;not null;default:''"`
+}
+
+// Result is what gets recorded when a game ends: the winner (nil for a draw or a cancelled game),
+// the gold paid out to each of the winners and the rating change of every player.
+// In a team game WinnerID is the captain of the winning team and Winners are its members
+type Result struct {
+	WinnerID      *int
+	Winners       []int
+	Prize         int
+	RatingChanges map[int]int
+	// BetFee — комиссия заведения с банка ставок зрителей в процентах
+	BetFee int
+	// RefundBets — ставки зрителей возвращаются, даже если победитель есть: дуэль не доиграна
+	// из-за неявки или ухода игрока
+	RefundBets bool
+	// Journal — записи журнала дуэли, которые пишутся в одной транзакции с итогом
+	Journal []Event
+}
+
+type Health struct {
+	GunfightID int `gorm:"not null;column:gunfight_id"`
+	UserID     int `gorm:"not null"`
+	Health     int `gorm:"default:3"`
+}
+
+type Escrow struct {
+	ID         int  `gorm:"primaryKey;autoIncrement"`
+	UserID     int  `gorm:"not null"`
+	GunfightID *int `gorm:"column:gunfight_id"`
+	Gold       int  `gorm:"not null"`
+}
+
+const DefaultRating = 1000
+
+// Tournament is a scheduled single-elimination tournament, Round is the round being played, 0 before the start
+type Tournament struct {
+	ID        int    `gorm:"primaryKey;autoIncrement"`
+	Size      int    `gorm:"not null"`
+	EntryFee  int    `gorm:"not null;default:0"`
+	Status    string `gorm:"size:16;not null;default:registration"`
+	Round     int    `gorm:"not null;default:0"`
+	WinnerID  *int
+	StartDate time.Time `gorm:"not null"`
+	EndDate   *time.Time
+
+	// Players — число зарегистрированных игроков, считается при загрузке списка турниров
+	Players int `gorm:"->"`
+}
+
+// TournamentPlayer is a registered player, Seed and Rating are set when the tournament starts
+type TournamentPlayer struct {
+	TournamentID int `gorm:"not null;column:tournament_id"`
+	UserID       int `gorm:"not null"`
+	Seed         *int
+	Rating       *int
+	Prize        int       `gorm:"not null;default:0"`
+	CreatedAt    time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TournamentMatch is a bracket slot: a gunfight between two players, a walkover when one of them is missing
+// or an empty match when both are missing
+type TournamentMatch struct {
+	ID           int  `gorm:"primaryKey;autoIncrement"`
+	TournamentID int  `gorm:"not null;column:tournament_id"`
+	Round        int  `gorm:"not null"`
+	Position     int  `gorm:"not null"`
+	User1ID      *int `gorm:"column:user_1_id"`
+	User2ID      *int `gorm:"column:user_2_id"`
+	GunfightID   *int `gorm:"column:gunfight_id"`
+	WinnerID     *int
+	Walkover     bool `gorm:"not null;default:false"`
+	Decided      bool `gorm:"not null;default:false"`
+}
+
+// Bounty is gold posted on a target player, held until a player who beats the target in a gunfight
+// collects it or until it expires and returns to the poster
+type Bounty struct {
+	ID         int       `gorm:"primaryKey;autoIncrement"`
+	PosterID   int       `gorm:"not null;column:poster_id"`
+	TargetID   int       `gorm:"not null;column:target_id"`
+	Gold       int       `gorm:"not null"`
+	Status     string    `gorm:"size:16;not null;default:'open'"`
+	HunterID   *int      `gorm:"column:hunter_id"`
+	GunfightID *int      `gorm:"column:gunfight_id"`
+	CreatedAt  time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	ExpiresAt  time.Time `gorm:"not null"`
+	EndDate    *time.Time
+}
+
+// Bet is a spectator bet in silver on a side of a gunfight, Side is the player or the team captain
+type Bet struct {
+	ID         int       `gorm:"primaryKey;autoIncrement"`
+	GunfightID int       `gorm:"not null;column:gunfight_id"`
+	UserID     int       `gorm:"not null"`
+	Side       int       `gorm:"not null"`
+	Silver     int       `gorm:"not null"`
+	Status     string    `gorm:"size:16;not null;default:'open'"`
+	Payout     int       `gorm:"not null;default:0"`
+	CreatedAt  time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	SettledAt  *time.Time
+}
+
+type Rating struct {
+	UserID int `gorm:"primaryKey;column:user_id"`
+	Rating int `gorm:"not null;default:1000"`
+	Games  int `gorm:"not null;default:0"`
+}
+
+// Stats are aggregated over the finished games of a player, Streak is positive for wins and negative for losses
+type Stats struct {
+	Wins                int
+	Losses              int
+	Draws               int
+	Streak              int
+	MostFacedOpponentID *int
+	MostFacedGames      int
+}
+
+// Event is a row of the append-only duel log: events sent to the players, accepted shots, forfeits and the moments
+// the pause before the draw starts.
+// RTT of a shot is in microseconds, Payload holds the DuelEvent of server events
+type Event struct {
+	ID         int64  `gorm:"primaryKey;autoIncrement"`
+	GunfightID int    `gorm:"not null;column:gunfight_id"`
+	Seq        int64  `gorm:"not null;default:0"`
+	Type       string `gorm:"size:32;not null"`
+	UserID     *int
+	Round      int       `gorm:"not null;default:0"`
+	RTT        int64     `gorm:"column:rtt;not null;default:0"`
+	Payload    []byte    `gorm:"type:jsonb"`
+	CreatedAt  time.Time `gorm:"not null"`
 }
@@ -1,10 +1,417 @@
 package gunfight
 
+import (
+	"encoding/json"
+	"time"
+)
+
 //type QueueRequest struct {
 //	Gold int `json:"gold" example:"100" extensions:"x-order=2"`
 //}
 
 type QueueResponse struct {
 	OpponentID int
+	GunfightID int
+	Bot        string
+	Teammates  []int
+	Opponents  []int
 	Message    string
 }
+
+// RatingResponse represents the gunfight rating
+// @Description Elo rating of the player and the number of rated games
+type RatingResponse struct {
+	Rating int `json:"rating" example:"1000" extensions:"x-order=1"`
+	Games  int `json:"games" example:"10" extensions:"x-order=2"`
+}
+
+// HistoryItem represents a finished gunfight from the player's point of view
+// @Description Finished gunfight: result is win, loss or draw, rematch_of links a rematch to the previous game.
+// @Description In a team gunfight (team_size 2) opponent_id and winner_id are team captains
+type HistoryItem struct {
+	ID         int        `json:"id" example:"1" extensions:"x-order=1"`
+	OpponentID int        `json:"opponent_id" example:"2" extensions:"x-order=2"`
+	WinnerID   *int       `json:"winner_id" example:"2" extensions:"x-order=3"`
+	Result     string     `json:"result" example:"loss" extensions:"x-order=4"`
+	Stake      int        `json:"stake" example:"100" extensions:"x-order=5"`
+	Bot        string     `json:"bot,omitempty" example:"deputy" extensions:"x-order=6"`
+	Queue      string     `json:"queue,omitempty" example:"ranked" extensions:"x-order=7"`
+	TeamSize   int        `json:"team_size" example:"1" extensions:"x-order=8"`
+	RematchOf  *int       `json:"rematch_of,omitempty" example:"1" extensions:"x-order=9"`
+	StartDate  time.Time  `json:"start_date" extensions:"x-order=10"`
+	EndDate    *time.Time `json:"end_date" extensions:"x-order=11"`
+}
+
+// HistoryResponse is a page of the gunfight history, next_cursor is passed as cursor to get the next page
+type HistoryResponse struct {
+	Items      []HistoryItem `json:"items" extensions:"x-order=1"`
+	NextCursor int           `json:"next_cursor,omitempty" example:"1" extensions:"x-order=2"`
+}
+
+// StatsResponse represents the gunfight statistics
+// @Description Results of finished gunfights, streak is positive for wins and negative for losses
+type StatsResponse struct {
+	Wins                int     `json:"wins" example:"10" extensions:"x-order=1"`
+	Losses              int     `json:"losses" example:"5" extensions:"x-order=2"`
+	Draws               int     `json:"draws" example:"1" extensions:"x-order=3"`
+	WinRate             float64 `json:"win_rate" example:"0.67" extensions:"x-order=4"`
+	Streak              int     `json:"streak" example:"3" extensions:"x-order=5"`
+	MostFacedOpponentID *int    `json:"most_faced_opponent_id" example:"2" extensions:"x-order=6"`
+	MostFacedGames      int     `json:"most_faced_games" example:"4" extensions:"x-order=7"`
+}
+
+const (
+	ResultWin  = "win"
+	ResultLoss = "loss"
+	ResultDraw = "draw"
+)
+
+// Band is the rating range in which an opponent is searched, it widens while the player waits
+type Band struct {
+	Percent   int `json:"percent" example:"5"`
+	MinRating int `json:"min_rating" example:"950"`
+	MaxRating int `json:"max_rating" example:"1050"`
+}
+
+// QueueStatus shows how busy a matchmaking queue is: players waiting in the rating band and in the whole queue.
+// Estimated wait is in seconds, it is null until matches in this queue have been recorded recently
+// @Description Matchmaking load and the estimated wait in seconds
+type QueueStatus struct {
+	Queue         string `json:"queue" example:"ranked" extensions:"x-order=1"`
+	Stake         int    `json:"stake" example:"100" extensions:"x-order=2"`
+	Band          Band   `json:"band" extensions:"x-order=3"`
+	InBand        int    `json:"in_band" example:"3" extensions:"x-order=4"`
+	Total         int    `json:"total" example:"12" extensions:"x-order=5"`
+	EstimatedWait *int   `json:"estimated_wait" example:"20" extensions:"x-order=6"`
+}
+
+// QueueMetrics describes a matchmaking queue and counts how searches in it ended: matched with a player, with a bot,
+// timed out or cancelled. Median wait is in seconds over recent matches, null if there were none
+// @Description Matchmaking queue settings and search outcome counters
+type QueueMetrics struct {
+	Name       string `json:"name" example:"ranked" extensions:"x-order=1"`
+	Stake      int    `json:"stake" example:"100" extensions:"x-order=2"`
+	Ranked     bool   `json:"ranked" example:"true" extensions:"x-order=3"`
+	MinBalance int    `json:"min_balance" example:"0" extensions:"x-order=4"`
+	HouseFee   int    `json:"house_fee" example:"5" extensions:"x-order=5"`
+	Timeout    int    `json:"timeout" example:"60" extensions:"x-order=6"`
+	Waiting    int    `json:"waiting" example:"4" extensions:"x-order=7"`
+	Matched    int    `json:"matched" example:"120" extensions:"x-order=8"`
+	Bots       int    `json:"bots" example:"15" extensions:"x-order=9"`
+	Timeouts   int    `json:"timeouts" example:"7" extensions:"x-order=10"`
+	Cancelled  int    `json:"cancelled" example:"9" extensions:"x-order=11"`
+	MedianWait *int   `json:"median_wait" example:"20" extensions:"x-order=12"`
+}
+
+// Search outcomes counted per queue
+const (
+	QueueMatched   = "matched"
+	QueueBot       = "bots"
+	QueueTimeout   = "timeouts"
+	QueueCancelled = "cancelled"
+)
+
+// Match is published to the waiting player when someone is matched with them and is the payload of the matched message,
+// bot is set when the opponent is a bot. In a team gunfight opponent_id is the captain of the other team
+// and teammates and opponents list the players of both teams
+type Match struct {
+	GunfightID int    `json:"gunfight_id" example:"1"`
+	OpponentID int    `json:"opponent_id" example:"2"`
+	Bot        string `json:"bot,omitempty" example:"deputy"`
+	Teammates  []int  `json:"teammates,omitempty"`
+	Opponents  []int  `json:"opponents,omitempty"`
+}
+
+// DuelState is the state of a running duel kept in Redis, so that a duel can be resumed after a reconnect
+type DuelState struct {
+	GunfightID int         `json:"gunfight_id"`
+	Players    []int       `json:"players"`
+	Health     map[int]int `json:"health"`
+	Round      int         `json:"round"`
+	Seq        int64       `json:"seq"`
+}
+
+// Party is a group of players that searches for a team gunfight together, the leader invites players and starts the search
+// @Description Party for team gunfights, members include the leader
+type Party struct {
+	ID       string `json:"id" example:"0b6f5a0e-8d0c-4f8e-a1c3-2f3d5c9e7b21" extensions:"x-order=1"`
+	LeaderID int    `json:"leader_id" example:"1" extensions:"x-order=2"`
+	Members  []int  `json:"members" extensions:"x-order=3"`
+}
+
+// PartyInvite is an invitation to join a party, it expires at expires_at
+type PartyInvite struct {
+	PartyID   string    `json:"party_id" example:"0b6f5a0e-8d0c-4f8e-a1c3-2f3d5c9e7b21" extensions:"x-order=1"`
+	LeaderID  int       `json:"leader_id" example:"1" extensions:"x-order=2"`
+	ExpiresAt time.Time `json:"expires_at" extensions:"x-order=3"`
+}
+
+// PartyResponse is the party of the player, null if the player is not in a party, and the invitations they have received
+type PartyResponse struct {
+	Party   *Party        `json:"party" extensions:"x-order=1"`
+	Invites []PartyInvite `json:"invites" extensions:"x-order=2"`
+}
+
+// TournamentItem describes a tournament: pot is the sum of entry fees, round is the round being played, 0 before the start
+// @Description Single-elimination tournament, status is registration, running, finished or cancelled
+type TournamentItem struct {
+	ID        int        `json:"id" example:"1" extensions:"x-order=1"`
+	Size      int        `json:"size" example:"16" extensions:"x-order=2"`
+	EntryFee  int        `json:"entry_fee" example:"100" extensions:"x-order=3"`
+	Status    string     `json:"status" example:"registration" extensions:"x-order=4"`
+	Players   int        `json:"players" example:"11" extensions:"x-order=5"`
+	Pot       int        `json:"pot" example:"1100" extensions:"x-order=6"`
+	Round     int        `json:"round" example:"0" extensions:"x-order=7"`
+	WinnerID  *int       `json:"winner_id" example:"1" extensions:"x-order=8"`
+	StartDate time.Time  `json:"start_date" extensions:"x-order=9"`
+	EndDate   *time.Time `json:"end_date" extensions:"x-order=10"`
+}
+
+// TournamentPlayerItem is a registered player, seed and rating are null until the tournament starts
+type TournamentPlayerItem struct {
+	UserID int  `json:"user_id" example:"1" extensions:"x-order=1"`
+	Seed   *int `json:"seed" example:"1" extensions:"x-order=2"`
+	Rating *int `json:"rating" example:"1240" extensions:"x-order=3"`
+	Prize  int  `json:"prize" example:"600" extensions:"x-order=4"`
+}
+
+// TournamentMatchItem is a bracket match: walkover is true when a player advanced without a gunfight,
+// decided is true once the winner is known
+type TournamentMatchItem struct {
+	Position   int  `json:"position" example:"0" extensions:"x-order=1"`
+	User1ID    *int `json:"user_1_id" example:"1" extensions:"x-order=2"`
+	User2ID    *int `json:"user_2_id" example:"16" extensions:"x-order=3"`
+	GunfightID *int `json:"gunfight_id" example:"42" extensions:"x-order=4"`
+	WinnerID   *int `json:"winner_id" example:"1" extensions:"x-order=5"`
+	Walkover   bool `json:"walkover" example:"false" extensions:"x-order=6"`
+	Decided    bool `json:"decided" example:"true" extensions:"x-order=7"`
+}
+
+// BracketResponse is a tournament with its players and the matches of every started round, rounds[0] is the first round
+type BracketResponse struct {
+	Tournament TournamentItem          `json:"tournament" extensions:"x-order=1"`
+	Players    []TournamentPlayerItem  `json:"players" extensions:"x-order=2"`
+	Rounds     [][]TournamentMatchItem `json:"rounds" extensions:"x-order=3"`
+}
+
+const (
+	TournamentRegistration = "registration"
+	TournamentRunning      = "running"
+	TournamentFinished     = "finished"
+	TournamentCancelled    = "cancelled"
+)
+
+// BountyItem is a bounty on the Wanted board
+// @Description Gold posted on a target player: the player who beats the target in a gunfight collects it, an unclaimed bounty returns to the poster when it expires
+type BountyItem struct {
+	ID        int       `json:"id" example:"1" extensions:"x-order=1"`
+	PosterID  int       `json:"poster_id" example:"1" extensions:"x-order=2"`
+	TargetID  int       `json:"target_id" example:"2" extensions:"x-order=3"`
+	Gold      int       `json:"gold" example:"500" extensions:"x-order=4"`
+	CreatedAt time.Time `json:"created_at" extensions:"x-order=5"`
+	ExpiresAt time.Time `json:"expires_at" extensions:"x-order=6"`
+}
+
+// BetItem is a spectator bet, payout is the silver returned to the bettor once the bet is settled
+type BetItem struct {
+	ID         int       `json:"id" example:"1" extensions:"x-order=1"`
+	GunfightID int       `json:"gunfight_id" example:"1" extensions:"x-order=2"`
+	Side       int       `json:"side" example:"2" extensions:"x-order=3"`
+	Silver     int       `json:"silver" example:"100" extensions:"x-order=4"`
+	Status     string    `json:"status" example:"open" extensions:"x-order=5"`
+	Payout     int       `json:"payout" example:"0" extensions:"x-order=6"`
+	CreatedAt  time.Time `json:"created_at" extensions:"x-order=7"`
+}
+
+// BetSide is the pool of bets on a side of a gunfight
+type BetSide struct {
+	Side   int `json:"side" example:"2" extensions:"x-order=1"`
+	Silver int `json:"silver" example:"300" extensions:"x-order=2"`
+	Bets   int `json:"bets" example:"3" extensions:"x-order=3"`
+}
+
+// BetPoolResponse describes the betting pool of a gunfight
+// @Description Pari-mutuel pool of a gunfight: bets are accepted until the first round starts, the winning side shares the whole pool minus the house fee in proportion to the bets. Bet is the bet of the user, null if the user did not bet
+type BetPoolResponse struct {
+	GunfightID int       `json:"gunfight_id" example:"1" extensions:"x-order=1"`
+	Open       bool      `json:"open" example:"true" extensions:"x-order=2"`
+	HouseFee   int       `json:"house_fee" example:"5" extensions:"x-order=3"`
+	Total      int       `json:"total" example:"500" extensions:"x-order=4"`
+	Sides      []BetSide `json:"sides" extensions:"x-order=5"`
+	Bet        *BetItem  `json:"bet" extensions:"x-order=6"`
+}
+
+const (
+	BetOpen     = "open"
+	BetWon      = "won"
+	BetLost     = "lost"
+	BetRefunded = "refunded"
+)
+
+const (
+	BountyOpen    = "open"
+	BountyClaimed = "claimed"
+	BountyExpired = "expired"
+)
+
+// Challenge is a private duel offer addressed to another player
+// @Description Private duel offer, expires at expires_at
+type Challenge struct {
+	ID           string    `json:"id" example:"5f1c6a8e-3c1a-4b7e-9a43-8d7f0b8f2c11" extensions:"x-order=1"`
+	ChallengerID int       `json:"challenger_id" example:"1" extensions:"x-order=2"`
+	TargetID     int       `json:"target_id" example:"2" extensions:"x-order=3"`
+	ExpiresAt    time.Time `json:"expires_at" extensions:"x-order=4"`
+}
+
+// ChallengeTarget is the challenged player, addressed by user ID or by invite link
+type ChallengeTarget struct {
+	UserID int
+	Link   string
+}
+
+// ChallengeReply tells the challenger how the challenge ended
+type ChallengeReply struct {
+	ChallengeID string `json:"challenge_id" example:"5f1c6a8e-3c1a-4b7e-9a43-8d7f0b8f2c11"`
+	Status      string `json:"status" example:"accepted"`
+	GunfightID  int    `json:"gunfight_id,omitempty" example:"1"`
+}
+
+const (
+	ChallengeAccepted = "accepted"
+	ChallengeDeclined = "declined"
+	ChallengeExpired  = "expired"
+)
+
+// ChallengeCommand is the payload of accept and decline commands
+type ChallengeCommand struct {
+	ChallengeID string `json:"challenge_id" example:"5f1c6a8e-3c1a-4b7e-9a43-8d7f0b8f2c11"`
+}
+
+// RematchOffer is sent when the rematch websocket opens and again when the opponent asks for a rematch
+// @Description Rematch of a finished gunfight with the same stake, requested is true once the opponent has asked for it
+type RematchOffer struct {
+	GunfightID int       `json:"gunfight_id" example:"1" extensions:"x-order=1"`
+	OpponentID int       `json:"opponent_id" example:"2" extensions:"x-order=2"`
+	Stake      int       `json:"stake" example:"100" extensions:"x-order=3"`
+	Requested  bool      `json:"requested" example:"true" extensions:"x-order=4"`
+	ExpiresAt  time.Time `json:"expires_at" extensions:"x-order=5"`
+}
+
+// RematchReply tells both players how the rematch vote went: user_id is the player who asked for or declined the rematch,
+// rematch_id is the new gunfight
+type RematchReply struct {
+	GunfightID int    `json:"gunfight_id" example:"1"`
+	Status     string `json:"status" example:"declined"`
+	UserID     int    `json:"user_id,omitempty" example:"2"`
+	RematchID  int    `json:"rematch_id,omitempty" example:"3"`
+	Message    string `json:"message,omitempty"`
+}
+
+const (
+	RematchRequested = "requested"
+	RematchAccepted  = "accepted"
+	RematchDeclined  = "declined"
+	RematchExpired   = "expired"
+)
+
+// TeamSize is the number of players in a team gunfight team and the size of a full party
+const TeamSize = 2
+
+// Statuses of joining and leaving a party
+const (
+	PartyJoined     = "joined"
+	PartyInParty    = "in_party"
+	PartyNoInvite   = "no_invite"
+	PartyGone       = "gone"
+	PartySearching  = "searching"
+	PartyFull       = "full"
+	PartyLeft       = "left"
+	PartyDisbanded  = "disbanded"
+	PartyNotInParty = "not_in_party"
+)
+
+// DuelCommand is sent by a player during the duel
+// @Description Shot fired in the round, a shot before the draw signal is a foul
+type DuelCommand struct {
+	Round int `json:"round" example:"1" extensions:"x-order=1"`
+}
+
+// DuelEvent is sent by the server during the duel, Type and Seq go to the message envelope
+// @Description Duel state update: waiting, round, draw, result, finished, cancelled or spectators. Reactions are in milliseconds
+type DuelEvent struct {
+	Type       string        `json:"-"`
+	Seq        int64         `json:"-"`
+	Round      int           `json:"round,omitempty" example:"1" extensions:"x-order=2"`
+	Health     map[int]int   `json:"health,omitempty" extensions:"x-order=3"`
+	Hits       []int         `json:"hits,omitempty" extensions:"x-order=4"`
+	Foul       int           `json:"foul,omitempty" example:"2" extensions:"x-order=5"`
+	Reactions  map[int]int64 `json:"reactions,omitempty" extensions:"x-order=6"`
+	WinnerID   int           `json:"winner_id,omitempty" example:"1" extensions:"x-order=7"`
+	Message    string        `json:"message,omitempty" extensions:"x-order=8"`
+	Spectators int           `json:"spectators,omitempty" example:"3" extensions:"x-order=9"`
+	SeedHash   string        `json:"seed_hash,omitempty" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08" extensions:"x-order=10"`
+}
+
+const (
+	DuelEventWaiting    = "waiting"
+	DuelEventRound      = "round"
+	DuelEventDraw       = "draw"
+	DuelEventResult     = "result"
+	DuelEventFinished   = "finished"
+	DuelEventCancelled  = "cancelled"
+	DuelEventSpectators = "spectators"
+)
+
+// Duel log entries that are not sent to the players. EventArmed is the moment the pause before the draw starts
+const (
+	EventShot    = "shot"
+	EventForfeit = "forfeit"
+	EventArmed   = "armed"
+)
+
+// ReplayEvent is an entry of the duel log, rtt is in microseconds
+// @Description Duel log entry: a server event with its payload, an accepted shot or a forfeit, at is the server timestamp
+type ReplayEvent struct {
+	Seq     int64           `json:"seq,omitempty" example:"3" extensions:"x-order=1"`
+	Type    string          `json:"type" example:"draw" extensions:"x-order=2"`
+	UserID  *int            `json:"user_id,omitempty" example:"1" extensions:"x-order=3"`
+	Round   int             `json:"round,omitempty" example:"1" extensions:"x-order=4"`
+	RTT     int64           `json:"rtt,omitempty" example:"42000" extensions:"x-order=5"`
+	Payload json.RawMessage `json:"payload,omitempty" swaggertype:"object" extensions:"x-order=6"`
+	At      time.Time       `json:"at" extensions:"x-order=7"`
+}
+
+// ReplayResponse is the full log of a gunfight
+type ReplayResponse struct {
+	GunfightID int           `json:"gunfight_id" example:"1" extensions:"x-order=1"`
+	Players    []int         `json:"players" extensions:"x-order=2"`
+	WinnerID   *int          `json:"winner_id" example:"1" extensions:"x-order=3"`
+	Events     []ReplayEvent `json:"events" extensions:"x-order=4"`
+}
+
+// FairnessResponse lets a player audit the random draws of a gunfight
+// @Description Commit-reveal data of a gunfight: seed_hash is published when the gunfight is created, server_seed is revealed once it is finished. The draw delay of a round is HMAC-SHA256 keyed by the server seed over the client seeds joined by ":" in the order of client_seeds, then ":" and the round number; the first 8 bytes of the MAC as a big-endian number modulo the delay range (3000000000 ns) plus the minimum delay (2 s). Draws are filled in only for finished gunfights
+type FairnessResponse struct {
+	GunfightID  int              `json:"gunfight_id" example:"1" extensions:"x-order=1"`
+	SeedHash    string           `json:"seed_hash" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08" extensions:"x-order=2"`
+	ServerSeed  string           `json:"server_seed,omitempty" example:"4d0f1c6e2b9a8f7e6d5c4b3a29180706f5e4d3c2b1a09f8e7d6c5b4a39281706" extensions:"x-order=3"`
+	ClientSeeds []ClientSeedItem `json:"client_seeds" extensions:"x-order=4"`
+	Draws       []FairDraw       `json:"draws,omitempty" extensions:"x-order=5"`
+	Verified    bool             `json:"verified" example:"true" extensions:"x-order=6"`
+	Message     string           `json:"message,omitempty" extensions:"x-order=7"`
+}
+
+// ClientSeedItem is the seed a player passed when joining the gunfight
+type ClientSeedItem struct {
+	UserID int    `json:"user_id" example:"1" extensions:"x-order=1"`
+	Seed   string `json:"seed" example:"lucky-horseshoe" extensions:"x-order=2"`
+}
+
+// FairDraw is a round of a finished gunfight: delay is the draw delay derived from the seeds,
+// observed is the time between the round and draw events in the duel log, null if the round ended with a foul.
+// Both are in milliseconds
+type FairDraw struct {
+	Round    int    `json:"round" example:"1" extensions:"x-order=1"`
+	Delay    int64  `json:"delay" example:"3412" extensions:"x-order=2"`
+	Observed *int64 `json:"observed" example:"3415" extensions:"x-order=3"`
+}
@@ -0,0 +1,97 @@
+package gunfight
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// MessageVersion is the version of the websocket envelope, bumped on incompatible changes
+const MessageVersion = 1
+
+// Server to client message types
+const (
+	MessageQueued    = "queued"
+	MessageSearching = "searching"
+	MessageQueue     = "queue"
+	MessageMatched   = "matched"
+	MessageTimeout   = "timeout"
+	MessageError     = "error"
+	MessageCancelled = "cancelled"
+
+	MessageChallenge     = "challenge"
+	MessageChallengeSent = "challenge_sent"
+	MessageDeclined      = "declined"
+	MessageExpired       = "expired"
+
+	MessageRematch = "rematch"
+)
+
+// Client to server command types
+const (
+	CommandCancel  = "cancel"
+	CommandShoot   = "shoot"
+	CommandAccept  = "accept"
+	CommandDecline = "decline"
+)
+
+// Message is the envelope of every gunfight websocket message in both directions
+// @Description Versioned websocket envelope: type defines the payload, seq grows with every server message
+type Message struct {
+	Version int             `json:"v" example:"1" extensions:"x-order=1"`
+	Type    string          `json:"type" example:"matched" extensions:"x-order=2"`
+	Seq     int64           `json:"seq" example:"1" extensions:"x-order=3"`
+	Payload json.RawMessage `json:"payload,omitempty" swaggertype:"object" extensions:"x-order=4"`
+}
+
+// QueuedPayload is sent when the player enters matchmaking
+type QueuedPayload struct {
+	Queue string `json:"queue" example:"ranked"`
+}
+
+// SearchingPayload is sent every time the search band widens
+type SearchingPayload struct {
+	Band Band `json:"band"`
+}
+
+// ErrorPayload is sent with error, timeout and cancelled messages
+type ErrorPayload struct {
+	Message string `json:"message" example:"No opponent found within the time limit"`
+}
+
+// EncodeMessage packs the payload into a versioned envelope
+func EncodeMessage(messageType string, seq int64, payload interface{}) ([]byte, error) {
+	message := Message{Version: MessageVersion, Type: messageType, Seq: seq}
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding %s payload: %w", messageType, err)
+		}
+		message.Payload = data
+	}
+	return json.Marshal(message)
+}
+
+// DecodeMessage unpacks the envelope, the payload is decoded separately with DecodePayload
+func DecodeMessage(data []byte) (Message, error) {
+	var message Message
+	if err := json.Unmarshal(data, &message); err != nil {
+		return message, fmt.Errorf("error decoding message: %w", err)
+	}
+	if message.Version != MessageVersion {
+		return message, fmt.Errorf("unsupported message version %d", message.Version)
+	}
+	if message.Type == "" {
+		return message, fmt.Errorf("message type is required")
+	}
+	return message, nil
+}
+
+func (m Message) DecodePayload(payload interface{}) error {
+	if len(m.Payload) == 0 {
+		return fmt.Errorf("%s message has no payload", m.Type)
+	}
+	if err := json.Unmarshal(m.Payload, payload); err != nil {
+		return fmt.Errorf("error decoding %s payload: %w", m.Type, err)
+	}
+	return nil
+}
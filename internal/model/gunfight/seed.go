@@ -0,0 +1,24 @@
+package gunfight
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// NewServerSeed returns a random server seed of a gunfight and its hash, the hash is published before the duel
+func NewServerSeed() (string, string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", "", fmt.Errorf("error generating server seed: %w", err)
+	}
+	seed := hex.EncodeToString(bytes)
+	return seed, SeedHash(seed), nil
+}
+
+// SeedHash returns the hex SHA-256 of the server seed
+func SeedHash(seed string) string {
+	sum := sha256.Sum256([]byte(seed))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,5 +1,7 @@
 package horse
 
+import "time"
+
 // BaseResponse represents the horse model
 // @Description This is a horse model
 type BaseResponse struct {
@@ -8,8 +10,18 @@ type BaseResponse struct {
 	Speed    int `json:"speed" example:"2000" extensions:"x-order=4"`
 }
 
+// RaceResponse is a race session started by the server, the distance of the race is accepted only up to
+// what the horse covers at this speed per second until the race is finished
+type RaceResponse struct {
+	RaceID    string    `json:"race_id" example:"5f1c6a8e-3c1a-4b7e-9a43-8d7f0b8f2c11" extensions:"x-order=1"`
+	Speed     int       `json:"speed" example:"2000" extensions:"x-order=2"`
+	StartedAt time.Time `json:"started_at" extensions:"x-order=3"`
+	ExpiresAt time.Time `json:"expires_at" extensions:"x-order=4"`
+}
+
 type GameRequest struct {
-	Distance int `json:"distance" extensions:"x-order=1"`
+	RaceID   string `json:"race_id" example:"5f1c6a8e-3c1a-4b7e-9a43-8d7f0b8f2c11" extensions:"x-order=1"`
+	Distance int    `json:"distance" extensions:"x-order=2"`
 }
 
 type GameResponse struct {
@@ -17,3 +29,11 @@ type GameResponse struct {
 	Record   bool `json:"record" extensions:"x-order=2"`
 	Distance int  `json:"distance" extensions:"x-order=3"`
 }
+
+// Race is a race session kept in Redis: the speed of the horse and the server time of the start
+type Race struct {
+	ID        string    `json:"id"`
+	UserID    int       `json:"user_id"`
+	Speed     int       `json:"speed"`
+	StartedAt time.Time `json:"started_at"`
+}
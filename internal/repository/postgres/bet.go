@@ -0,0 +1,146 @@
+package postgres
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+	"wildwest/internal/errors"
+	"wildwest/internal/model/gunfight"
+	"wildwest/pkg/contextutils"
+)
+
+// PlaceBet списывает серебро и принимает ставку зрителя, пока прием ставок на игру открыт.
+// Сумма ставок игрока за последние сутки не может превысить dailyLimit
+func (r *GunfightPostgresRepository) PlaceBet(ctx context.Context, bet *gunfight.Bet, dailyLimit int) error {
+	tx := r.BeginTransaction()
+	contextData := contextutils.ExtractContextData(ctx)
+	if tx.Error != nil {
+		return errors.TransactionStartError(contextData, tx.Error)
+	}
+
+	// Блокировка игры не дает принять ставку после того, как дуэль закрыла прием ставок
+	var game gunfight.Game
+	err := tx.WithContext(ctx).Table("gunfight").Clauses(clause.Locking{Strength: "SHARE"}).
+		Where("id = ? AND end_date IS NULL AND bets_closed = FALSE", bet.GunfightID).First(&game).Error
+	if err != nil {
+		tx.Rollback()
+		return errors.RecordNotFoundError(contextData, "gunfight")
+	}
+
+	// Списание блокирует строку баланса игрока, поэтому параллельные ставки не обойдут суточный лимит
+	result := tx.WithContext(ctx).Table("money").
+		Where("user_id = ? AND silver >= ?", bet.UserID, bet.Silver).
+		Update("silver", gorm.Expr("silver - ?", bet.Silver))
+	if result.Error != nil {
+		tx.Rollback()
+		return errors.UpdateError(contextData, "money", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return errors.InsufficientFundsError(contextData, "money")
+	}
+
+	var placed int64
+	err = tx.WithContext(ctx).Table("gunfight_bets").Select("COALESCE(SUM(silver), 0)").
+		Where("user_id = ? AND created_at > ?", bet.UserID, time.Now().Add(-24*time.Hour)).Scan(&placed).Error
+	if err != nil {
+		tx.Rollback()
+		return errors.RecordNotFoundError(contextData, "gunfight_bets")
+	}
+	if placed+int64(bet.Silver) > int64(dailyLimit) {
+		tx.Rollback()
+		return errors.LimitExceededError(contextData, "gunfight_bets")
+	}
+
+	if _, err := r.BaseRepository.Create(ctx, tx, "gunfight_bets", bet); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return errors.TransactionCommitError(contextData, err)
+	}
+
+	return nil
+}
+
+// CloseBets закрывает прием ставок на игру
+func (r *GunfightPostgresRepository) CloseBets(ctx context.Context, gunfightID int) error {
+	result := r.db.WithContext(ctx).Table("gunfight").Where("id = ?", gunfightID).Update("bets_closed", true)
+	if result.Error != nil {
+		contextData := contextutils.ExtractContextData(ctx)
+		return errors.UpdateError(contextData, "gunfight", result.Error)
+	}
+	return nil
+}
+
+// GetBets возвращает ставки зрителей на игру
+func (r *GunfightPostgresRepository) GetBets(ctx context.Context, gunfightID int) ([]gunfight.Bet, error) {
+	var bets []gunfight.Bet
+	err := r.db.WithContext(ctx).Table("gunfight_bets").Where("gunfight_id = ?", gunfightID).Order("id").Find(&bets).Error
+	if err != nil {
+		contextData := contextutils.ExtractContextData(ctx)
+		return nil, errors.RecordNotFoundError(contextData, "gunfight_bets")
+	}
+	return bets, nil
+}
+
+// settleBets рассчитывает ставки зрителей в транзакции, которая записывает итог игры: ставившие на победителя
+// делят весь банк за вычетом комиссии fee пропорционально ставкам, остаток от деления достается заведению.
+// Без победителя, при refund, а также если все ставки на одну сторону, ставки возвращаются
+func (r *GunfightPostgresRepository) settleBets(ctx context.Context, tx *gorm.DB, gunfightID int, winnerID *int, fee int, refund bool) error {
+	contextData := contextutils.ExtractContextData(ctx)
+
+	var bets []gunfight.Bet
+	err := tx.WithContext(ctx).Table("gunfight_bets").Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("gunfight_id = ? AND status = ?", gunfightID, gunfight.BetOpen).Order("id").Find(&bets).Error
+	if err != nil {
+		return errors.RecordNotFoundError(contextData, "gunfight_bets")
+	}
+
+	settleBetPayouts(bets, winnerID, fee, refund)
+
+	now := time.Now()
+	for _, bet := range bets {
+		if _, err := r.BaseRepository.Update(ctx, tx, "gunfight_bets", "id", bet.ID, map[string]interface{}{
+			"status":     bet.Status,
+			"payout":     bet.Payout,
+			"settled_at": now,
+		}); err != nil {
+			return err
+		}
+		if bet.Payout > 0 {
+			if _, err := r.BaseRepository.Update(ctx, tx, "money", "user_id", bet.UserID, map[string]interface{}{
+				"silver": gorm.Expr("silver + ?", bet.Payout),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// settleBetPayouts записывает в ставки исход и выплату по правилам settleBets
+func settleBetPayouts(bets []gunfight.Bet, winnerID *int, fee int, refund bool) {
+	total, winning := 0, 0
+	for _, bet := range bets {
+		total += bet.Silver
+		if winnerID != nil && bet.Side == *winnerID {
+			winning += bet.Silver
+		}
+	}
+	refund = refund || winnerID == nil || winning == 0 || winning == total
+	pool := total - total*fee/100
+
+	for i := range bets {
+		bet := &bets[i]
+		bet.Status, bet.Payout = gunfight.BetLost, 0
+		switch {
+		case refund:
+			bet.Status, bet.Payout = gunfight.BetRefunded, bet.Silver
+		case bet.Side == *winnerID:
+			bet.Status, bet.Payout = gunfight.BetWon, bet.Silver*pool/winning
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"testing"
+	"wildwest/internal/model/gunfight"
+)
+
+func TestSettleBetPayouts(t *testing.T) {
+	winner := 1
+
+	tests := []struct {
+		name     string
+		bets     []gunfight.Bet
+		winnerID *int
+		fee      int
+		refund   bool
+		statuses []string
+		payouts  []int
+	}{
+		{
+			name:     "pool split in proportion minus the fee",
+			bets:     []gunfight.Bet{{Side: 1, Silver: 100}, {Side: 1, Silver: 300}, {Side: 2, Silver: 600}},
+			winnerID: &winner,
+			fee:      10,
+			statuses: []string{gunfight.BetWon, gunfight.BetWon, gunfight.BetLost},
+			payouts:  []int{225, 675, 0},
+		},
+		{
+			// Банк 100 - 5% = 95 делится на три равные ставки: по 31, остаток 2 остается заведению
+			name:     "rounding leftover stays with the house",
+			bets:     []gunfight.Bet{{Side: 1, Silver: 10}, {Side: 1, Silver: 10}, {Side: 1, Silver: 10}, {Side: 2, Silver: 70}},
+			winnerID: &winner,
+			fee:      5,
+			statuses: []string{gunfight.BetWon, gunfight.BetWon, gunfight.BetWon, gunfight.BetLost},
+			payouts:  []int{31, 31, 31, 0},
+		},
+		{
+			name:     "one-sided pool is refunded",
+			bets:     []gunfight.Bet{{Side: 1, Silver: 100}, {Side: 1, Silver: 50}},
+			winnerID: &winner,
+			fee:      10,
+			statuses: []string{gunfight.BetRefunded, gunfight.BetRefunded},
+			payouts:  []int{100, 50},
+		},
+		{
+			name:     "nobody bet on the winner",
+			bets:     []gunfight.Bet{{Side: 2, Silver: 100}, {Side: 2, Silver: 50}},
+			winnerID: &winner,
+			fee:      10,
+			statuses: []string{gunfight.BetRefunded, gunfight.BetRefunded},
+			payouts:  []int{100, 50},
+		},
+		{
+			name:     "no winner is refunded",
+			bets:     []gunfight.Bet{{Side: 1, Silver: 100}, {Side: 2, Silver: 200}},
+			fee:      10,
+			statuses: []string{gunfight.BetRefunded, gunfight.BetRefunded},
+			payouts:  []int{100, 200},
+		},
+		{
+			name:     "forfeit is refunded despite the winner",
+			bets:     []gunfight.Bet{{Side: 1, Silver: 100}, {Side: 2, Silver: 200}},
+			winnerID: &winner,
+			fee:      10,
+			refund:   true,
+			statuses: []string{gunfight.BetRefunded, gunfight.BetRefunded},
+			payouts:  []int{100, 200},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			settleBetPayouts(test.bets, test.winnerID, test.fee, test.refund)
+
+			paid := 0
+			for i, bet := range test.bets {
+				if bet.Status != test.statuses[i] || bet.Payout != test.payouts[i] {
+					t.Errorf("bet %d: %s %d, want %s %d", i, bet.Status, bet.Payout, test.statuses[i], test.payouts[i])
+				}
+				paid += bet.Payout
+			}
+
+			total := 0
+			for _, bet := range test.bets {
+				total += bet.Silver
+			}
+			if paid > total {
+				t.Errorf("paid %d out of a pool of %d", paid, total)
+			}
+		})
+	}
+}
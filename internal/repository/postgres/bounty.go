@@ -0,0 +1,147 @@
+package postgres
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+	"wildwest/internal/errors"
+	"wildwest/internal/model/gunfight"
+	"wildwest/pkg/contextutils"
+)
+
+// CreateBounty списывает золото с заказчика и объявляет награду за цель
+func (r *GunfightPostgresRepository) CreateBounty(ctx context.Context, bounty *gunfight.Bounty) error {
+	tx := r.BeginTransaction()
+	contextData := contextutils.ExtractContextData(ctx)
+	if tx.Error != nil {
+		return errors.TransactionStartError(contextData, tx.Error)
+	}
+
+	result := tx.WithContext(ctx).Table("money").
+		Where("user_id = ? AND gold >= ?", bounty.PosterID, bounty.Gold).
+		Update("gold", gorm.Expr("gold - ?", bounty.Gold))
+	if result.Error != nil {
+		tx.Rollback()
+		return errors.UpdateError(contextData, "money", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return errors.InsufficientFundsError(contextData, "money")
+	}
+
+	if _, err := r.BaseRepository.Create(ctx, tx, "gunfight_bounty", bounty); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return errors.TransactionCommitError(contextData, err)
+	}
+
+	return nil
+}
+
+// GetBounties возвращает открытые награды от крупных к мелким
+func (r *GunfightPostgresRepository) GetBounties(ctx context.Context, limit int) ([]gunfight.Bounty, error) {
+	var bounties []gunfight.Bounty
+	err := r.db.WithContext(ctx).Table("gunfight_bounty").
+		Where("status = ? AND expires_at > ?", gunfight.BountyOpen, time.Now()).
+		Order("gold DESC, id").Limit(limit).Find(&bounties).Error
+	if err != nil {
+		contextData := contextutils.ExtractContextData(ctx)
+		return nil, errors.RecordNotFoundError(contextData, "gunfight_bounty")
+	}
+	return bounties, nil
+}
+
+// ExpireBounties закрывает истекшие награды и возвращает золото заказчикам, возвращает число закрытых наград.
+// Награды, которые сейчас забирает победитель, пропускаются
+func (r *GunfightPostgresRepository) ExpireBounties(ctx context.Context, limit int) (int, error) {
+	tx := r.BeginTransaction()
+	contextData := contextutils.ExtractContextData(ctx)
+	if tx.Error != nil {
+		return 0, errors.TransactionStartError(contextData, tx.Error)
+	}
+
+	now := time.Now()
+	var bounties []gunfight.Bounty
+	err := tx.WithContext(ctx).Table("gunfight_bounty").Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = ? AND expires_at <= ?", gunfight.BountyOpen, now).Order("id").Limit(limit).Find(&bounties).Error
+	if err != nil {
+		tx.Rollback()
+		return 0, errors.RecordNotFoundError(contextData, "gunfight_bounty")
+	}
+
+	for _, bounty := range bounties {
+		if _, err := r.BaseRepository.Update(ctx, tx, "gunfight_bounty", "id", bounty.ID, map[string]interface{}{
+			"status":   gunfight.BountyExpired,
+			"end_date": now,
+		}); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+		if err := r.addGold(ctx, tx, bounty.PosterID, bounty.Gold); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return 0, errors.TransactionCommitError(contextData, err)
+	}
+
+	return len(bounties), nil
+}
+
+// claimBounties отдает победителям игры открытые награды за проигравших игроков. В командной игре награда
+// делится поровну между победителями, остаток от деления достается капитану. Награды закрывают только игры
+// с соперником из очереди или рейтинговые: вызов, реванш или игру с ботом можно подстроить
+func (r *GunfightPostgresRepository) claimBounties(ctx context.Context, tx *gorm.DB, gunfightID int, captainID int, winners []int) error {
+	contextData := contextutils.ExtractContextData(ctx)
+
+	var game gunfight.Game
+	if err := tx.WithContext(ctx).Table("gunfight").Where("id = ?", gunfightID).Take(&game).Error; err != nil {
+		return errors.RecordNotFoundError(contextData, "gunfight")
+	}
+	if game.Bot != "" || game.RematchOf != nil || (game.Queue == "" && !game.Ranked) {
+		return nil
+	}
+
+	now := time.Now()
+	losers := tx.WithContext(ctx).Table("gunfight_players").Select("user_id").
+		Where("gunfight_id = ? AND user_id NOT IN ?", gunfightID, winners)
+	var bounties []gunfight.Bounty
+	err := tx.WithContext(ctx).Table("gunfight_bounty").Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("status = ? AND expires_at > ? AND target_id IN (?)", gunfight.BountyOpen, now, losers).
+		Order("id").Find(&bounties).Error
+	if err != nil {
+		return errors.RecordNotFoundError(contextData, "gunfight_bounty")
+	}
+
+	for _, bounty := range bounties {
+		if _, err := r.BaseRepository.Update(ctx, tx, "gunfight_bounty", "id", bounty.ID, map[string]interface{}{
+			"status":      gunfight.BountyClaimed,
+			"hunter_id":   captainID,
+			"gunfight_id": gunfightID,
+			"end_date":    now,
+		}); err != nil {
+			return err
+		}
+
+		share := bounty.Gold / len(winners)
+		for _, userID := range winners {
+			gold := share
+			if userID == captainID {
+				gold += bounty.Gold - share*len(winners)
+			}
+			if gold == 0 {
+				continue
+			}
+			if err := r.addGold(ctx, tx, userID, gold); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
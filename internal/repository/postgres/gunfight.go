@@ -3,7 +3,11 @@ package postgres
 import (
 	"context"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+	"wildwest/internal/errors"
 	"wildwest/internal/model/gunfight"
+	"wildwest/pkg/contextutils"
 )
 
 type GunfightPostgresRepository struct {
@@ -17,10 +21,423 @@ func NewGunfightRepository(db *gorm.DB) *GunfightPostgresRepository {
 }
 
 func (r *GunfightPostgresRepository) Create(ctx context.Context, game *gunfight.Game) (int, error) {
-	result := r.db.WithContext(ctx).Table("gunfight").Create(game)
-	if result.Error != nil {
-		return 0, result.Error
+	tx := r.BeginTransaction()
+	contextData := contextutils.ExtractContextData(ctx)
+	if tx.Error != nil {
+		return 0, errors.TransactionStartError(contextData, tx.Error)
+	}
+
+	if err := r.createGame(ctx, tx, game); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return 0, errors.TransactionCommitError(contextData, err)
 	}
 
 	return game.ID, nil
 }
+
+// createGame записывает игру с составом команд и здоровьем и привязывает к ней удержанные ставки игроков.
+// Сид игры создается здесь же, чтобы он был у игры из любого источника: поиска, вызова, реванша или турнира
+func (r *GunfightPostgresRepository) createGame(ctx context.Context, tx *gorm.DB, game *gunfight.Game) error {
+	contextData := contextutils.ExtractContextData(ctx)
+	if game.ServerSeed == "" {
+		seed, hash, err := gunfight.NewServerSeed()
+		if err != nil {
+			return err
+		}
+		game.ServerSeed, game.SeedHash = seed, hash
+	}
+	if _, err := r.BaseRepository.Create(ctx, tx, "gunfight", game); err != nil {
+		return err
+	}
+
+	players := game.Players
+	if len(players) == 0 {
+		players = []gunfight.Player{{UserID: game.User1ID, Team: 1}, {UserID: game.User2ID, Team: 2}}
+	}
+	userIDs := make([]int, 0, len(players))
+	for i := range players {
+		players[i].GunfightID = game.ID
+		userIDs = append(userIDs, players[i].UserID)
+		if _, err := r.BaseRepository.Create(ctx, tx, "gunfight_players", &players[i]); err != nil {
+			return err
+		}
+	}
+
+	if game.Stake > 0 {
+		result := tx.WithContext(ctx).Table("gunfight_escrow").
+			Where("user_id IN ? AND gunfight_id IS NULL AND gold = ?", userIDs, game.Stake).
+			Update("gunfight_id", game.ID)
+		if result.Error != nil {
+			return errors.UpdateError(contextData, "gunfight_escrow", result.Error)
+		}
+		if result.RowsAffected != int64(len(userIDs)) {
+			return errors.RecordNotFoundError(contextData, "gunfight_escrow")
+		}
+	}
+
+	for _, userID := range []int{game.User1ID, game.User2ID} {
+		// Здоровье командной игры записывается на капитана: это общий запас всех игроков команды
+		health := &gunfight.Health{GunfightID: game.ID, UserID: userID}
+		if game.TeamSize > 1 {
+			health.Health = 3 * game.TeamSize
+		}
+		if _, err := r.BaseRepository.Create(ctx, tx, "gunfight_health", health); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *GunfightPostgresRepository) Get(ctx context.Context, gunfightID int) (*gunfight.Game, error) {
+	var game gunfight.Game
+	err := r.BaseRepository.Get(ctx, nil, "gunfight", "id", gunfightID, &game)
+	if err != nil {
+		return nil, err
+	}
+	return &game, nil
+}
+
+// GetRematch возвращает игру-реванш, созданную после игры gunfightID
+func (r *GunfightPostgresRepository) GetRematch(ctx context.Context, gunfightID int) (*gunfight.Game, error) {
+	var game gunfight.Game
+	err := r.BaseRepository.Get(ctx, nil, "gunfight", "rematch_of", gunfightID, &game)
+	if err != nil {
+		return nil, err
+	}
+	return &game, nil
+}
+
+// GetPlayers возвращает состав команд игры
+func (r *GunfightPostgresRepository) GetPlayers(ctx context.Context, gunfightID int) ([]gunfight.Player, error) {
+	var players []gunfight.Player
+	err := r.db.WithContext(ctx).Table("gunfight_players").
+		Where("gunfight_id = ?", gunfightID).Order("team, user_id").Find(&players).Error
+	if err != nil || len(players) == 0 {
+		contextData := contextutils.ExtractContextData(ctx)
+		return nil, errors.RecordNotFoundError(contextData, "gunfight_players")
+	}
+	return players, nil
+}
+
+// SetClientSeeds записывает сиды, которые игроки передали при входе в дуэль
+func (r *GunfightPostgresRepository) SetClientSeeds(ctx context.Context, gunfightID int, seeds map[int]string) error {
+	tx := r.BeginTransaction()
+	contextData := contextutils.ExtractContextData(ctx)
+	if tx.Error != nil {
+		return errors.TransactionStartError(contextData, tx.Error)
+	}
+
+	for userID, seed := range seeds {
+		result := tx.WithContext(ctx).Table("gunfight_players").
+			Where("gunfight_id = ? AND user_id = ?", gunfightID, userID).Update("client_seed", seed)
+		if result.Error != nil {
+			tx.Rollback()
+			return errors.UpdateError(contextData, "gunfight_players", result.Error)
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return errors.TransactionCommitError(contextData, err)
+	}
+
+	return nil
+}
+
+func (r *GunfightPostgresRepository) UpdateHealth(ctx context.Context, gunfightID int, userID int, health int) error {
+	result := r.db.WithContext(ctx).Table("gunfight_health").
+		Where("gunfight_id = ? AND user_id = ?", gunfightID, userID).
+		Update("health", health)
+	contextData := contextutils.ExtractContextData(ctx)
+	if result.Error != nil {
+		return errors.UpdateError(contextData, "gunfight_health", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.RecordNotFoundError(contextData, "gunfight_health")
+	}
+	return nil
+}
+
+// Finish записывает победителя и время окончания игры, рассчитывает ставки и рейтинг:
+// каждый из победителей получает result.Prize, при ничьей (result.WinnerID == nil) ставки возвращаются игрокам
+func (r *GunfightPostgresRepository) Finish(ctx context.Context, gunfightID int, result *gunfight.Result) error {
+	tx := r.BeginTransaction()
+	contextData := contextutils.ExtractContextData(ctx)
+	if tx.Error != nil {
+		return errors.TransactionStartError(contextData, tx.Error)
+	}
+
+	endDate := time.Now()
+	updated := tx.WithContext(ctx).Table("gunfight").
+		Where("id = ? AND end_date IS NULL", gunfightID).
+		Updates(&gunfight.Game{WinnerID: result.WinnerID, EndDate: &endDate})
+	if updated.Error != nil {
+		tx.Rollback()
+		return errors.UpdateError(contextData, "gunfight", updated.Error)
+	}
+	if updated.RowsAffected == 0 {
+		tx.Rollback()
+		return errors.RecordNotFoundError(contextData, "gunfight")
+	}
+
+	if len(result.Journal) > 0 {
+		if _, err := r.BaseRepository.Create(ctx, tx, "gunfight_events", &result.Journal); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	var escrows []gunfight.Escrow
+	deleted := tx.WithContext(ctx).Table("gunfight_escrow").Clauses(clause.Returning{}).
+		Where("gunfight_id = ?", gunfightID).Delete(&escrows)
+	if deleted.Error != nil {
+		tx.Rollback()
+		return errors.DeleteError(contextData, "gunfight_escrow", deleted.Error)
+	}
+
+	var err error
+	var winners []int
+	if result.WinnerID != nil {
+		winners = result.Winners
+		if len(winners) == 0 {
+			winners = []int{*result.WinnerID}
+		}
+	}
+	if result.WinnerID != nil && len(escrows) > 0 {
+		for _, userID := range winners {
+			if err = r.addGold(ctx, tx, userID, result.Prize); err != nil {
+				break
+			}
+		}
+	} else {
+		for _, escrow := range escrows {
+			if err = r.addGold(ctx, tx, escrow.UserID, escrow.Gold); err != nil {
+				break
+			}
+		}
+	}
+	if err == nil && result.WinnerID != nil {
+		err = r.claimBounties(ctx, tx, gunfightID, *result.WinnerID, winners)
+	}
+	if err == nil {
+		err = r.settleBets(ctx, tx, gunfightID, result.WinnerID, result.BetFee, result.RefundBets)
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for userID, change := range result.RatingChanges {
+		if err = r.changeRating(ctx, tx, userID, change); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		return errors.TransactionCommitError(contextData, err)
+	}
+
+	return nil
+}
+
+// GetRating возвращает рейтинг игрока, у игрока без рейтинговых игр рейтинг по умолчанию
+func (r *GunfightPostgresRepository) GetRating(ctx context.Context, userID int) (*gunfight.Rating, error) {
+	rating := gunfight.Rating{UserID: userID, Rating: gunfight.DefaultRating}
+	result := r.db.WithContext(ctx).Table("gunfight_rating").Where("user_id = ?", userID).Limit(1).Find(&rating)
+	if result.Error != nil {
+		contextData := contextutils.ExtractContextData(ctx)
+		return nil, errors.RecordNotFoundError(contextData, "gunfight_rating")
+	}
+	return &rating, nil
+}
+
+// GetHistory возвращает завершенные игры игрока, включая командные, от новых к старым, cursor — id последней игры
+// предыдущей страницы. Game.Team — команда игрока в игре
+func (r *GunfightPostgresRepository) GetHistory(ctx context.Context, userID int, cursor int, limit int) ([]gunfight.Game, error) {
+	var games []gunfight.Game
+	query := r.db.WithContext(ctx).Table("gunfight").
+		Select("gunfight.*, gunfight_players.team").
+		Joins("JOIN gunfight_players ON gunfight_players.gunfight_id = gunfight.id").
+		Where("gunfight_players.user_id = ? AND gunfight.end_date IS NOT NULL", userID)
+	if cursor > 0 {
+		query = query.Where("gunfight.id < ?", cursor)
+	}
+
+	if err := query.Order("gunfight.id DESC").Limit(limit).Find(&games).Error; err != nil {
+		contextData := contextutils.ExtractContextData(ctx)
+		return nil, errors.RecordNotFoundError(contextData, "gunfight")
+	}
+	return games, nil
+}
+
+// AppendEvents дописывает записи в журнал дуэли в том же порядке
+func (r *GunfightPostgresRepository) AppendEvents(ctx context.Context, events []gunfight.Event) error {
+	_, err := r.BaseRepository.Create(ctx, nil, "gunfight_events", &events)
+	return err
+}
+
+// GetEvents возвращает журнал дуэли в порядке записи
+func (r *GunfightPostgresRepository) GetEvents(ctx context.Context, gunfightID int) ([]gunfight.Event, error) {
+	var events []gunfight.Event
+	err := r.db.WithContext(ctx).Table("gunfight_events").
+		Where("gunfight_id = ?", gunfightID).Order("id").Find(&events).Error
+	if err != nil {
+		contextData := contextutils.ExtractContextData(ctx)
+		return nil, errors.RecordNotFoundError(contextData, "gunfight_events")
+	}
+	return events, nil
+}
+
+// GetStale возвращает незавершенные игры, начатые раньше startedBefore
+func (r *GunfightPostgresRepository) GetStale(ctx context.Context, startedBefore time.Time, limit int) ([]gunfight.Game, error) {
+	var games []gunfight.Game
+	err := r.db.WithContext(ctx).Table("gunfight").
+		Where("end_date IS NULL AND start_date < ?", startedBefore).
+		Order("id").Limit(limit).Find(&games).Error
+	if err != nil {
+		contextData := contextutils.ExtractContextData(ctx)
+		return nil, errors.RecordNotFoundError(contextData, "gunfight")
+	}
+	return games, nil
+}
+
+// statsGames — законченные игры игрока вместе с командными, как в истории: captain_id — капитан его команды,
+// opponent_id — капитан команды соперника
+const statsGames = `
+WITH games AS (
+    SELECT gunfight.id, gunfight.winner_id,
+           CASE WHEN gunfight_players.team = 1 THEN user_1_id ELSE user_2_id END AS captain_id,
+           CASE WHEN gunfight_players.team = 1 THEN user_2_id ELSE user_1_id END AS opponent_id
+    FROM gunfight
+    JOIN gunfight_players ON gunfight_players.gunfight_id = gunfight.id
+    WHERE gunfight_players.user_id = @user AND gunfight.end_date IS NOT NULL
+)`
+
+const statsResultsQuery = statsGames + `
+SELECT COUNT(*) FILTER (WHERE winner_id = captain_id) AS wins,
+       COUNT(*) FILTER (WHERE winner_id <> captain_id) AS losses,
+       COUNT(*) FILTER (WHERE winner_id IS NULL) AS draws
+FROM games`
+
+// Серия — это игры после последней игры с другим исходом
+const statsStreakQuery = statsGames + `, results AS (
+    SELECT id, winner_id = captain_id AS won
+    FROM games
+    WHERE winner_id IS NOT NULL
+), last AS (
+    SELECT won FROM results ORDER BY id DESC LIMIT 1
+)
+SELECT CASE WHEN last.won THEN COUNT(*) ELSE -COUNT(*) END AS streak
+FROM results, last
+WHERE results.id > COALESCE((SELECT MAX(id) FROM results WHERE won <> last.won), 0)
+GROUP BY last.won`
+
+const statsOpponentQuery = statsGames + `
+SELECT opponent_id AS most_faced_opponent_id, COUNT(*) AS most_faced_games
+FROM games
+GROUP BY opponent_id
+ORDER BY most_faced_games DESC, MAX(id) DESC
+LIMIT 1`
+
+func (r *GunfightPostgresRepository) GetStats(ctx context.Context, userID int) (*gunfight.Stats, error) {
+	var stats gunfight.Stats
+	args := map[string]interface{}{"user": userID}
+	db := r.db.WithContext(ctx)
+
+	for _, query := range []string{statsResultsQuery, statsStreakQuery, statsOpponentQuery} {
+		if err := db.Raw(query, args).Scan(&stats).Error; err != nil {
+			contextData := contextutils.ExtractContextData(ctx)
+			return nil, errors.RecordNotFoundError(contextData, "gunfight")
+		}
+	}
+	return &stats, nil
+}
+
+func (r *GunfightPostgresRepository) changeRating(ctx context.Context, tx *gorm.DB, userID int, change int) error {
+	rating := &gunfight.Rating{UserID: userID, Rating: gunfight.DefaultRating + change, Games: 1}
+	result := tx.WithContext(ctx).Table("gunfight_rating").Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"rating": gorm.Expr("gunfight_rating.rating + ?", change),
+			"games":  gorm.Expr("gunfight_rating.games + 1"),
+		}),
+	}).Create(rating)
+	if result.Error != nil {
+		contextData := contextutils.ExtractContextData(ctx)
+		return errors.UpdateError(contextData, "gunfight_rating", result.Error)
+	}
+	return nil
+}
+
+// HoldStake списывает ставку с баланса игрока и удерживает ее до окончания игры
+func (r *GunfightPostgresRepository) HoldStake(ctx context.Context, userID int, gold int) error {
+	tx := r.BeginTransaction()
+	contextData := contextutils.ExtractContextData(ctx)
+	if tx.Error != nil {
+		return errors.TransactionStartError(contextData, tx.Error)
+	}
+
+	result := tx.WithContext(ctx).Table("money").
+		Where("user_id = ? AND gold >= ?", userID, gold).
+		Update("gold", gorm.Expr("gold - ?", gold))
+	if result.Error != nil {
+		tx.Rollback()
+		return errors.UpdateError(contextData, "money", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return errors.InsufficientFundsError(contextData, "money")
+	}
+
+	if _, err := r.BaseRepository.Create(ctx, tx, "gunfight_escrow", &gunfight.Escrow{UserID: userID, Gold: gold}); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return errors.TransactionCommitError(contextData, err)
+	}
+
+	return nil
+}
+
+// ReleaseStake возвращает игроку ставку, если он покинул очередь, так и не начав игру
+func (r *GunfightPostgresRepository) ReleaseStake(ctx context.Context, userID int) error {
+	tx := r.BeginTransaction()
+	contextData := contextutils.ExtractContextData(ctx)
+	if tx.Error != nil {
+		return errors.TransactionStartError(contextData, tx.Error)
+	}
+
+	var escrows []gunfight.Escrow
+	result := tx.WithContext(ctx).Table("gunfight_escrow").Clauses(clause.Returning{}).
+		Where("user_id = ? AND gunfight_id IS NULL", userID).Delete(&escrows)
+	if result.Error != nil {
+		tx.Rollback()
+		return errors.DeleteError(contextData, "gunfight_escrow", result.Error)
+	}
+
+	for _, escrow := range escrows {
+		if err := r.addGold(ctx, tx, escrow.UserID, escrow.Gold); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return errors.TransactionCommitError(contextData, err)
+	}
+
+	return nil
+}
+
+func (r *GunfightPostgresRepository) addGold(ctx context.Context, tx *gorm.DB, userID int, gold int) error {
+	_, err := r.BaseRepository.Update(ctx, tx, "money", "user_id", userID, map[string]interface{}{
+		"gold": gorm.Expr("gold + ?", gold),
+	})
+	return err
+}
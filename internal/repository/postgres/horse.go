@@ -62,3 +62,35 @@ func (r *HorsePostgresRepository) Update(ctx context.Context, userID int, horse
 
 	return nil
 }
+
+// AddRaceResult начисляет серебро за забег и обновляет рекорд дистанции, если он побит. Серебро прибавляется
+// к текущему балансу в базе, а не перезаписывается, поэтому параллельные списания и забеги не теряются.
+// Возвращает true, если забег стал новым рекордом
+func (r *HorsePostgresRepository) AddRaceResult(ctx context.Context, userID int, distance int, silver int) (bool, error) {
+	tx := r.BeginTransaction()
+	contextData := contextutils.ExtractContextData(ctx)
+	if tx.Error != nil {
+		return false, errors.TransactionStartError(contextData, tx.Error)
+	}
+
+	record := tx.WithContext(ctx).Table("horse").
+		Where("user_id = ? AND distance < ?", userID, distance).Update("distance", distance)
+	if record.Error != nil {
+		tx.Rollback()
+		return false, errors.UpdateError(contextData, "horse", record.Error)
+	}
+
+	_, err := r.BaseRepository.Update(ctx, tx, "money", "user_id", userID, map[string]interface{}{
+		"silver": gorm.Expr("silver + ?", silver),
+	})
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		return false, errors.TransactionCommitError(contextData, err)
+	}
+
+	return record.RowsAffected > 0, nil
+}
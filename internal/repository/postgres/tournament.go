@@ -0,0 +1,335 @@
+package postgres
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+	"wildwest/internal/errors"
+	"wildwest/internal/model/gunfight"
+	"wildwest/pkg/contextutils"
+)
+
+// tournamentColumns добавляет к турниру число зарегистрированных игроков
+const tournamentColumns = `gunfight_tournament.*,
+(SELECT COUNT(*) FROM gunfight_tournament_players WHERE tournament_id = gunfight_tournament.id) AS players`
+
+// CreateTournament создает турнир, если турнира с тем же временем старта еще нет. Возвращает false, если турнир уже был
+func (r *GunfightPostgresRepository) CreateTournament(ctx context.Context, tournament *gunfight.Tournament) (bool, error) {
+	result := r.db.WithContext(ctx).Table("gunfight_tournament").
+		Clauses(clause.OnConflict{DoNothing: true}).Create(tournament)
+	if result.Error != nil {
+		contextData := contextutils.ExtractContextData(ctx)
+		return false, errors.CreateError(contextData, "gunfight_tournament", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// GetTournament возвращает турнир с числом зарегистрированных игроков
+func (r *GunfightPostgresRepository) GetTournament(ctx context.Context, tournamentID int) (*gunfight.Tournament, error) {
+	var tournament gunfight.Tournament
+	err := r.db.WithContext(ctx).Table("gunfight_tournament").Select(tournamentColumns).
+		Where("id = ?", tournamentID).First(&tournament).Error
+	if err != nil {
+		contextData := contextutils.ExtractContextData(ctx)
+		return nil, errors.RecordNotFoundError(contextData, "gunfight_tournament")
+	}
+	return &tournament, nil
+}
+
+// GetTournaments возвращает турниры с перечисленными статусами по времени старта
+func (r *GunfightPostgresRepository) GetTournaments(ctx context.Context, statuses []string, limit int) ([]gunfight.Tournament, error) {
+	var tournaments []gunfight.Tournament
+	err := r.db.WithContext(ctx).Table("gunfight_tournament").Select(tournamentColumns).
+		Where("status IN ?", statuses).Order("start_date, id").Limit(limit).Find(&tournaments).Error
+	if err != nil {
+		contextData := contextutils.ExtractContextData(ctx)
+		return nil, errors.RecordNotFoundError(contextData, "gunfight_tournament")
+	}
+	return tournaments, nil
+}
+
+// GetTournamentPlayers возвращает участников турнира в порядке регистрации
+func (r *GunfightPostgresRepository) GetTournamentPlayers(ctx context.Context, tournamentID int) ([]gunfight.TournamentPlayer, error) {
+	var players []gunfight.TournamentPlayer
+	err := r.db.WithContext(ctx).Table("gunfight_tournament_players").
+		Where("tournament_id = ?", tournamentID).Order("created_at, user_id").Find(&players).Error
+	if err != nil {
+		contextData := contextutils.ExtractContextData(ctx)
+		return nil, errors.RecordNotFoundError(contextData, "gunfight_tournament_players")
+	}
+	return players, nil
+}
+
+// GetTournamentMatches возвращает матчи сетки по раундам
+func (r *GunfightPostgresRepository) GetTournamentMatches(ctx context.Context, tournamentID int) ([]gunfight.TournamentMatch, error) {
+	var matches []gunfight.TournamentMatch
+	err := r.db.WithContext(ctx).Table("gunfight_tournament_matches").
+		Where("tournament_id = ?", tournamentID).Order("round, position").Find(&matches).Error
+	if err != nil {
+		contextData := contextutils.ExtractContextData(ctx)
+		return nil, errors.RecordNotFoundError(contextData, "gunfight_tournament_matches")
+	}
+	return matches, nil
+}
+
+// RegisterTournament списывает взнос и регистрирует игрока, пока регистрация открыта и в сетке есть места
+func (r *GunfightPostgresRepository) RegisterTournament(ctx context.Context, tournamentID, userID int) error {
+	tx := r.BeginTransaction()
+	contextData := contextutils.ExtractContextData(ctx)
+	if tx.Error != nil {
+		return errors.TransactionStartError(contextData, tx.Error)
+	}
+
+	tournament, err := r.lockRegistration(ctx, tx, tournamentID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	var players int64
+	if err := tx.WithContext(ctx).Table("gunfight_tournament_players").
+		Where("tournament_id = ?", tournamentID).Count(&players).Error; err != nil {
+		tx.Rollback()
+		return errors.RecordNotFoundError(contextData, "gunfight_tournament_players")
+	}
+	if players >= int64(tournament.Size) {
+		tx.Rollback()
+		return errors.CapacityExceededError(contextData, "gunfight_tournament")
+	}
+
+	if tournament.EntryFee > 0 {
+		result := tx.WithContext(ctx).Table("money").
+			Where("user_id = ? AND gold >= ?", userID, tournament.EntryFee).
+			Update("gold", gorm.Expr("gold - ?", tournament.EntryFee))
+		if result.Error != nil {
+			tx.Rollback()
+			return errors.UpdateError(contextData, "money", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			tx.Rollback()
+			return errors.InsufficientFundsError(contextData, "money")
+		}
+	}
+
+	player := &gunfight.TournamentPlayer{TournamentID: tournamentID, UserID: userID}
+	if _, err := r.BaseRepository.Create(ctx, tx, "gunfight_tournament_players", player); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return errors.TransactionCommitError(contextData, err)
+	}
+
+	return nil
+}
+
+// UnregisterTournament снимает игрока с турнира до старта и возвращает взнос
+func (r *GunfightPostgresRepository) UnregisterTournament(ctx context.Context, tournamentID, userID int) error {
+	tx := r.BeginTransaction()
+	contextData := contextutils.ExtractContextData(ctx)
+	if tx.Error != nil {
+		return errors.TransactionStartError(contextData, tx.Error)
+	}
+
+	tournament, err := r.lockRegistration(ctx, tx, tournamentID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	deleted := tx.WithContext(ctx).Table("gunfight_tournament_players").
+		Where("tournament_id = ? AND user_id = ?", tournamentID, userID).Delete(nil)
+	if deleted.Error != nil {
+		tx.Rollback()
+		return errors.DeleteError(contextData, "gunfight_tournament_players", deleted.Error)
+	}
+	if deleted.RowsAffected == 0 {
+		tx.Rollback()
+		return errors.RecordNotFoundError(contextData, "gunfight_tournament_players")
+	}
+
+	if tournament.EntryFee > 0 {
+		if err := r.addGold(ctx, tx, userID, tournament.EntryFee); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return errors.TransactionCommitError(contextData, err)
+	}
+
+	return nil
+}
+
+// lockRegistration блокирует турнир, пока регистрация на него открыта, чтобы регистрации не превысили размер сетки
+func (r *GunfightPostgresRepository) lockRegistration(ctx context.Context, tx *gorm.DB, tournamentID int) (*gunfight.Tournament, error) {
+	var tournament gunfight.Tournament
+	err := tx.WithContext(ctx).Table("gunfight_tournament").Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND status = ? AND start_date > ?", tournamentID, gunfight.TournamentRegistration, time.Now()).
+		First(&tournament).Error
+	if err != nil {
+		contextData := contextutils.ExtractContextData(ctx)
+		return nil, errors.RecordNotFoundError(contextData, "gunfight_tournament")
+	}
+	return &tournament, nil
+}
+
+// CancelTournament отменяет турнир, который не набрал игроков, и возвращает взносы
+func (r *GunfightPostgresRepository) CancelTournament(ctx context.Context, tournamentID int) error {
+	tx := r.BeginTransaction()
+	contextData := contextutils.ExtractContextData(ctx)
+	if tx.Error != nil {
+		return errors.TransactionStartError(contextData, tx.Error)
+	}
+
+	var tournament gunfight.Tournament
+	err := tx.WithContext(ctx).Table("gunfight_tournament").Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND status = ?", tournamentID, gunfight.TournamentRegistration).First(&tournament).Error
+	if err != nil {
+		tx.Rollback()
+		return errors.RecordNotFoundError(contextData, "gunfight_tournament")
+	}
+
+	if _, err := r.BaseRepository.Update(ctx, tx, "gunfight_tournament", "id", tournamentID, map[string]interface{}{
+		"status":   gunfight.TournamentCancelled,
+		"end_date": time.Now(),
+	}); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if tournament.EntryFee > 0 {
+		var players []gunfight.TournamentPlayer
+		if err := tx.WithContext(ctx).Table("gunfight_tournament_players").
+			Where("tournament_id = ?", tournamentID).Find(&players).Error; err != nil {
+			tx.Rollback()
+			return errors.RecordNotFoundError(contextData, "gunfight_tournament_players")
+		}
+		for _, player := range players {
+			if err := r.addGold(ctx, tx, player.UserID, tournament.EntryFee); err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return errors.TransactionCommitError(contextData, err)
+	}
+
+	return nil
+}
+
+// StartTournamentRound начинает раунд round: создает игры матчей с двумя игроками и записывает матчи сетки.
+// Для первого раунда players — посев участников. Раунд начинает только один инстанс: возвращает false,
+// если раунд уже начат
+func (r *GunfightPostgresRepository) StartTournamentRound(ctx context.Context, tournamentID, round int, players []gunfight.TournamentPlayer, matches []gunfight.TournamentMatch) (bool, error) {
+	tx := r.BeginTransaction()
+	contextData := contextutils.ExtractContextData(ctx)
+	if tx.Error != nil {
+		return false, errors.TransactionStartError(contextData, tx.Error)
+	}
+
+	updated := tx.WithContext(ctx).Table("gunfight_tournament").
+		Where("id = ? AND round = ? AND status IN ?", tournamentID, round-1,
+			[]string{gunfight.TournamentRegistration, gunfight.TournamentRunning}).
+		Updates(map[string]interface{}{"round": round, "status": gunfight.TournamentRunning})
+	if updated.Error != nil {
+		tx.Rollback()
+		return false, errors.UpdateError(contextData, "gunfight_tournament", updated.Error)
+	}
+	if updated.RowsAffected == 0 {
+		tx.Rollback()
+		return false, nil
+	}
+
+	for _, player := range players {
+		seeded := tx.WithContext(ctx).Table("gunfight_tournament_players").
+			Where("tournament_id = ? AND user_id = ?", tournamentID, player.UserID).
+			Updates(map[string]interface{}{"seed": player.Seed, "rating": player.Rating})
+		if seeded.Error != nil {
+			tx.Rollback()
+			return false, errors.UpdateError(contextData, "gunfight_tournament_players", seeded.Error)
+		}
+	}
+
+	for i := range matches {
+		match := &matches[i]
+		if match.User1ID != nil && match.User2ID != nil {
+			game := &gunfight.Game{User1ID: *match.User1ID, User2ID: *match.User2ID}
+			if err := r.createGame(ctx, tx, game); err != nil {
+				tx.Rollback()
+				return false, err
+			}
+			match.GunfightID = &game.ID
+		}
+		if _, err := r.BaseRepository.Create(ctx, tx, "gunfight_tournament_matches", match); err != nil {
+			tx.Rollback()
+			return false, err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return false, errors.TransactionCommitError(contextData, err)
+	}
+
+	return true, nil
+}
+
+// DecideTournamentMatch записывает победителя матча, nil — в матче никто не прошел дальше
+func (r *GunfightPostgresRepository) DecideTournamentMatch(ctx context.Context, matchID int, winnerID *int, walkover bool) error {
+	result := r.db.WithContext(ctx).Table("gunfight_tournament_matches").
+		Where("id = ? AND decided = FALSE", matchID).
+		Updates(map[string]interface{}{"winner_id": winnerID, "walkover": walkover, "decided": true})
+	contextData := contextutils.ExtractContextData(ctx)
+	if result.Error != nil {
+		return errors.UpdateError(contextData, "gunfight_tournament_matches", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.RecordNotFoundError(contextData, "gunfight_tournament_matches")
+	}
+	return nil
+}
+
+// FinishTournament записывает победителя турнира и выплачивает призы из банка
+func (r *GunfightPostgresRepository) FinishTournament(ctx context.Context, tournamentID int, winnerID *int, prizes map[int]int) error {
+	tx := r.BeginTransaction()
+	contextData := contextutils.ExtractContextData(ctx)
+	if tx.Error != nil {
+		return errors.TransactionStartError(contextData, tx.Error)
+	}
+
+	updated := tx.WithContext(ctx).Table("gunfight_tournament").
+		Where("id = ? AND status = ?", tournamentID, gunfight.TournamentRunning).
+		Updates(map[string]interface{}{"status": gunfight.TournamentFinished, "winner_id": winnerID, "end_date": time.Now()})
+	if updated.Error != nil {
+		tx.Rollback()
+		return errors.UpdateError(contextData, "gunfight_tournament", updated.Error)
+	}
+	if updated.RowsAffected == 0 {
+		tx.Rollback()
+		return errors.RecordNotFoundError(contextData, "gunfight_tournament")
+	}
+
+	for userID, prize := range prizes {
+		if err := r.addGold(ctx, tx, userID, prize); err != nil {
+			tx.Rollback()
+			return err
+		}
+		paid := tx.WithContext(ctx).Table("gunfight_tournament_players").
+			Where("tournament_id = ? AND user_id = ?", tournamentID, userID).Update("prize", prize)
+		if paid.Error != nil {
+			tx.Rollback()
+			return errors.UpdateError(contextData, "gunfight_tournament_players", paid.Error)
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return errors.TransactionCommitError(contextData, err)
+	}
+
+	return nil
+}
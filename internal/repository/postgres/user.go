@@ -29,6 +29,15 @@ func (r *UserPostgresRepository) Get(ctx context.Context, userID int) (*user.Use
 	return &userData, nil
 }
 
+func (r *UserPostgresRepository) GetByLink(ctx context.Context, link string) (*user.User, error) {
+	var userData user.User
+	err := r.BaseRepository.Get(ctx, nil, "users", "link", link, &userData)
+	if err != nil {
+		return nil, err
+	}
+	return &userData, nil
+}
+
 func (r *UserPostgresRepository) Create(ctx context.Context, user *user.User, horse *horse.Horse, money *money.Money) error {
 	tx := r.db.Begin()
 	contextData := contextutils.ExtractContextData(ctx)
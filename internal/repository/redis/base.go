@@ -2,6 +2,7 @@ package redis
 
 import (
 	"context"
+	"encoding/json"
 	"github.com/redis/go-redis/v9"
 	"time"
 )
@@ -33,3 +34,50 @@ func (r *BaseRedis) Delete(ctx context.Context, key string) error {
 	}
 	return nil
 }
+
+// publish отправляет значение в канал в виде JSON
+func publish(ctx context.Context, client *redis.Client, channel string, value interface{}) error {
+	payload, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return client.Publish(ctx, channel, payload).Err()
+}
+
+// subscribe подписывается на канал и разбирает сообщения из JSON, подписка закрывается вместе с ctx
+func subscribe[T any](ctx context.Context, client *redis.Client, channel string) (<-chan T, error) {
+	sub := client.Subscribe(ctx, channel)
+	if _, err := sub.Receive(ctx); err != nil {
+		sub.Close()
+		return nil, err
+	}
+
+	values := make(chan T, 1)
+	go func() {
+		defer close(values)
+		defer sub.Close()
+
+		messages := sub.Channel()
+		for {
+			select {
+			case message, ok := <-messages:
+				if !ok {
+					return
+				}
+				var value T
+				if err := json.Unmarshal([]byte(message.Payload), &value); err != nil {
+					continue
+				}
+				select {
+				case values <- value:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return values, nil
+}
@@ -2,9 +2,14 @@ package redis
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"github.com/redis/go-redis/v9"
-	"math"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
+	"wildwest/internal/model/gunfight"
 )
 
 type GunfightRedisRepository struct {
@@ -17,42 +22,752 @@ func NewGunfightRedis(redis *redis.Client) *GunfightRedisRepository {
 	}
 }
 
-// AddPlayerToQueue добавляет игрока в очередь для поиска соперника
-func (r *GunfightRedisRepository) AddPlayerToQueue(ctx context.Context, userID int, gold int) error {
-	return r.redis.ZAdd(ctx, "gunfight_queue", redis.Z{
-		Score:  float64(gold),
-		Member: userID,
-	}).Err()
+// queuePlayersKey хранит, в какой очереди ждет каждый игрок
+const queuePlayersKey = "gunfight_queue_players"
+
+const (
+	matchWaitsLimit = 50
+	matchWaitsTTL   = time.Hour
+)
+
+// matchOrEnqueueScript атомарно забирает из очереди первого подходящего соперника, пропуская самого игрока,
+// а если соперника нет, ставит игрока в очередь. Участник очереди — игрок или группа. Для уже ждущего
+// участника (ARGV[5] == '1') поиск идет, только пока он сам в очереди: иначе его уже забрал другой участник.
+// Из другой очереди участник уходит до запуска скрипта, см. leaveOtherQueue. Возвращает соперника или пустую строку
+var matchOrEnqueueScript = redis.NewScript(`
+if ARGV[5] == '1' and not redis.call('ZSCORE', KEYS[1], ARGV[1]) then
+	return ''
+end
+local candidates = redis.call('ZRANGEBYSCORE', KEYS[1], ARGV[2], ARGV[3], 'LIMIT', 0, 2)
+for _, member in ipairs(candidates) do
+	if member ~= ARGV[1] then
+		redis.call('ZREM', KEYS[1], member, ARGV[1])
+		redis.call('HDEL', KEYS[2], member, ARGV[1])
+		return member
+	end
+end
+redis.call('ZADD', KEYS[1], ARGV[4], ARGV[1])
+redis.call('HSET', KEYS[2], ARGV[1], KEYS[1])
+return ''
+`)
+
+// removePlayerScript удаляет игрока из очереди KEYS[1], если он все еще ждет именно в ней: очередь
+// передается ключом, чтобы скрипт трогал только объявленные ключи. Возвращает 1, если игрок был удален
+var removePlayerScript = redis.NewScript(`
+if redis.call('HGET', KEYS[2], ARGV[1]) ~= KEYS[1] then
+	return 0
+end
+redis.call('ZREM', KEYS[1], ARGV[1])
+redis.call('HDEL', KEYS[2], ARGV[1])
+return 1
+`)
+
+// joinPartyScript принимает игрока в группу по приглашению: приглашение тратится в любом случае,
+// а вступить можно только в существующую группу, которая не ищет соперников и не заполнена.
+// Возвращает статус вступления
+var joinPartyScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[3]) == 1 then
+	return 'in_party'
+end
+local expires = redis.call('HGET', KEYS[4], ARGV[2])
+if not expires then
+	return 'no_invite'
+end
+redis.call('HDEL', KEYS[4], ARGV[2])
+if tonumber(expires) < tonumber(ARGV[3]) then
+	return 'no_invite'
+end
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 'gone'
+end
+if redis.call('EXISTS', KEYS[5]) == 1 then
+	return 'searching'
+end
+if redis.call('SCARD', KEYS[2]) >= tonumber(ARGV[4]) then
+	return 'full'
+end
+redis.call('SADD', KEYS[2], ARGV[1])
+redis.call('SET', KEYS[3], ARGV[2], 'PX', ARGV[5])
+redis.call('PEXPIRE', KEYS[1], ARGV[5])
+redis.call('PEXPIRE', KEYS[2], ARGV[5])
+return 'joined'
+`)
+
+// leavePartyScript выводит игрока из группы, пока группа не ищет соперников; уход лидера распускает группу.
+// Ключи группы строятся из префиксов ARGV[2..4], потому что группа известна только по ключу игрока.
+// Возвращает статус ухода
+var leavePartyScript = redis.NewScript(`
+local partyID = redis.call('GET', KEYS[1])
+if not partyID then
+	return 'not_in_party'
+end
+local party = ARGV[2] .. partyID
+local members = ARGV[3] .. partyID
+if redis.call('EXISTS', ARGV[4] .. partyID) == 1 then
+	return 'searching'
+end
+if redis.call('HGET', party, 'leader') ~= ARGV[1] then
+	redis.call('SREM', members, ARGV[1])
+	redis.call('DEL', KEYS[1])
+	return 'left'
+end
+for _, member in ipairs(redis.call('SMEMBERS', members)) do
+	redis.call('DEL', ARGV[5] .. member)
+end
+redis.call('DEL', party, members)
+return 'disbanded'
+`)
+
+// partyLeaderField — поле хеша группы с лидером
+const partyLeaderField = "leader"
+
+// partyMemberPrefix — префикс ключа с группой игрока, он нужен leavePartyScript, чтобы распустить группу
+const partyMemberPrefix = "gunfight_party_member:"
+
+// partyQueueMember — участник очереди командных игр, которым группа стоит в очереди
+const partyQueueMember = "party:"
+
+// rematchStatusField хранит исход голосования за реванш, остальные поля хеша — голоса игроков
+const rematchStatusField = "status"
+
+// voteRematchScript записывает голос игрока за реванш, пока исход не решен. Реванш принят, когда за него
+// проголосовали оба игрока; отказ или истечение окна (ARGV[2] == 'expired') решают исход сразу.
+// Возвращает статус голосования и 1, если исход решил именно этот голос
+var voteRematchScript = redis.NewScript(`
+local status = redis.call('HGET', KEYS[1], ARGV[4])
+if status then
+	return {status, 0}
+end
+if ARGV[2] ~= 'expired' then
+	redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+end
+redis.call('PEXPIREAT', KEYS[1], ARGV[3])
+if ARGV[2] == 'accepted' and redis.call('HLEN', KEYS[1]) < 2 then
+	return {'requested', 0}
+end
+redis.call('HSET', KEYS[1], ARGV[4], ARGV[2])
+return {ARGV[2], 1}
+`)
+
+// acquireDuelLeaseScript занимает дуэль за инстансом ARGV[1] или продлевает его аренду на ARGV[2] мс.
+// Возвращает 1, если дуэль принадлежит этому инстансу
+var acquireDuelLeaseScript = redis.NewScript(`
+local owner = redis.call('GET', KEYS[1])
+if owner and owner ~= ARGV[1] then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+return 1
+`)
+
+// releaseDuelLeaseScript снимает аренду дуэли, только если она принадлежит инстансу ARGV[1]
+var releaseDuelLeaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// MatchOrEnqueue ищет соперника в диапазоне рейтинга среди игроков той же очереди и удаляет его из очереди,
+// если соперник не найден, игрок добавляется в очередь. Возвращает 0, если соперник не найден
+func (r *GunfightRedisRepository) MatchOrEnqueue(ctx context.Context, userID int, queue string, rating int, band gunfight.Band) (int, error) {
+	return r.match(ctx, userID, queue, rating, band, false)
+}
+
+// MatchWaiting повторяет поиск для игрока, который уже ждет в очереди
+func (r *GunfightRedisRepository) MatchWaiting(ctx context.Context, userID int, queue string, rating int, band gunfight.Band) (int, error) {
+	return r.match(ctx, userID, queue, rating, band, true)
+}
+
+func (r *GunfightRedisRepository) match(ctx context.Context, userID int, queue string, rating int, band gunfight.Band, waiting bool) (int, error) {
+	waitingFlag := "0"
+	if waiting {
+		waitingFlag = "1"
+	} else if err := r.leaveOtherQueue(ctx, strconv.Itoa(userID), queueKey(queue)); err != nil {
+		return 0, err
+	}
+
+	opponent, err := matchOrEnqueueScript.Run(ctx, r.redis, []string{queueKey(queue), queuePlayersKey},
+		userID, band.MinRating, band.MaxRating, rating, waitingFlag).Text()
+	if err != nil || opponent == "" {
+		return 0, err
+	}
+
+	return strconv.Atoi(opponent)
+}
+
+// MatchTeamOrEnqueue ищет группе соперников среди групп той же очереди командных игр и удаляет их из очереди,
+// если соперники не найдены, группа добавляется в очередь. Возвращает пустую строку, если соперники не найдены
+func (r *GunfightRedisRepository) MatchTeamOrEnqueue(ctx context.Context, partyID string, queue string, rating int, band gunfight.Band) (string, error) {
+	return r.matchTeam(ctx, partyID, queue, rating, band, "0")
+}
+
+// MatchTeamWaiting повторяет поиск для группы, которая уже ждет в очереди
+func (r *GunfightRedisRepository) MatchTeamWaiting(ctx context.Context, partyID string, queue string, rating int, band gunfight.Band) (string, error) {
+	return r.matchTeam(ctx, partyID, queue, rating, band, "1")
 }
 
-// FindOpponent ищет соперника для игрока с заданным количеством золота
-func (r *GunfightRedisRepository) FindOpponent(ctx context.Context, gold int) (int, error) {
-	minGold := int(math.Round(float64(gold) * 0.95))
-	maxGold := int(math.Round(float64(gold) * 1.05))
+func (r *GunfightRedisRepository) matchTeam(ctx context.Context, partyID string, queue string, rating int, band gunfight.Band, waitingFlag string) (string, error) {
+	if waitingFlag == "0" {
+		if err := r.leaveOtherQueue(ctx, partyQueueMember+partyID, teamQueueKey(queue)); err != nil {
+			return "", err
+		}
+	}
+
+	opponent, err := matchOrEnqueueScript.Run(ctx, r.redis, []string{teamQueueKey(queue), queuePlayersKey},
+		partyQueueMember+partyID, band.MinRating, band.MaxRating, rating, waitingFlag).Text()
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimPrefix(opponent, partyQueueMember), nil
+}
+
+// RemovePartyFromQueue удаляет группу из очереди командных игр. Возвращает false, если группы в очереди уже не было
+func (r *GunfightRedisRepository) RemovePartyFromQueue(ctx context.Context, partyID string) (bool, error) {
+	return r.removeFromQueue(ctx, partyQueueMember+partyID, "")
+}
+
+// RemovePlayerFromQueue Удаляет игрока из очереди. Возвращает false, если игрока в очереди уже не было:
+// например, его только что забрал соперник
+func (r *GunfightRedisRepository) RemovePlayerFromQueue(ctx context.Context, userID int) (bool, error) {
+	return r.removeFromQueue(ctx, strconv.Itoa(userID), "")
+}
+
+// leaveOtherQueue убирает участника из очереди, в которой он ждал, если это не очередь queue
+func (r *GunfightRedisRepository) leaveOtherQueue(ctx context.Context, member string, queue string) error {
+	_, err := r.removeFromQueue(ctx, member, queue)
+	return err
+}
+
+// removeFromQueue удаляет участника из очереди, в которой он ждет, кроме очереди except. Очередь читается
+// до запуска removePlayerScript, поэтому если участник за это время перешел в другую очередь, попытка повторяется.
+// Возвращает false, если участника в очереди уже не было
+func (r *GunfightRedisRepository) removeFromQueue(ctx context.Context, member string, except string) (bool, error) {
+	for {
+		queue, err := r.redis.HGet(ctx, queuePlayersKey, member).Result()
+		if err == redis.Nil || (err == nil && queue == except) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+		removed, err := removePlayerScript.Run(ctx, r.redis, []string{queue, queuePlayersKey}, member).Int()
+		if err != nil || removed == 1 {
+			return removed == 1, err
+		}
+	}
+}
+
+// GetQueueStatus возвращает число игроков очереди queue в диапазоне рейтинга и во всей очереди, не считая самого игрока
+func (r *GunfightRedisRepository) GetQueueStatus(ctx context.Context, userID int, queue string, band gunfight.Band) (int, int, error) {
+	member := strconv.Itoa(userID)
+
+	pipe := r.redis.Pipeline()
+	inBand := pipe.ZCount(ctx, queueKey(queue), strconv.Itoa(band.MinRating), strconv.Itoa(band.MaxRating))
+	total := pipe.ZCard(ctx, queueKey(queue))
+	score := pipe.ZScore(ctx, queueKey(queue), member)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return 0, 0, err
+	}
+
+	inBandCount, totalCount := int(inBand.Val()), int(total.Val())
+	if rating, err := score.Result(); err == nil {
+		totalCount--
+		if rating >= float64(band.MinRating) && rating <= float64(band.MaxRating) {
+			inBandCount--
+		}
+	}
+	return inBandCount, totalCount, nil
+}
+
+// RecordMatchWait запоминает, сколько игрок ждал соперника; хранятся последние matchWaitsLimit матчей за matchWaitsTTL
+func (r *GunfightRedisRepository) RecordMatchWait(ctx context.Context, queue string, wait time.Duration) error {
+	pipe := r.redis.TxPipeline()
+	pipe.LPush(ctx, matchWaitsKey(queue), wait.Milliseconds())
+	pipe.LTrim(ctx, matchWaitsKey(queue), 0, matchWaitsLimit-1)
+	pipe.Expire(ctx, matchWaitsKey(queue), matchWaitsTTL)
+	_, err := pipe.Exec(ctx)
+	return err
+}
 
-	opponents, err := r.redis.ZRangeByScore(ctx, "gunfight_queue", &redis.ZRangeBy{
-		Min:    strconv.Itoa(minGold), // Преобразование целого числа в строку
-		Max:    strconv.Itoa(maxGold), // Преобразование целого числа в строку
-		Offset: 0,
-		Count:  1,
+// GetMatchWaits возвращает время ожидания недавних матчей в очереди queue
+func (r *GunfightRedisRepository) GetMatchWaits(ctx context.Context, queue string) ([]time.Duration, error) {
+	values, err := r.redis.LRange(ctx, matchWaitsKey(queue), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	waits := make([]time.Duration, 0, len(values))
+	for _, value := range values {
+		millis, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		waits = append(waits, time.Duration(millis)*time.Millisecond)
+	}
+	return waits, nil
+}
+
+// IncrementQueueCounter засчитывает очереди queue исход поиска соперника
+func (r *GunfightRedisRepository) IncrementQueueCounter(ctx context.Context, queue string, counter string) error {
+	return r.redis.HIncrBy(ctx, queueCountersKey(queue), counter, 1).Err()
+}
+
+// GetQueueCounters возвращает число ждущих в очереди queue игроков и счетчики исходов поиска
+func (r *GunfightRedisRepository) GetQueueCounters(ctx context.Context, queue string) (int, map[string]int, error) {
+	pipe := r.redis.Pipeline()
+	waiting := pipe.ZCard(ctx, queueKey(queue))
+	fields := pipe.HGetAll(ctx, queueCountersKey(queue))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, nil, err
+	}
+
+	counters := make(map[string]int, len(fields.Val()))
+	for counter, value := range fields.Val() {
+		if count, err := strconv.Atoi(value); err == nil {
+			counters[counter] = count
+		}
+	}
+	return int(waiting.Val()), counters, nil
+}
+
+// PublishMatch уведомляет ожидающего игрока о найденном матче, на каком бы инстансе ни был открыт его сокет
+func (r *GunfightRedisRepository) PublishMatch(ctx context.Context, userID int, match gunfight.Match) error {
+	return publish(ctx, r.redis, matchChannel(userID), match)
+}
+
+// SubscribeMatch подписывается на уведомления о матче для игрока, подписка закрывается вместе с ctx
+func (r *GunfightRedisRepository) SubscribeMatch(ctx context.Context, userID int) (<-chan gunfight.Match, error) {
+	return subscribe[gunfight.Match](ctx, r.redis, matchChannel(userID))
+}
+
+// CreateChallenge сохраняет вызов до его истечения и уведомляет вызванного игрока
+func (r *GunfightRedisRepository) CreateChallenge(ctx context.Context, challenge gunfight.Challenge, ttl time.Duration) error {
+	payload, err := json.Marshal(challenge)
+	if err != nil {
+		return err
+	}
+
+	pipe := r.redis.TxPipeline()
+	pipe.Set(ctx, challengeKey(challenge.TargetID, challenge.ID), payload, ttl)
+	pipe.ZRemRangeByScore(ctx, challengesKey(challenge.TargetID), "-inf", strconv.FormatInt(time.Now().Unix(), 10))
+	pipe.ZAdd(ctx, challengesKey(challenge.TargetID), redis.Z{Score: float64(challenge.ExpiresAt.Unix()), Member: challenge.ID})
+	pipe.Expire(ctx, challengesKey(challenge.TargetID), ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	return publish(ctx, r.redis, challengeChannel(challenge.TargetID), challenge)
+}
+
+// ClaimChallenge атомарно забирает вызов: ответить, отозвать или просрочить его можно только один раз.
+// Возвращает nil, если вызова уже нет
+func (r *GunfightRedisRepository) ClaimChallenge(ctx context.Context, targetID int, challengeID string) (*gunfight.Challenge, error) {
+	payload, err := r.redis.GetDel(ctx, challengeKey(targetID, challengeID)).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.redis.ZRem(ctx, challengesKey(targetID), challengeID).Err(); err != nil {
+		return nil, err
+	}
+
+	var challenge gunfight.Challenge
+	if err := json.Unmarshal([]byte(payload), &challenge); err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+// GetPendingChallenges возвращает еще не истекшие вызовы игрока
+func (r *GunfightRedisRepository) GetPendingChallenges(ctx context.Context, targetID int) ([]gunfight.Challenge, error) {
+	ids, err := r.redis.ZRangeByScore(ctx, challengesKey(targetID), &redis.ZRangeBy{
+		Min: strconv.FormatInt(time.Now().Unix(), 10),
+		Max: "+inf",
 	}).Result()
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, challengeKey(targetID, id))
+	}
+
+	payloads, err := r.redis.MGet(ctx, keys...).Result()
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	if len(opponents) == 0 {
-		return 0, nil // No opponent found
+	var challenges []gunfight.Challenge
+	for _, payload := range payloads {
+		data, ok := payload.(string)
+		if !ok {
+			continue
+		}
+		var challenge gunfight.Challenge
+		if err := json.Unmarshal([]byte(data), &challenge); err != nil {
+			continue
+		}
+		challenges = append(challenges, challenge)
 	}
+	return challenges, nil
+}
 
-	opponentID, err := strconv.Atoi(opponents[0])
+// SubscribeChallenges подписывается на новые вызовы игроку
+func (r *GunfightRedisRepository) SubscribeChallenges(ctx context.Context, targetID int) (<-chan gunfight.Challenge, error) {
+	return subscribe[gunfight.Challenge](ctx, r.redis, challengeChannel(targetID))
+}
+
+// PublishChallengeReply сообщает бросившему вызов игроку, чем закончился вызов
+func (r *GunfightRedisRepository) PublishChallengeReply(ctx context.Context, reply gunfight.ChallengeReply) error {
+	return publish(ctx, r.redis, challengeReplyChannel(reply.ChallengeID), reply)
+}
+
+// SubscribeChallengeReply подписывается на ответ на вызов
+func (r *GunfightRedisRepository) SubscribeChallengeReply(ctx context.Context, challengeID string) (<-chan gunfight.ChallengeReply, error) {
+	return subscribe[gunfight.ChallengeReply](ctx, r.redis, challengeReplyChannel(challengeID))
+}
+
+// VoteRematch записывает голос игрока за реванш: accepted, declined или expired, когда окно реванша закончилось.
+// Голоса хранятся до expireAt. Возвращает статус голосования и признак того, что исход решил этот голос
+func (r *GunfightRedisRepository) VoteRematch(ctx context.Context, gunfightID, userID int, vote string, expireAt time.Time) (string, bool, error) {
+	result, err := voteRematchScript.Run(ctx, r.redis, []string{rematchKey(gunfightID)},
+		userID, vote, expireAt.UnixMilli(), rematchStatusField).Slice()
+	if err != nil {
+		return "", false, err
+	}
+	if len(result) != 2 {
+		return "", false, fmt.Errorf("unexpected rematch vote result %v", result)
+	}
+
+	status, _ := result[0].(string)
+	decided, _ := result[1].(int64)
+	return status, decided == 1, nil
+}
+
+// GetRematchVotes возвращает голоса игроков за реванш и исход голосования, пустой — если он еще не решен
+func (r *GunfightRedisRepository) GetRematchVotes(ctx context.Context, gunfightID int) (map[int]string, string, error) {
+	fields, err := r.redis.HGetAll(ctx, rematchKey(gunfightID)).Result()
+	if err != nil {
+		return nil, "", err
+	}
+
+	votes := make(map[int]string, len(fields))
+	for field, vote := range fields {
+		if userID, err := strconv.Atoi(field); err == nil {
+			votes[userID] = vote
+		}
+	}
+	return votes, fields[rematchStatusField], nil
+}
+
+// PublishRematch сообщает обоим игрокам о просьбе о реванше и об исходе голосования
+func (r *GunfightRedisRepository) PublishRematch(ctx context.Context, reply gunfight.RematchReply) error {
+	return publish(ctx, r.redis, rematchChannel(reply.GunfightID), reply)
+}
+
+// SubscribeRematch подписывается на голосование за реванш
+func (r *GunfightRedisRepository) SubscribeRematch(ctx context.Context, gunfightID int) (<-chan gunfight.RematchReply, error) {
+	return subscribe[gunfight.RematchReply](ctx, r.redis, rematchChannel(gunfightID))
+}
+
+// CreateParty создает группу с лидером во главе. Возвращает false, если лидер уже состоит в группе
+func (r *GunfightRedisRepository) CreateParty(ctx context.Context, party gunfight.Party, ttl time.Duration) (bool, error) {
+	created, err := r.redis.SetNX(ctx, partyMemberKey(party.LeaderID), party.ID, ttl).Result()
+	if err != nil || !created {
+		return false, err
+	}
+
+	pipe := r.redis.TxPipeline()
+	pipe.HSet(ctx, partyKey(party.ID), partyLeaderField, party.LeaderID)
+	pipe.Expire(ctx, partyKey(party.ID), ttl)
+	pipe.SAdd(ctx, partyMembersKey(party.ID), party.LeaderID)
+	pipe.Expire(ctx, partyMembersKey(party.ID), ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetParty возвращает группу, nil — если ее нет
+func (r *GunfightRedisRepository) GetParty(ctx context.Context, partyID string) (*gunfight.Party, error) {
+	pipe := r.redis.Pipeline()
+	leader := pipe.HGet(ctx, partyKey(partyID), partyLeaderField)
+	members := pipe.SMembers(ctx, partyMembersKey(partyID))
+	if _, err := pipe.Exec(ctx); err == redis.Nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	leaderID, err := strconv.Atoi(leader.Val())
+	if err != nil {
+		return nil, err
+	}
+
+	party := &gunfight.Party{ID: partyID, LeaderID: leaderID, Members: make([]int, 0, len(members.Val()))}
+	for _, member := range members.Val() {
+		if userID, err := strconv.Atoi(member); err == nil {
+			party.Members = append(party.Members, userID)
+		}
+	}
+	slices.Sort(party.Members)
+	return party, nil
+}
+
+// GetPartyOf возвращает группу игрока, nil — если игрок не состоит в группе
+func (r *GunfightRedisRepository) GetPartyOf(ctx context.Context, userID int) (*gunfight.Party, error) {
+	partyID, err := r.Get(ctx, partyMemberKey(userID))
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.GetParty(ctx, partyID)
+}
+
+// AddPartyInvite сохраняет приглашение игрока в группу до expiresAt
+func (r *GunfightRedisRepository) AddPartyInvite(ctx context.Context, userID int, partyID string, expiresAt time.Time) error {
+	pipe := r.redis.TxPipeline()
+	pipe.HSet(ctx, partyInvitesKey(userID), partyID, expiresAt.UnixMilli())
+	pipe.PExpireAt(ctx, partyInvitesKey(userID), expiresAt)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// GetPartyInvites возвращает еще не истекшие приглашения игрока: группу и время истечения приглашения
+func (r *GunfightRedisRepository) GetPartyInvites(ctx context.Context, userID int) (map[string]time.Time, error) {
+	fields, err := r.redis.HGetAll(ctx, partyInvitesKey(userID)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	invites := make(map[string]time.Time, len(fields))
+	for partyID, value := range fields {
+		millis, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		if expiresAt := time.UnixMilli(millis); expiresAt.After(time.Now()) {
+			invites[partyID] = expiresAt
+		}
+	}
+	return invites, nil
+}
+
+// JoinParty принимает игрока в группу по приглашению, не больше size игроков в группе.
+// Возвращает статус вступления: joined или причину отказа
+func (r *GunfightRedisRepository) JoinParty(ctx context.Context, userID int, partyID string, size int, ttl time.Duration) (string, error) {
+	keys := []string{partyKey(partyID), partyMembersKey(partyID), partyMemberKey(userID), partyInvitesKey(userID), partySearchKey(partyID)}
+	return joinPartyScript.Run(ctx, r.redis, keys,
+		userID, partyID, time.Now().UnixMilli(), size, ttl.Milliseconds()).Text()
+}
+
+// LeaveParty выводит игрока из группы, уход лидера распускает группу. Возвращает статус ухода
+func (r *GunfightRedisRepository) LeaveParty(ctx context.Context, userID int) (string, error) {
+	return leavePartyScript.Run(ctx, r.redis, []string{partyMemberKey(userID)},
+		userID, partyKey(""), partyMembersKey(""), partySearchKey(""), partyMemberPrefix).Text()
+}
+
+// SetPartySearching отмечает, что группа ищет соперников: пока отметка жива, состав группы не меняется
+func (r *GunfightRedisRepository) SetPartySearching(ctx context.Context, partyID string, ttl time.Duration) error {
+	return r.Set(ctx, partySearchKey(partyID), 1, ttl)
+}
+
+// ClearPartySearching снимает отметку поиска с группы
+func (r *GunfightRedisRepository) ClearPartySearching(ctx context.Context, partyID string) error {
+	return r.redis.Del(ctx, partySearchKey(partyID)).Err()
+}
+
+// SaveDuelState сохраняет состояние идущей дуэли
+func (r *GunfightRedisRepository) SaveDuelState(ctx context.Context, state gunfight.DuelState, ttl time.Duration) error {
+	payload, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return r.Set(ctx, duelStateKey(state.GunfightID), payload, ttl)
+}
+
+// GetDuelState возвращает сохраненное состояние дуэли, nil — если его нет
+func (r *GunfightRedisRepository) GetDuelState(ctx context.Context, gunfightID int) (*gunfight.DuelState, error) {
+	payload, err := r.Get(ctx, duelStateKey(gunfightID))
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var state gunfight.DuelState
+	if err := json.Unmarshal([]byte(payload), &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// AppendDuelEvent дописывает сообщение дуэли в журнал, по которому переподключившийся игрок получает пропущенное
+func (r *GunfightRedisRepository) AppendDuelEvent(ctx context.Context, gunfightID int, message []byte, ttl time.Duration) error {
+	pipe := r.redis.TxPipeline()
+	pipe.RPush(ctx, duelEventsKey(gunfightID), message)
+	pipe.Expire(ctx, duelEventsKey(gunfightID), ttl)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// AcquireDuelLease занимает дуэль за инстансом owner на время ttl или продлевает его аренду.
+// Возвращает false, если дуэль идет на другом инстансе
+func (r *GunfightRedisRepository) AcquireDuelLease(ctx context.Context, gunfightID int, owner string, ttl time.Duration) (bool, error) {
+	acquired, err := acquireDuelLeaseScript.Run(ctx, r.redis, []string{duelLeaseKey(gunfightID)}, owner, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return acquired == 1, nil
+}
+
+// ReleaseDuelLease снимает аренду дуэли инстанса owner
+func (r *GunfightRedisRepository) ReleaseDuelLease(ctx context.Context, gunfightID int, owner string) error {
+	return releaseDuelLeaseScript.Run(ctx, r.redis, []string{duelLeaseKey(gunfightID)}, owner).Err()
+}
+
+// HasDuelLease проверяет, идет ли дуэль на каком-нибудь инстансе
+func (r *GunfightRedisRepository) HasDuelLease(ctx context.Context, gunfightID int) (bool, error) {
+	count, err := r.redis.Exists(ctx, duelLeaseKey(gunfightID)).Result()
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// GetDuelEvents возвращает журнал сообщений дуэли по порядку
+func (r *GunfightRedisRepository) GetDuelEvents(ctx context.Context, gunfightID int) ([]gunfight.Message, error) {
+	payloads, err := r.redis.LRange(ctx, duelEventsKey(gunfightID), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]gunfight.Message, 0, len(payloads))
+	for _, payload := range payloads {
+		message, err := gunfight.DecodeMessage([]byte(payload))
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+// AddPenalty засчитывает игроку уход из дуэли и возвращает число уходов за последнее окно window
+func (r *GunfightRedisRepository) AddPenalty(ctx context.Context, userID int, window time.Duration) (int, error) {
+	pipe := r.redis.TxPipeline()
+	count := pipe.Incr(ctx, penaltyKey(userID))
+	pipe.Expire(ctx, penaltyKey(userID), window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return int(count.Val()), nil
+}
+
+// SetCooldown запрещает игроку поиск соперника на время cooldown
+func (r *GunfightRedisRepository) SetCooldown(ctx context.Context, userID int, cooldown time.Duration) error {
+	return r.Set(ctx, cooldownKey(userID), 1, cooldown)
+}
+
+// GetCooldown возвращает, сколько игроку еще ждать до поиска соперника, 0 — если ждать не нужно
+func (r *GunfightRedisRepository) GetCooldown(ctx context.Context, userID int) (time.Duration, error) {
+	ttl, err := r.redis.PTTL(ctx, cooldownKey(userID)).Result()
 	if err != nil {
 		return 0, err
 	}
+	return max(ttl, 0), nil
+}
+
+func queueKey(queue string) string {
+	return fmt.Sprintf("gunfight_queue:%s", queue)
+}
+
+func teamQueueKey(queue string) string {
+	return fmt.Sprintf("gunfight_team_queue:%s", queue)
+}
+
+func queueCountersKey(queue string) string {
+	return fmt.Sprintf("gunfight_queue_counters:%s", queue)
+}
+
+func matchChannel(userID int) string {
+	return fmt.Sprintf("gunfight_match:%d", userID)
+}
+
+func matchWaitsKey(queue string) string {
+	return fmt.Sprintf("gunfight_match_waits:%s", queue)
+}
+
+func duelStateKey(gunfightID int) string {
+	return fmt.Sprintf("gunfight_state:%d", gunfightID)
+}
+
+func duelEventsKey(gunfightID int) string {
+	return fmt.Sprintf("gunfight_events:%d", gunfightID)
+}
+
+func duelLeaseKey(gunfightID int) string {
+	return fmt.Sprintf("gunfight_owner:%d", gunfightID)
+}
+
+func penaltyKey(userID int) string {
+	return fmt.Sprintf("gunfight_penalty:%d", userID)
+}
+
+func cooldownKey(userID int) string {
+	return fmt.Sprintf("gunfight_cooldown:%d", userID)
+}
+
+func challengeKey(targetID int, challengeID string) string {
+	return fmt.Sprintf("gunfight_challenge:%d:%s", targetID, challengeID)
+}
+
+func challengesKey(targetID int) string {
+	return fmt.Sprintf("gunfight_challenges:%d", targetID)
+}
+
+func challengeChannel(targetID int) string {
+	return fmt.Sprintf("gunfight_challenge_inbox:%d", targetID)
+}
+
+func challengeReplyChannel(challengeID string) string {
+	return fmt.Sprintf("gunfight_challenge_reply:%s", challengeID)
+}
+
+func partyKey(partyID string) string {
+	return fmt.Sprintf("gunfight_party:%s", partyID)
+}
+
+func partyMembersKey(partyID string) string {
+	return fmt.Sprintf("gunfight_party_members:%s", partyID)
+}
+
+func partySearchKey(partyID string) string {
+	return fmt.Sprintf("gunfight_party_search:%s", partyID)
+}
+
+func partyMemberKey(userID int) string {
+	return fmt.Sprintf("%s%d", partyMemberPrefix, userID)
+}
+
+func partyInvitesKey(userID int) string {
+	return fmt.Sprintf("gunfight_party_invites:%d", userID)
+}
 
-	return opponentID, nil
+func rematchKey(gunfightID int) string {
+	return fmt.Sprintf("gunfight_rematch:%d", gunfightID)
 }
 
-// RemovePlayerFromQueue Удаляет игрока из очереди
-func (r *GunfightRedisRepository) RemovePlayerFromQueue(ctx context.Context, userID int) error {
-	return r.redis.ZRem(ctx, "gunfight_queue", userID).Err()
+func rematchChannel(gunfightID int) string {
+	return fmt.Sprintf("gunfight_rematch_reply:%d", gunfightID)
 }
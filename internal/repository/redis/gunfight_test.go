@@ -0,0 +1,131 @@
+package redis
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"sync"
+	"testing"
+	"wildwest/internal/model/gunfight"
+)
+
+func newTestGunfightRedis(t *testing.T) (*GunfightRedisRepository, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewGunfightRedis(client), server
+}
+
+var testBand = gunfight.Band{MinRating: 0, MaxRating: 2000}
+
+func TestMatchOrEnqueueConcurrent(t *testing.T) {
+	repo, _ := newTestGunfightRedis(t)
+	ctx := context.Background()
+
+	const players = 100
+	var mu sync.Mutex
+	matched := make(map[int]int, players)
+	match := func(userID, opponentID int) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, id := range []int{userID, opponentID} {
+			if previous, ok := matched[id]; ok {
+				t.Errorf("player %d matched twice: with %d and with %d", id, previous, userID+opponentID-id)
+			}
+		}
+		matched[userID], matched[opponentID] = opponentID, userID
+	}
+
+	var wg sync.WaitGroup
+	for userID := 1; userID <= players; userID++ {
+		wg.Add(1)
+		go func(userID int) {
+			defer wg.Done()
+			opponentID, err := repo.MatchOrEnqueue(ctx, userID, "casual", 1000, testBand)
+			if err != nil {
+				t.Errorf("player %d: %v", userID, err)
+				return
+			}
+			if opponentID == userID {
+				t.Errorf("player %d matched with themself", userID)
+				return
+			}
+			if opponentID != 0 {
+				match(userID, opponentID)
+			}
+		}(userID)
+	}
+	wg.Wait()
+
+	// Игрок встает в очередь, только если в ней нет подходящего соперника, поэтому четное число игроков
+	// разбирается целиком
+	if len(matched) != players {
+		t.Fatalf("%d of %d players matched", len(matched), players)
+	}
+	for userID, opponentID := range matched {
+		if matched[opponentID] != userID {
+			t.Fatalf("player %d matched with %d, but %d matched with %d", userID, opponentID, opponentID, matched[opponentID])
+		}
+	}
+	if waiting, err := repo.redis.ZCard(ctx, queueKey("casual")).Result(); err != nil || waiting != 0 {
+		t.Fatalf("queue has %d players left (%v), want 0", waiting, err)
+	}
+	if waiting, err := repo.redis.HLen(ctx, queuePlayersKey).Result(); err != nil || waiting != 0 {
+		t.Fatalf("%d players are still marked as waiting (%v), want 0", waiting, err)
+	}
+}
+
+func TestMatchOrEnqueueLeavesPreviousQueue(t *testing.T) {
+	repo, _ := newTestGunfightRedis(t)
+	ctx := context.Background()
+
+	for _, queue := range []string{"casual", "ranked"} {
+		if opponentID, err := repo.MatchOrEnqueue(ctx, 1, queue, 1000, testBand); err != nil || opponentID != 0 {
+			t.Fatalf("enqueue into %s: opponent %d, error %v", queue, opponentID, err)
+		}
+	}
+
+	if waiting, err := repo.redis.ZCard(ctx, queueKey("casual")).Result(); err != nil || waiting != 0 {
+		t.Fatalf("previous queue has %d players (%v), want 0", waiting, err)
+	}
+	if opponentID, err := repo.MatchOrEnqueue(ctx, 2, "casual", 1000, testBand); err != nil || opponentID != 0 {
+		t.Fatalf("player 2 matched with %d (%v) from the queue player 1 left", opponentID, err)
+	}
+	if opponentID, err := repo.MatchOrEnqueue(ctx, 3, "ranked", 1000, testBand); err != nil || opponentID != 1 {
+		t.Fatalf("player 3 matched with %d (%v), want player 1", opponentID, err)
+	}
+}
+
+func TestGetQueueStatusCountsOnlyTheQueue(t *testing.T) {
+	repo, _ := newTestGunfightRedis(t)
+	ctx := context.Background()
+
+	enqueue := func(userID int, queue string, rating int) {
+		if opponentID, err := repo.MatchOrEnqueue(ctx, userID, queue, rating, gunfight.Band{MinRating: rating, MaxRating: rating}); err != nil || opponentID != 0 {
+			t.Fatalf("enqueue %d into %s: opponent %d, error %v", userID, queue, opponentID, err)
+		}
+	}
+	enqueue(1, "ranked", 1000)
+	enqueue(2, "ranked", 1500)
+	enqueue(3, "ranked", 1900)
+	enqueue(4, "casual", 1000)
+	enqueue(5, "casual", 1200)
+
+	inBand, total, err := repo.GetQueueStatus(ctx, 1, "ranked", gunfight.Band{MinRating: 900, MaxRating: 1600})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inBand != 1 || total != 2 {
+		t.Fatalf("in band %d, total %d; want 1 and 2 without the player and other queues", inBand, total)
+	}
+
+	// Игрок, который ждет в другой очереди, не вычитается из этой
+	inBand, total, err = repo.GetQueueStatus(ctx, 4, "ranked", gunfight.Band{MinRating: 900, MaxRating: 1600})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inBand != 2 || total != 3 {
+		t.Fatalf("in band %d, total %d; want 2 and 3", inBand, total)
+	}
+}
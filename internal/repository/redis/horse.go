@@ -0,0 +1,63 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"time"
+	"wildwest/internal/model/horse"
+)
+
+type HorseRedisRepository struct {
+	BaseRedis
+}
+
+func NewHorseRedis(redis *redis.Client) *HorseRedisRepository {
+	return &HorseRedisRepository{
+		BaseRedis: BaseRedis{redis: redis},
+	}
+}
+
+// finishRaceScript атомарно забирает сессию забега, если ее ID совпадает: закончить забег можно только один раз,
+// а устаревший ID не сбрасывает новый забег. Возвращает сессию или nil
+var finishRaceScript = redis.NewScript(`
+local payload = redis.call('GET', KEYS[1])
+if not payload or cjson.decode(payload)['id'] ~= ARGV[1] then
+	return false
+end
+redis.call('DEL', KEYS[1])
+return payload
+`)
+
+// raceKey — сессия забега игрока: у игрока один забег, новый старт заменяет прежний
+func raceKey(userID int) string {
+	return fmt.Sprintf("horse_race:%d", userID)
+}
+
+// StartRace сохраняет сессию забега на ttl
+func (r *HorseRedisRepository) StartRace(ctx context.Context, race horse.Race, ttl time.Duration) error {
+	payload, err := json.Marshal(race)
+	if err != nil {
+		return err
+	}
+	return r.Set(ctx, raceKey(race.UserID), payload, ttl)
+}
+
+// FinishRace забирает сессию забега raceID. Возвращает nil, если сессии нет: она истекла, уже закончена
+// или заменена новым забегом
+func (r *HorseRedisRepository) FinishRace(ctx context.Context, userID int, raceID string) (*horse.Race, error) {
+	payload, err := finishRaceScript.Run(ctx, r.redis, []string{raceKey(userID)}, raceID).Text()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var race horse.Race
+	if err := json.Unmarshal([]byte(payload), &race); err != nil {
+		return nil, err
+	}
+	return &race, nil
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 	"wildwest/internal/model/gunfight"
 	"wildwest/internal/model/horse"
 	"wildwest/internal/model/money"
@@ -10,18 +11,94 @@ import (
 
 type GunfightPostgresRepository interface {
 	Create(ctx context.Context, game *gunfight.Game) (int, error)
+	Get(ctx context.Context, gunfightID int) (*gunfight.Game, error)
+	GetRematch(ctx context.Context, gunfightID int) (*gunfight.Game, error)
+	GetPlayers(ctx context.Context, gunfightID int) ([]gunfight.Player, error)
+	SetClientSeeds(ctx context.Context, gunfightID int, seeds map[int]string) error
+	UpdateHealth(ctx context.Context, gunfightID int, userID int, health int) error
+	Finish(ctx context.Context, gunfightID int, result *gunfight.Result) error
+	HoldStake(ctx context.Context, userID int, gold int) error
+	ReleaseStake(ctx context.Context, userID int) error
+	GetRating(ctx context.Context, userID int) (*gunfight.Rating, error)
+	GetHistory(ctx context.Context, userID int, cursor int, limit int) ([]gunfight.Game, error)
+	GetStats(ctx context.Context, userID int) (*gunfight.Stats, error)
+	GetStale(ctx context.Context, startedBefore time.Time, limit int) ([]gunfight.Game, error)
+	AppendEvents(ctx context.Context, events []gunfight.Event) error
+	GetEvents(ctx context.Context, gunfightID int) ([]gunfight.Event, error)
+	PlaceBet(ctx context.Context, bet *gunfight.Bet, dailyLimit int) error
+	CloseBets(ctx context.Context, gunfightID int) error
+	GetBets(ctx context.Context, gunfightID int) ([]gunfight.Bet, error)
+	CreateBounty(ctx context.Context, bounty *gunfight.Bounty) error
+	GetBounties(ctx context.Context, limit int) ([]gunfight.Bounty, error)
+	ExpireBounties(ctx context.Context, limit int) (int, error)
+	CreateTournament(ctx context.Context, tournament *gunfight.Tournament) (bool, error)
+	GetTournament(ctx context.Context, tournamentID int) (*gunfight.Tournament, error)
+	GetTournaments(ctx context.Context, statuses []string, limit int) ([]gunfight.Tournament, error)
+	GetTournamentPlayers(ctx context.Context, tournamentID int) ([]gunfight.TournamentPlayer, error)
+	GetTournamentMatches(ctx context.Context, tournamentID int) ([]gunfight.TournamentMatch, error)
+	RegisterTournament(ctx context.Context, tournamentID, userID int) error
+	UnregisterTournament(ctx context.Context, tournamentID, userID int) error
+	CancelTournament(ctx context.Context, tournamentID int) error
+	StartTournamentRound(ctx context.Context, tournamentID, round int, players []gunfight.TournamentPlayer, matches []gunfight.TournamentMatch) (bool, error)
+	DecideTournamentMatch(ctx context.Context, matchID int, winnerID *int, walkover bool) error
+	FinishTournament(ctx context.Context, tournamentID int, winnerID *int, prizes map[int]int) error
 }
 
 type GunfightRedisRepository interface {
-	AddPlayerToQueue(ctx context.Context, userID int, gold int) error
-	FindOpponent(ctx context.Context, gold int) (int, error)
-	RemovePlayerFromQueue(ctx context.Context, userID int) error
+	MatchOrEnqueue(ctx context.Context, userID int, queue string, rating int, band gunfight.Band) (int, error)
+	MatchWaiting(ctx context.Context, userID int, queue string, rating int, band gunfight.Band) (int, error)
+	RemovePlayerFromQueue(ctx context.Context, userID int) (bool, error)
+	MatchTeamOrEnqueue(ctx context.Context, partyID string, queue string, rating int, band gunfight.Band) (string, error)
+	MatchTeamWaiting(ctx context.Context, partyID string, queue string, rating int, band gunfight.Band) (string, error)
+	RemovePartyFromQueue(ctx context.Context, partyID string) (bool, error)
+	GetQueueStatus(ctx context.Context, userID int, queue string, band gunfight.Band) (int, int, error)
+	RecordMatchWait(ctx context.Context, queue string, wait time.Duration) error
+	GetMatchWaits(ctx context.Context, queue string) ([]time.Duration, error)
+	IncrementQueueCounter(ctx context.Context, queue string, counter string) error
+	GetQueueCounters(ctx context.Context, queue string) (int, map[string]int, error)
+	PublishMatch(ctx context.Context, userID int, match gunfight.Match) error
+	SubscribeMatch(ctx context.Context, userID int) (<-chan gunfight.Match, error)
+	SaveDuelState(ctx context.Context, state gunfight.DuelState, ttl time.Duration) error
+	GetDuelState(ctx context.Context, gunfightID int) (*gunfight.DuelState, error)
+	AppendDuelEvent(ctx context.Context, gunfightID int, message []byte, ttl time.Duration) error
+	GetDuelEvents(ctx context.Context, gunfightID int) ([]gunfight.Message, error)
+	AcquireDuelLease(ctx context.Context, gunfightID int, owner string, ttl time.Duration) (bool, error)
+	ReleaseDuelLease(ctx context.Context, gunfightID int, owner string) error
+	HasDuelLease(ctx context.Context, gunfightID int) (bool, error)
+	AddPenalty(ctx context.Context, userID int, window time.Duration) (int, error)
+	SetCooldown(ctx context.Context, userID int, cooldown time.Duration) error
+	GetCooldown(ctx context.Context, userID int) (time.Duration, error)
+	CreateChallenge(ctx context.Context, challenge gunfight.Challenge, ttl time.Duration) error
+	ClaimChallenge(ctx context.Context, targetID int, challengeID string) (*gunfight.Challenge, error)
+	GetPendingChallenges(ctx context.Context, targetID int) ([]gunfight.Challenge, error)
+	SubscribeChallenges(ctx context.Context, targetID int) (<-chan gunfight.Challenge, error)
+	PublishChallengeReply(ctx context.Context, reply gunfight.ChallengeReply) error
+	SubscribeChallengeReply(ctx context.Context, challengeID string) (<-chan gunfight.ChallengeReply, error)
+	VoteRematch(ctx context.Context, gunfightID, userID int, vote string, expireAt time.Time) (string, bool, error)
+	GetRematchVotes(ctx context.Context, gunfightID int) (map[int]string, string, error)
+	PublishRematch(ctx context.Context, reply gunfight.RematchReply) error
+	SubscribeRematch(ctx context.Context, gunfightID int) (<-chan gunfight.RematchReply, error)
+	CreateParty(ctx context.Context, party gunfight.Party, ttl time.Duration) (bool, error)
+	GetParty(ctx context.Context, partyID string) (*gunfight.Party, error)
+	GetPartyOf(ctx context.Context, userID int) (*gunfight.Party, error)
+	AddPartyInvite(ctx context.Context, userID int, partyID string, expiresAt time.Time) error
+	GetPartyInvites(ctx context.Context, userID int) (map[string]time.Time, error)
+	JoinParty(ctx context.Context, userID int, partyID string, size int, ttl time.Duration) (string, error)
+	LeaveParty(ctx context.Context, userID int) (string, error)
+	SetPartySearching(ctx context.Context, partyID string, ttl time.Duration) error
+	ClearPartySearching(ctx context.Context, partyID string) error
 }
 
 type HorsePostgresRepository interface {
 	GetHorse(ctx context.Context, userID int) (*horse.Horse, error)
 	GetMoney(ctx context.Context, userID int) (*money.Money, error)
 	Update(ctx context.Context, userID int, horse *horse.Horse, money *money.Money) error
+	AddRaceResult(ctx context.Context, userID int, distance int, silver int) (bool, error)
+}
+
+type HorseRedisRepository interface {
+	StartRace(ctx context.Context, race horse.Race, ttl time.Duration) error
+	FinishRace(ctx context.Context, userID int, raceID string) (*horse.Race, error)
 }
 
 type MoneyPostgresRepository interface {
@@ -30,6 +107,7 @@ type MoneyPostgresRepository interface {
 
 type UserPostgresRepository interface {
 	Get(ctx context.Context, userID int) (*user.User, error)
+	GetByLink(ctx context.Context, link string) (*user.User, error)
 	Create(ctx context.Context, user *user.User, horse *horse.Horse, money *money.Money) error
 	Update(ctx context.Context, userID int, userUpdate *user.UpdateUser) (int, error)
 }
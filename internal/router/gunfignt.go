@@ -13,4 +13,29 @@ func NewGunfightRouter(router *mux.Router, gunfightHandler handler.GunfightHandl
 	gunfightRouter.Use(middleware.AuthMiddleware(cfg))
 
 	gunfightRouter.HandleFunc("/find", gunfightHandler.FindGunfight).Methods("GET")
+	gunfightRouter.HandleFunc("/{id:[0-9]+}/play", gunfightHandler.PlayGunfight).Methods("GET")
+	gunfightRouter.HandleFunc("/{id:[0-9]+}/watch", gunfightHandler.WatchGunfight).Methods("GET")
+	gunfightRouter.HandleFunc("/{id:[0-9]+}/replay", gunfightHandler.GetReplay).Methods("GET")
+	gunfightRouter.HandleFunc("/{id:[0-9]+}/fairness", gunfightHandler.GetFairness).Methods("GET")
+	gunfightRouter.HandleFunc("/{id:[0-9]+}/bets", gunfightHandler.GetBetPool).Methods("GET")
+	gunfightRouter.HandleFunc("/{id:[0-9]+}/bets", gunfightHandler.PlaceBet).Methods("POST")
+	gunfightRouter.HandleFunc("/{id:[0-9]+}/rematch", gunfightHandler.RematchGunfight).Methods("GET")
+	gunfightRouter.HandleFunc("/challenge", gunfightHandler.ChallengeGunfight).Methods("GET")
+	gunfightRouter.HandleFunc("/challenges", gunfightHandler.ListenChallenges).Methods("GET")
+	gunfightRouter.HandleFunc("/party", gunfightHandler.GetParty).Methods("GET")
+	gunfightRouter.HandleFunc("/party/invite", gunfightHandler.InviteToParty).Methods("POST")
+	gunfightRouter.HandleFunc("/party/accept", gunfightHandler.AcceptPartyInvite).Methods("POST")
+	gunfightRouter.HandleFunc("/party/leave", gunfightHandler.LeaveParty).Methods("POST")
+	gunfightRouter.HandleFunc("/party/find", gunfightHandler.FindTeamGunfight).Methods("GET")
+	gunfightRouter.HandleFunc("/queue", gunfightHandler.GetQueueStatus).Methods("GET")
+	gunfightRouter.HandleFunc("/queues", gunfightHandler.GetQueueMetrics).Methods("GET")
+	gunfightRouter.HandleFunc("/rating", gunfightHandler.GetRating).Methods("GET")
+	gunfightRouter.HandleFunc("/history", gunfightHandler.GetHistory).Methods("GET")
+	gunfightRouter.HandleFunc("/stats", gunfightHandler.GetStats).Methods("GET")
+	gunfightRouter.HandleFunc("/bounties", gunfightHandler.GetWanted).Methods("GET")
+	gunfightRouter.HandleFunc("/bounties", gunfightHandler.PostBounty).Methods("POST")
+	gunfightRouter.HandleFunc("/tournaments", gunfightHandler.GetTournaments).Methods("GET")
+	gunfightRouter.HandleFunc("/tournaments/{id:[0-9]+}", gunfightHandler.GetBracket).Methods("GET")
+	gunfightRouter.HandleFunc("/tournaments/{id:[0-9]+}/register", gunfightHandler.RegisterTournament).Methods("POST")
+	gunfightRouter.HandleFunc("/tournaments/{id:[0-9]+}/unregister", gunfightHandler.UnregisterTournament).Methods("POST")
 }
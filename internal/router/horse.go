@@ -14,5 +14,6 @@ func NewHorseRouter(router *mux.Router, horseHandler handler.HorseHandler, cfg *
 
 	horseRouter.HandleFunc("", horseHandler.GetHorse).Methods("GET")
 	horseRouter.HandleFunc("/upgrade", horseHandler.UpgradeHorse).Methods("GET")
+	horseRouter.HandleFunc("/start", horseHandler.StartRace).Methods("POST")
 	horseRouter.HandleFunc("/finish", horseHandler.GameHorse).Methods("POST")
 }
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"wildwest/internal/model/gunfight"
+)
+
+// PlaceBet принимает ставку зрителя в серебре на сторону дуэли: side — игрок или капитан команды.
+// Ставки принимаются до первого раунда, участники игры на нее не ставят, одна ставка на игру
+func (s *gunfightService) PlaceBet(ctx context.Context, userID, gunfightID, side, silver int) (*gunfight.BetItem, error) {
+	limits := s.cfg.Gunfight.Bets
+	if silver < limits.Min || silver > limits.Max {
+		return nil, fmt.Errorf("bet must be from %d to %d silver", limits.Min, limits.Max)
+	}
+
+	game, err := s.gunfightRepo.Get(ctx, gunfightID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting gunfight: %w", err)
+	}
+	if game.EndDate != nil || game.BetsClosed {
+		return nil, fmt.Errorf("betting on gunfight %d is closed", gunfightID)
+	}
+	if side != game.User1ID && side != game.User2ID {
+		return nil, fmt.Errorf("user %d is not a side of gunfight %d", side, gunfightID)
+	}
+
+	players, err := s.gunfightRepo.GetPlayers(ctx, gunfightID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting gunfight players: %w", err)
+	}
+	if slices.ContainsFunc(players, func(player gunfight.Player) bool { return player.UserID == userID }) {
+		return nil, fmt.Errorf("players can not bet on their own gunfight")
+	}
+
+	bet := &gunfight.Bet{GunfightID: gunfightID, UserID: userID, Side: side, Silver: silver, Status: gunfight.BetOpen}
+	if err := s.gunfightRepo.PlaceBet(ctx, bet, limits.DailyLimit); err != nil {
+		return nil, fmt.Errorf("error placing bet: %w", err)
+	}
+
+	item := betItem(*bet)
+	return &item, nil
+}
+
+// GetBetPool возвращает банк ставок игры по сторонам и ставку игрока
+func (s *gunfightService) GetBetPool(ctx context.Context, userID, gunfightID int) (*gunfight.BetPoolResponse, error) {
+	game, err := s.gunfightRepo.Get(ctx, gunfightID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting gunfight: %w", err)
+	}
+
+	bets, err := s.gunfightRepo.GetBets(ctx, gunfightID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &gunfight.BetPoolResponse{
+		GunfightID: gunfightID,
+		Open:       game.EndDate == nil && !game.BetsClosed,
+		HouseFee:   s.cfg.Gunfight.Bets.HouseFee,
+		Sides:      []gunfight.BetSide{{Side: game.User1ID}, {Side: game.User2ID}},
+	}
+	for _, bet := range bets {
+		response.Total += bet.Silver
+		for i := range response.Sides {
+			if response.Sides[i].Side == bet.Side {
+				response.Sides[i].Silver += bet.Silver
+				response.Sides[i].Bets++
+			}
+		}
+		if bet.UserID == userID {
+			item := betItem(bet)
+			response.Bet = &item
+		}
+	}
+	return response, nil
+}
+
+func betItem(bet gunfight.Bet) gunfight.BetItem {
+	return gunfight.BetItem{
+		ID:         bet.ID,
+		GunfightID: bet.GunfightID,
+		Side:       bet.Side,
+		Silver:     bet.Silver,
+		Status:     bet.Status,
+		Payout:     bet.Payout,
+		CreatedAt:  bet.CreatedAt,
+	}
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	customErrors "wildwest/internal/errors"
+	"wildwest/internal/model/gunfight"
+	"wildwest/internal/repository"
+	"wildwest/pkg/contextutils"
+	"wildwest/pkg/settings"
+)
+
+// fakeGunfightRepo — репозиторий игр в памяти: методы, не нужные тесту, не реализованы
+type fakeGunfightRepo struct {
+	repository.GunfightPostgresRepository
+	game       *gunfight.Game
+	players    []gunfight.Player
+	placeErr   error
+	placed     *gunfight.Bet
+	dailyLimit int
+	decided    []decidedMatch
+}
+
+type decidedMatch struct {
+	matchID  int
+	winnerID *int
+	walkover bool
+}
+
+func (r *fakeGunfightRepo) Get(ctx context.Context, gunfightID int) (*gunfight.Game, error) {
+	game := *r.game
+	return &game, nil
+}
+
+func (r *fakeGunfightRepo) GetPlayers(ctx context.Context, gunfightID int) ([]gunfight.Player, error) {
+	return r.players, nil
+}
+
+func (r *fakeGunfightRepo) PlaceBet(ctx context.Context, bet *gunfight.Bet, dailyLimit int) error {
+	if r.placeErr != nil {
+		return r.placeErr
+	}
+	r.placed, r.dailyLimit = bet, dailyLimit
+	return nil
+}
+
+func (r *fakeGunfightRepo) DecideTournamentMatch(ctx context.Context, matchID int, winnerID *int, walkover bool) error {
+	r.decided = append(r.decided, decidedMatch{matchID: matchID, winnerID: winnerID, walkover: walkover})
+	return nil
+}
+
+func newTestBetService(repo *fakeGunfightRepo) *gunfightService {
+	cfg := &settings.Config{}
+	cfg.Gunfight.Bets.Min = 10
+	cfg.Gunfight.Bets.Max = 1000
+	cfg.Gunfight.Bets.DailyLimit = 5000
+	return &gunfightService{gunfightRepo: repo, cfg: cfg}
+}
+
+func TestPlaceBet(t *testing.T) {
+	ended := time.Now()
+	limitErr := customErrors.LimitExceededError(contextutils.ExtractContextData(context.Background()), "daily bets")
+
+	tests := []struct {
+		name     string
+		userID   int
+		side     int
+		silver   int
+		game     gunfight.Game
+		placeErr error
+		wantErr  string
+	}{
+		{name: "accepted", userID: 10, side: 1, silver: 100},
+		{name: "min and max are inclusive", userID: 10, side: 2, silver: 1000},
+		{name: "below the minimum", userID: 10, side: 1, silver: 9, wantErr: "bet must be from 10 to 1000 silver"},
+		{name: "above the maximum", userID: 10, side: 1, silver: 1001, wantErr: "bet must be from 10 to 1000 silver"},
+		{name: "own gunfight", userID: 1, side: 2, silver: 100, wantErr: "players can not bet on their own gunfight"},
+		{name: "own team gunfight", userID: 3, side: 2, silver: 100, wantErr: "players can not bet on their own gunfight"},
+		{name: "not a side", userID: 10, side: 3, silver: 100, wantErr: "is not a side"},
+		{name: "bets closed", userID: 10, side: 1, silver: 100, game: gunfight.Game{BetsClosed: true}, wantErr: "is closed"},
+		{name: "gunfight ended", userID: 10, side: 1, silver: 100, game: gunfight.Game{EndDate: &ended}, wantErr: "is closed"},
+		{name: "daily limit", userID: 10, side: 1, silver: 100, placeErr: limitErr, wantErr: "limit exceeded"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			game := test.game
+			game.ID, game.User1ID, game.User2ID = 7, 1, 2
+			repo := &fakeGunfightRepo{
+				game:     &game,
+				players:  []gunfight.Player{{UserID: 1}, {UserID: 2}, {UserID: 3}},
+				placeErr: test.placeErr,
+			}
+
+			item, err := newTestBetService(repo).PlaceBet(context.Background(), test.userID, 7, test.side, test.silver)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("error = %v, want %q", err, test.wantErr)
+				}
+				if test.placeErr != nil && !errors.Is(err, test.placeErr) {
+					t.Fatalf("error = %v, want the repository error wrapped", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if repo.placed == nil || repo.placed.UserID != test.userID || repo.placed.Side != test.side || repo.placed.Silver != test.silver {
+				t.Fatalf("placed bet = %+v", repo.placed)
+			}
+			if repo.dailyLimit != 5000 {
+				t.Fatalf("daily limit = %d, want the configured 5000", repo.dailyLimit)
+			}
+			if item.Silver != test.silver || item.Status != gunfight.BetOpen {
+				t.Fatalf("item = %+v", item)
+			}
+		})
+	}
+}
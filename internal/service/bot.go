@@ -0,0 +1,80 @@
+package service
+
+import (
+	"math/rand/v2"
+	"time"
+	"wildwest/internal/model/gunfight"
+)
+
+// botProfile — манера игры бота: время реакции после сигнала и вероятность фальстарта
+type botProfile struct {
+	name        string
+	userID      int
+	minReaction time.Duration
+	maxReaction time.Duration
+	foulChance  float64
+}
+
+// botProfiles упорядочены от слабого к сильному, userID — служебные пользователи из миграции
+var botProfiles = []botProfile{
+	{name: "greenhorn", userID: -1, minReaction: 450 * time.Millisecond, maxReaction: 800 * time.Millisecond, foulChance: 0.10},
+	{name: "deputy", userID: -2, minReaction: 300 * time.Millisecond, maxReaction: 550 * time.Millisecond, foulChance: 0.05},
+	{name: "outlaw", userID: -3, minReaction: 200 * time.Millisecond, maxReaction: 380 * time.Millisecond, foulChance: 0.02},
+}
+
+// botRatingSteps — рейтинг, начиная с которого игроку достается следующий по силе бот
+var botRatingSteps = []int{1100, 1300}
+
+// pickBot подбирает бота под игрока: чем дальше очередь tier в списке из tiers очередей или выше рейтинг,
+// тем сильнее бот
+func pickBot(tier, tiers, rating int) botProfile {
+	level := 0
+	if tier > 0 {
+		level = tier * len(botProfiles) / tiers
+	}
+	for i, step := range botRatingSteps {
+		if rating >= step {
+			level = max(level, i+1)
+		}
+	}
+	return botProfiles[min(level, len(botProfiles)-1)]
+}
+
+func botByName(name string) (botProfile, bool) {
+	for _, profile := range botProfiles {
+		if profile.name == name {
+			return profile, true
+		}
+	}
+	return botProfile{}, false
+}
+
+func (p botProfile) reaction() time.Duration {
+	return p.minReaction + rand.N(p.maxReaction-p.minReaction)
+}
+
+// runBot садится за дуэль вместо соперника и играет до ее окончания
+func runBot(d *duel, profile botProfile) {
+	events, _, err := d.join(profile.userID, "")
+	if err != nil {
+		return
+	}
+	seat := &DuelSeat{Events: events, duel: d, userID: profile.userID}
+
+	for event := range events {
+		var delay time.Duration
+		switch {
+		case event.Type == gunfight.DuelEventRound && rand.Float64() < profile.foulChance:
+			delay = rand.N(duelDrawMinDelay)
+		case event.Type == gunfight.DuelEventDraw:
+			delay = profile.reaction()
+		default:
+			continue
+		}
+
+		go func(round int) {
+			<-d.clock.After(delay)
+			seat.Send(gunfight.DuelCommand{Round: round}, 0)
+		}(event.Round)
+	}
+}
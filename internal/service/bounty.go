@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"wildwest/internal/model/gunfight"
+)
+
+// wantedBoardLimit — сколько наград показывает доска «Wanted»
+const wantedBoardLimit = 50
+
+// PostBounty объявляет награду за игрока, золото списывается с заказчика сразу. Награду забирает тот,
+// кто победит цель в перестрелке, а если за BountyTTL никто не победил, золото возвращается заказчику
+func (s *gunfightService) PostBounty(ctx context.Context, userID, targetID, gold int) (*gunfight.BountyItem, error) {
+	if targetID == userID {
+		return nil, fmt.Errorf("can not post a bounty on yourself")
+	}
+	if gold < s.cfg.Gunfight.BountyMin {
+		return nil, fmt.Errorf("bounty must be at least %d gold", s.cfg.Gunfight.BountyMin)
+	}
+	if _, err := s.userRepo.Get(ctx, targetID); err != nil {
+		return nil, fmt.Errorf("error getting user: %w", err)
+	}
+
+	bounty := &gunfight.Bounty{
+		PosterID:  userID,
+		TargetID:  targetID,
+		Gold:      gold,
+		Status:    gunfight.BountyOpen,
+		CreatedAt: time.Now(),
+		ExpiresAt: time.Now().Add(s.cfg.Gunfight.BountyTTL),
+	}
+	if err := s.gunfightRepo.CreateBounty(ctx, bounty); err != nil {
+		return nil, fmt.Errorf("error posting bounty: %w", err)
+	}
+
+	item := bountyItem(*bounty)
+	return &item, nil
+}
+
+// GetWanted возвращает доску «Wanted»: открытые награды от крупных к мелким
+func (s *gunfightService) GetWanted(ctx context.Context) ([]gunfight.BountyItem, error) {
+	bounties, err := s.gunfightRepo.GetBounties(ctx, wantedBoardLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]gunfight.BountyItem, 0, len(bounties))
+	for _, bounty := range bounties {
+		items = append(items, bountyItem(bounty))
+	}
+	return items, nil
+}
+
+// ExpireBounties возвращает заказчикам золото истекших наград. Возвращает число закрытых наград
+func (s *gunfightService) ExpireBounties(ctx context.Context) (int, error) {
+	return s.gunfightRepo.ExpireBounties(ctx, sweepBatchSize)
+}
+
+func bountyItem(bounty gunfight.Bounty) gunfight.BountyItem {
+	return gunfight.BountyItem{
+		ID:        bounty.ID,
+		PosterID:  bounty.PosterID,
+		TargetID:  bounty.TargetID,
+		Gold:      bounty.Gold,
+		CreatedAt: bounty.CreatedAt,
+		ExpiresAt: bounty.ExpiresAt,
+	}
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"time"
+	"wildwest/internal/model/gunfight"
+)
+
+// challengeReplyWait — сколько бросивший вызов ждет ответа, если вызванный игрок успел ответить в последний момент
+const challengeReplyWait = 5 * time.Second
+
+// Challenge бросает вызов игроку и ждет ответа, но не дольше времени жизни вызова.
+// Дуэль по вызову не рейтинговая и без ставки, очередь поиска не используется
+func (s *gunfightService) Challenge(ctx context.Context, userID int, target gunfight.ChallengeTarget, onSent func(gunfight.Challenge)) (gunfight.ChallengeReply, error) {
+	var reply gunfight.ChallengeReply
+
+	targetID, err := s.challengeTarget(ctx, target)
+	if err != nil {
+		return reply, err
+	}
+	if targetID == userID {
+		return reply, fmt.Errorf("can not challenge yourself")
+	}
+	if err := s.checkChallengeCooldowns(ctx, userID, targetID); err != nil {
+		return reply, err
+	}
+
+	ttl := s.cfg.Gunfight.ChallengeTTL
+	challenge := gunfight.Challenge{
+		ID:           uuid.New().String(),
+		ChallengerID: userID,
+		TargetID:     targetID,
+		ExpiresAt:    time.Now().Add(ttl),
+	}
+
+	// Подписываемся до создания вызова, чтобы не пропустить мгновенный ответ
+	subCtx, unsubscribe := context.WithCancel(ctx)
+	defer unsubscribe()
+
+	replies, err := s.gunfightRedis.SubscribeChallengeReply(subCtx, challenge.ID)
+	if err != nil {
+		return reply, fmt.Errorf("error subscribing to challenge reply: %w", err)
+	}
+
+	if err := s.gunfightRedis.CreateChallenge(ctx, challenge, ttl); err != nil {
+		return reply, fmt.Errorf("error creating challenge: %w", err)
+	}
+	onSent(challenge)
+
+	timer := time.NewTimer(ttl)
+	defer timer.Stop()
+
+	expired := false
+	for {
+		select {
+		case reply, ok := <-replies:
+			if !ok {
+				return reply, fmt.Errorf("challenge replies closed")
+			}
+			return reply, nil
+		case <-timer.C:
+			if expired {
+				return gunfight.ChallengeReply{ChallengeID: challenge.ID, Status: gunfight.ChallengeExpired}, nil
+			}
+
+			claimed, err := s.gunfightRedis.ClaimChallenge(ctx, targetID, challenge.ID)
+			if err != nil {
+				return reply, fmt.Errorf("error expiring challenge: %w", err)
+			}
+			if claimed != nil {
+				return gunfight.ChallengeReply{ChallengeID: challenge.ID, Status: gunfight.ChallengeExpired}, nil
+			}
+
+			// Вызов уже забрал соперник, ответ вот-вот придет
+			expired = true
+			timer.Reset(challengeReplyWait)
+		case <-ctx.Done():
+			// Отзываем вызов, если на него еще не ответили
+			s.gunfightRedis.ClaimChallenge(context.WithoutCancel(ctx), targetID, challenge.ID)
+			return reply, ctx.Err()
+		}
+	}
+}
+
+// ListenChallenges отдает игроку еще не истекшие вызовы, а затем новые, пока ctx не отменен
+func (s *gunfightService) ListenChallenges(ctx context.Context, userID int, onChallenge func(gunfight.Challenge)) error {
+	challenges, err := s.gunfightRedis.SubscribeChallenges(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("error subscribing to challenges: %w", err)
+	}
+
+	pending, err := s.gunfightRedis.GetPendingChallenges(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("error getting pending challenges: %w", err)
+	}
+	for _, challenge := range pending {
+		onChallenge(challenge)
+	}
+
+	for {
+		select {
+		case challenge, ok := <-challenges:
+			if !ok {
+				return ctx.Err()
+			}
+			onChallenge(challenge)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// RespondChallenge принимает или отклоняет вызов; принятый вызов сразу создает игру
+func (s *gunfightService) RespondChallenge(ctx context.Context, userID int, challengeID string, accept bool) (gunfight.ChallengeReply, error) {
+	reply := gunfight.ChallengeReply{ChallengeID: challengeID, Status: gunfight.ChallengeDeclined}
+
+	challenge, err := s.gunfightRedis.ClaimChallenge(ctx, userID, challengeID)
+	if err != nil {
+		return reply, fmt.Errorf("error getting challenge: %w", err)
+	}
+	if challenge == nil {
+		return reply, fmt.Errorf("challenge %s not found or expired", challengeID)
+	}
+
+	// Пока вызов ждал ответа, один из игроков мог бросить другую дуэль: тогда вызов отклоняется
+	if accept {
+		if err := s.checkChallengeCooldowns(ctx, challenge.ChallengerID, challenge.TargetID); err != nil {
+			s.gunfightRedis.PublishChallengeReply(ctx, reply)
+			return reply, err
+		}
+
+		game := &gunfight.Game{User1ID: challenge.ChallengerID, User2ID: challenge.TargetID}
+		gunfightID, err := s.gunfightRepo.Create(ctx, game)
+		if err != nil {
+			return reply, fmt.Errorf("error creating gunfight: %w", err)
+		}
+		reply.Status = gunfight.ChallengeAccepted
+		reply.GunfightID = gunfightID
+	}
+
+	if err := s.gunfightRedis.PublishChallengeReply(ctx, reply); err != nil {
+		return reply, fmt.Errorf("error notifying challenger: %w", err)
+	}
+
+	return reply, nil
+}
+
+// challengeTarget находит вызываемого игрока по ссылке или ID. Боты (ID <= 0) вызов не принимают
+func (s *gunfightService) challengeTarget(ctx context.Context, target gunfight.ChallengeTarget) (int, error) {
+	if target.Link != "" {
+		targetUser, err := s.userRepo.GetByLink(ctx, target.Link)
+		if err != nil {
+			return 0, fmt.Errorf("error getting user by link: %w", err)
+		}
+		if targetUser.ID <= 0 {
+			return 0, fmt.Errorf("can not challenge a bot")
+		}
+		return targetUser.ID, nil
+	}
+
+	if target.UserID <= 0 {
+		return 0, fmt.Errorf("can not challenge a bot")
+	}
+	targetUser, err := s.userRepo.Get(ctx, target.UserID)
+	if err != nil {
+		return 0, fmt.Errorf("error getting user: %w", err)
+	}
+	return targetUser.ID, nil
+}
+
+// checkChallengeCooldowns проверяет, что никто из игроков не ждет окончания блокировки за брошенную дуэль
+func (s *gunfightService) checkChallengeCooldowns(ctx context.Context, userIDs ...int) error {
+	for _, userID := range userIDs {
+		cooldown, err := s.gunfightRedis.GetCooldown(ctx, userID)
+		if err != nil {
+			return fmt.Errorf("error getting matchmaking cooldown: %w", err)
+		}
+		if cooldown > 0 {
+			return fmt.Errorf("matchmaking is locked for player %d for %s after leaving a gunfight", userID, cooldown.Round(time.Second))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	"wildwest/internal/model/gunfight"
+	"wildwest/internal/model/user"
+	"wildwest/internal/repository"
+	"wildwest/pkg/settings"
+)
+
+type fakeUserRepo struct {
+	repository.UserPostgresRepository
+}
+
+func (r *fakeUserRepo) Get(ctx context.Context, userID int) (*user.User, error) {
+	return &user.User{ID: userID}, nil
+}
+
+func (r *fakeUserRepo) GetByLink(ctx context.Context, link string) (*user.User, error) {
+	return &user.User{ID: -1}, nil
+}
+
+// fakeChallengeRedis хранит блокировки поиска и ответы на вызовы
+type fakeChallengeRedis struct {
+	repository.GunfightRedisRepository
+	cooldowns map[int]time.Duration
+	challenge *gunfight.Challenge
+	replies   []gunfight.ChallengeReply
+}
+
+func (r *fakeChallengeRedis) GetCooldown(ctx context.Context, userID int) (time.Duration, error) {
+	return r.cooldowns[userID], nil
+}
+
+func (r *fakeChallengeRedis) ClaimChallenge(ctx context.Context, targetID int, challengeID string) (*gunfight.Challenge, error) {
+	challenge := r.challenge
+	r.challenge = nil
+	return challenge, nil
+}
+
+func (r *fakeChallengeRedis) PublishChallengeReply(ctx context.Context, reply gunfight.ChallengeReply) error {
+	r.replies = append(r.replies, reply)
+	return nil
+}
+
+func newTestChallengeService(redis *fakeChallengeRedis) *gunfightService {
+	cfg := &settings.Config{}
+	cfg.Gunfight.ChallengeTTL = time.Minute
+	return &gunfightService{gunfightRepo: &fakeGunfightRepo{}, gunfightRedis: redis, userRepo: &fakeUserRepo{}, cfg: cfg}
+}
+
+func TestChallengeRejected(t *testing.T) {
+	tests := []struct {
+		name      string
+		target    gunfight.ChallengeTarget
+		cooldowns map[int]time.Duration
+		wantErr   string
+	}{
+		{name: "bot by ID", target: gunfight.ChallengeTarget{UserID: -2}, wantErr: "can not challenge a bot"},
+		{name: "bot by link", target: gunfight.ChallengeTarget{Link: "bot"}, wantErr: "can not challenge a bot"},
+		{name: "challenger on cooldown", target: gunfight.ChallengeTarget{UserID: 2}, cooldowns: map[int]time.Duration{1: time.Minute}, wantErr: "locked for player 1"},
+		{name: "target on cooldown", target: gunfight.ChallengeTarget{UserID: 2}, cooldowns: map[int]time.Duration{2: time.Minute}, wantErr: "locked for player 2"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newTestChallengeService(&fakeChallengeRedis{cooldowns: test.cooldowns})
+			_, err := s.Challenge(context.Background(), 1, test.target, func(gunfight.Challenge) {
+				t.Fatal("challenge sent")
+			})
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("error = %v, want %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestRespondChallengeOnCooldown(t *testing.T) {
+	redis := &fakeChallengeRedis{
+		cooldowns: map[int]time.Duration{1: time.Minute},
+		challenge: &gunfight.Challenge{ID: "challenge", ChallengerID: 1, TargetID: 2},
+	}
+	s := newTestChallengeService(redis)
+
+	// Игра не создается: Create фейкового репозитория не реализован и упал бы
+	reply, err := s.RespondChallenge(context.Background(), 2, "challenge", true)
+	if err == nil || !strings.Contains(err.Error(), "locked for player 1") {
+		t.Fatalf("error = %v, want the challenger cooldown", err)
+	}
+	if reply.Status != gunfight.ChallengeDeclined || reply.GunfightID != 0 {
+		t.Fatalf("reply = %+v, want declined without a gunfight", reply)
+	}
+	if len(redis.replies) != 1 || redis.replies[0].Status != gunfight.ChallengeDeclined {
+		t.Fatalf("challenger got %+v, want a declined reply", redis.replies)
+	}
+}
@@ -0,0 +1,627 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+	"wildwest/internal/model/gunfight"
+	"wildwest/pkg/settings"
+)
+
+const (
+	duelHealth       = 3
+	duelMaxRounds    = 15
+	duelRoundTimeout = 10 * time.Second
+	duelJoinTimeout  = 30 * time.Second
+	duelStateTTL     = 30 * time.Minute
+	duelDrawMinDelay = 2 * time.Second
+	duelDrawMaxDelay = 5 * time.Second
+	// duelLeaseTTL — на сколько инстанс занимает дуэль, аренда продлевается каждую треть срока
+	duelLeaseTTL = 15 * time.Second
+	// maxRTTCompensation — больше этого время реакции не уменьшается, как бы медленно ни отвечало соединение.
+	// RTT меряется по понгам, которые клиент может задерживать, поэтому поправка меньше заметной доли реакции
+	maxRTTCompensation = 40 * time.Millisecond
+)
+
+// drawDelay возвращает паузу перед сигналом раунда, выведенную из сидов; это переменная, чтобы тесты могли ее зафиксировать
+var drawDelay = fairDrawDelay
+
+// Clock — источник времени дуэли. Время реакции считается только по нему,
+// поэтому в тестах его можно подменить и получить детерминированный результат
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// DuelSeat — место игрока в идущей дуэли: в Missed события, пропущенные до переподключения,
+// дальше события приходят из Events, выстрелы передаются через Send
+type DuelSeat struct {
+	Missed []gunfight.DuelEvent
+	Events <-chan gunfight.DuelEvent
+	duel   *duel
+	userID int
+}
+
+// Send передает выстрел в дуэль, rtt — измеренное время отклика соединения игрока
+func (s *DuelSeat) Send(command gunfight.DuelCommand, rtt time.Duration) {
+	if !s.duel.seated(s.userID, s.Events) {
+		return
+	}
+	move := duelMove{userID: s.userID, command: command, at: s.duel.clock.Now(), rtt: rtt}
+	select {
+	case s.duel.moves <- move:
+	default:
+	}
+}
+
+// duelMove — выстрел игрока и момент, когда его получил сервер
+type duelMove struct {
+	userID  int
+	command gunfight.DuelCommand
+	at      time.Time
+	rtt     time.Duration
+}
+
+// drawRound — итог раунда: время реакции выстреливших игроков, фальстарт или игрок, который не вернулся
+type drawRound struct {
+	reactions map[int]time.Duration
+	foul      int
+	forfeited int
+}
+
+// spectatorEvent — событие для зрителей и момент, когда его получили игроки
+type spectatorEvent struct {
+	event gunfight.DuelEvent
+	at    time.Time
+}
+
+// duelRecorder сохраняет событие и состояние дуэли до того, как событие получат игроки
+type duelRecorder func(event gunfight.DuelEvent, state gunfight.DuelState)
+
+type duel struct {
+	game      *gunfight.Game
+	members   []int
+	sides     map[int]int
+	clock     Clock
+	moves     chan duelMove
+	ready     chan struct{}
+	readyOnce sync.Once
+	started   bool
+	mu        sync.Mutex
+	seats     map[int]chan gunfight.DuelEvent
+	seq       int64
+	closed    bool
+	done      chan struct{}
+	state     gunfight.DuelState
+	resumed   bool
+	record    duelRecorder
+
+	// Сиды игроков принимаются, пока дуэль не началась, и записываются в игру перед первым раундом
+	clientSeeds map[int]string
+
+	// Журнал дуэли копится в памяти и пишется в Postgres после каждого раунда, а остаток — в одной транзакции
+	// с итогом игры. Журнал трогает только горутина дуэли
+	journal []gunfight.Event
+
+	// Отключившийся игрок проигрывает, если не вернулся за reconnectGrace; ожидание отменяется
+	// закрытием канала из graceCancels
+	forfeits       chan int
+	graceCancels   map[int]chan struct{}
+	reconnectGrace time.Duration
+
+	// Зрители получают события из отдельной горутины, чтобы не задерживать игроков
+	spectatorFeed  chan spectatorEvent
+	spectatorsMu   sync.Mutex
+	spectators     map[int]chan gunfight.DuelEvent
+	nextSpectator  int
+	spectatorLimit int
+	spectatorDelay time.Duration
+}
+
+// newDuel готовит дуэль игры. В командной дуэли здоровье общее у команды и записано на капитана
+func newDuel(game *gunfight.Game, cfg *settings.Config, clock Clock, record duelRecorder) *duel {
+	players := []int{game.User1ID, game.User2ID}
+	members, sides := teamSides(game)
+	health := duelHealth * teamSize(game)
+	clientSeeds := make(map[int]string, len(members))
+	for _, player := range game.Players {
+		clientSeeds[player.UserID] = player.ClientSeed
+	}
+	return &duel{
+		game:    game,
+		members: members,
+		sides:   sides,
+		clock:   clock,
+		moves:   make(chan duelMove, 16),
+		ready:   make(chan struct{}),
+		done:    make(chan struct{}),
+		seats:   make(map[int]chan gunfight.DuelEvent),
+		state: gunfight.DuelState{
+			GunfightID: game.ID,
+			Players:    players,
+			Health:     map[int]int{players[0]: health, players[1]: health},
+		},
+		record:         record,
+		clientSeeds:    clientSeeds,
+		forfeits:       make(chan int, len(members)),
+		graceCancels:   make(map[int]chan struct{}),
+		reconnectGrace: cfg.Gunfight.ReconnectGrace,
+		spectatorFeed:  make(chan spectatorEvent, 64),
+		spectators:     make(map[int]chan gunfight.DuelEvent),
+		spectatorLimit: cfg.Gunfight.SpectatorLimit,
+		spectatorDelay: cfg.Gunfight.SpectatorDelay,
+	}
+}
+
+// resume продолжает дуэль с сохраненного состояния, например после перезапуска сервера
+func (d *duel) resume(state gunfight.DuelState) {
+	d.state = state
+	d.seq = state.Seq
+	d.resumed = true
+}
+
+// seeds возвращает копию сидов игроков
+func (d *duel) seeds() map[int]string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	seeds := make(map[int]string, len(d.clientSeeds))
+	for userID, seed := range d.clientSeeds {
+		seeds[userID] = seed
+	}
+	return seeds
+}
+
+// players возвращает стороны дуэли: игроков или капитанов команд
+func (d *duel) players() []int {
+	return []int{d.game.User1ID, d.game.User2ID}
+}
+
+// absent возвращает игрока, который так и не сел за дуэль, пока другие игроки ждут; 0 — если таких нет
+func (d *duel) absent() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.seats) == 0 || len(d.seats) == len(d.members) {
+		return 0
+	}
+	for _, userID := range d.members {
+		if _, ok := d.seats[userID]; !ok {
+			return userID
+		}
+	}
+	return 0
+}
+
+// join сажает игрока за дуэль и возвращает номер последнего события, которое он получит не через канал.
+// Повторный вход того же игрока заменяет прежнее место: старое соединение могло еще не заметить обрыв,
+// его канал закрывается. Сид игрока учитывается, только если дуэль еще не началась
+func (d *duel) join(userID int, clientSeed string) (<-chan gunfight.DuelEvent, int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return nil, 0, fmt.Errorf("gunfight %d is over", d.game.ID)
+	}
+	if stale, ok := d.seats[userID]; ok {
+		close(stale)
+		delete(d.seats, userID)
+	}
+
+	if cancel, ok := d.graceCancels[userID]; ok {
+		close(cancel)
+		delete(d.graceCancels, userID)
+	}
+
+	if !d.started && !d.resumed {
+		d.clientSeeds[userID] = clientSeed
+	}
+
+	events := make(chan gunfight.DuelEvent, 16)
+	d.seats[userID] = events
+	if len(d.seats) == len(d.members) {
+		d.readyOnce.Do(func() {
+			d.started = true
+			close(d.ready)
+		})
+	} else if !d.started {
+		events <- gunfight.DuelEvent{Type: gunfight.DuelEventWaiting, Seq: d.seq, SeedHash: d.game.SeedHash}
+	}
+	return events, d.seq, nil
+}
+
+// seated проверяет, что место игрока не заменило более новое соединение
+func (d *duel) seated(userID int, events <-chan gunfight.DuelEvent) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	seat, ok := d.seats[userID]
+	return ok && seat == events
+}
+
+// leave освобождает место игрока, если его не заняло новое соединение того же игрока
+func (d *duel) leave(userID int, events <-chan gunfight.DuelEvent) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	seat, ok := d.seats[userID]
+	if !ok || seat != events {
+		return
+	}
+	close(seat)
+	delete(d.seats, userID)
+
+	if d.started && !d.closed {
+		cancel := make(chan struct{})
+		d.graceCancels[userID] = cancel
+		expired := d.clock.After(d.reconnectGrace)
+		go func() {
+			select {
+			case <-expired:
+				// Игрок мог вернуться в момент, когда срок уже вышел: проигрыш засчитывается, только если
+				// ожидание не отменено
+				d.mu.Lock()
+				defer d.mu.Unlock()
+				if d.graceCancels[userID] != cancel {
+					return
+				}
+				delete(d.graceCancels, userID)
+				select {
+				case d.forfeits <- userID:
+				default:
+				}
+			case <-cancel:
+			case <-d.done:
+			}
+		}()
+	}
+}
+
+// broadcast нумерует событие, сохраняет его и рассылает игрокам и зрителям. Возвращает время события
+func (d *duel) broadcast(event gunfight.DuelEvent) time.Time {
+	event, at := d.stamp(event)
+	d.journal = append(d.journal, d.journalEvent(event, at))
+	d.publish(event)
+	return at
+}
+
+// stamp присваивает событию следующий номер и время, но не занимает номер: события рассылает только
+// горутина дуэли, поэтому до publish другое событие этот номер не получит
+func (d *duel) stamp(event gunfight.DuelEvent) (gunfight.DuelEvent, time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	event.Seq = d.seq + 1
+	return event, d.clock.Now()
+}
+
+// publish сохраняет событие с номером из stamp и рассылает его. Сохранение в Redis идет без блокировки дуэли,
+// а номер занимается только после него: игрок, который входит в дуэль в это время, получит событие через канал
+func (d *duel) publish(event gunfight.DuelEvent) {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return
+	}
+	state := d.copyState()
+	d.mu.Unlock()
+
+	state.Seq = event.Seq
+	if d.record != nil {
+		d.record(event, state)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return
+	}
+	d.seq = event.Seq
+	d.state.Seq = d.seq
+	d.deliver(event)
+}
+
+// journalEvent — запись журнала о событии, которое получили игроки
+func (d *duel) journalEvent(event gunfight.DuelEvent, at time.Time) gunfight.Event {
+	payload, _ := json.Marshal(event)
+	return gunfight.Event{
+		GunfightID: d.game.ID,
+		Seq:        event.Seq,
+		Type:       event.Type,
+		Round:      event.Round,
+		Payload:    payload,
+		CreatedAt:  at,
+	}
+}
+
+// logShot записывает в журнал выстрел, который учла дуэль
+func (d *duel) logShot(move duelMove) {
+	userID := move.userID
+	d.journal = append(d.journal, gunfight.Event{
+		GunfightID: d.game.ID,
+		Type:       gunfight.EventShot,
+		UserID:     &userID,
+		Round:      move.command.Round,
+		RTT:        move.rtt.Microseconds(),
+		CreatedAt:  move.at,
+	})
+}
+
+// logArmed записывает момент, когда пошла пауза перед сигналом: от него, а не от события раунда, которое еще
+// сохраняется в Redis, проверяется длина паузы
+func (d *duel) logArmed(round int) {
+	d.journal = append(d.journal, gunfight.Event{
+		GunfightID: d.game.ID,
+		Type:       gunfight.EventArmed,
+		Round:      round,
+		CreatedAt:  d.clock.Now(),
+	})
+}
+
+func (d *duel) logForfeit(userID int) {
+	d.journal = append(d.journal, gunfight.Event{
+		GunfightID: d.game.ID,
+		Type:       gunfight.EventForfeit,
+		UserID:     &userID,
+		CreatedAt:  d.clock.Now(),
+	})
+}
+
+// notify рассылает служебное событие без своего номера: его не нужно повторять после переподключения
+func (d *duel) notify(event gunfight.DuelEvent) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return
+	}
+
+	event.Seq = d.seq
+	d.deliver(event)
+}
+
+func (d *duel) deliver(event gunfight.DuelEvent) {
+	for _, events := range d.seats {
+		select {
+		case events <- event:
+		default:
+		}
+	}
+
+	select {
+	case d.spectatorFeed <- spectatorEvent{event: event, at: d.clock.Now()}:
+	default:
+	}
+}
+
+// update запоминает здоровье игроков после сыгранного раунда
+func (d *duel) update(round int, health map[int]int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.state.Round = round
+	d.state.Health = copyHealth(health)
+}
+
+func (d *duel) copyState() gunfight.DuelState {
+	state := d.state
+	state.Health = copyHealth(d.state.Health)
+	return state
+}
+
+func (d *duel) close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.closed = true
+	close(d.done)
+	for userID, events := range d.seats {
+		close(events)
+		delete(d.seats, userID)
+	}
+	for userID, cancel := range d.graceCancels {
+		close(cancel)
+		delete(d.graceCancels, userID)
+	}
+	close(d.spectatorFeed)
+}
+
+// watch добавляет зрителя, если лимит зрителей дуэли не исчерпан
+func (d *duel) watch() (int, <-chan gunfight.DuelEvent, error) {
+	d.spectatorsMu.Lock()
+	if d.spectators == nil {
+		d.spectatorsMu.Unlock()
+		return 0, nil, fmt.Errorf("gunfight %d is over", d.game.ID)
+	}
+	if len(d.spectators) >= d.spectatorLimit {
+		d.spectatorsMu.Unlock()
+		return 0, nil, fmt.Errorf("gunfight %d has reached the spectator limit", d.game.ID)
+	}
+
+	d.nextSpectator++
+	spectatorID := d.nextSpectator
+	events := make(chan gunfight.DuelEvent, 16)
+	d.spectators[spectatorID] = events
+	count := len(d.spectators)
+	d.spectatorsMu.Unlock()
+
+	d.notify(gunfight.DuelEvent{Type: gunfight.DuelEventSpectators, Spectators: count})
+	return spectatorID, events, nil
+}
+
+func (d *duel) unwatch(spectatorID int) {
+	d.spectatorsMu.Lock()
+	events, ok := d.spectators[spectatorID]
+	if ok {
+		close(events)
+		delete(d.spectators, spectatorID)
+	}
+	count := len(d.spectators)
+	d.spectatorsMu.Unlock()
+
+	if ok {
+		d.notify(gunfight.DuelEvent{Type: gunfight.DuelEventSpectators, Spectators: count})
+	}
+}
+
+// feedSpectators раздает события зрителям с задержкой spectatorDelay; медленный зритель пропускает события
+func (d *duel) feedSpectators() {
+	defer func() {
+		d.spectatorsMu.Lock()
+		for _, events := range d.spectators {
+			close(events)
+		}
+		d.spectators = nil
+		d.spectatorsMu.Unlock()
+	}()
+
+	for item := range d.spectatorFeed {
+		// После окончания дуэли подсказывать игрокам уже нечего, остаток событий уходит зрителям сразу
+		if wait := item.at.Add(d.spectatorDelay).Sub(d.clock.Now()); wait > 0 {
+			select {
+			case <-d.clock.After(wait):
+			case <-d.done:
+			}
+		}
+
+		d.spectatorsMu.Lock()
+		for _, events := range d.spectators {
+			select {
+			case events <- item.event:
+			default:
+			}
+		}
+		d.spectatorsMu.Unlock()
+	}
+}
+
+// steady ждет сигнала delay. Выстрел до сигнала — фальстарт, раунд на нем заканчивается
+func (d *duel) steady(round int, delay time.Duration) drawRound {
+	timeout := d.clock.After(delay)
+	d.logArmed(round)
+	for {
+		select {
+		case move := <-d.moves:
+			if move.command.Round == round {
+				d.logShot(move)
+				return drawRound{foul: move.userID}
+			}
+		case userID := <-d.forfeits:
+			return drawRound{forfeited: userID}
+		case <-timeout:
+			return drawRound{}
+		}
+	}
+}
+
+// collect ждет выстрелы всех игроков после сигнала drawAt, но не дольше duelRoundTimeout.
+// Если игрок не вернулся после отключения, возвращает его как сдавшегося
+func (d *duel) collect(round int, drawAt time.Time) drawRound {
+	result := drawRound{reactions: make(map[int]time.Duration, len(d.members))}
+	timeout := d.clock.After(duelRoundTimeout)
+
+	for len(result.reactions) < len(d.members) {
+		select {
+		case move := <-d.moves:
+			if move.command.Round != round {
+				continue
+			}
+			if move.at.Before(drawAt) {
+				d.logShot(move)
+				return drawRound{foul: move.userID}
+			}
+			if _, ok := result.reactions[move.userID]; !ok {
+				d.logShot(move)
+				result.reactions[move.userID] = reaction(drawAt, move)
+			}
+		case userID := <-d.forfeits:
+			result.forfeited = userID
+			return result
+		case <-timeout:
+			return result
+		}
+	}
+	return result
+}
+
+// reaction — время от отправки сигнала до получения выстрела по часам сервера за вычетом пути сигнала до игрока
+// и выстрела обратно, то есть полного RTT, но не больше maxRTTCompensation
+func reaction(drawAt time.Time, move duelMove) time.Duration {
+	return max(move.at.Sub(drawAt)-min(move.rtt, maxRTTCompensation), 0)
+}
+
+// resolveDraw возвращает игроков, в которых попали. Фальстарт стоит нарушителю попадания,
+// иначе попадает тот, кто выстрелил быстрее; при равном времени реакции попадают оба
+func resolveDraw(players []int, round drawRound) []int {
+	if round.foul != 0 {
+		return []int{round.foul}
+	}
+
+	first, second := players[0], players[1]
+	firstReaction, firstShot := round.reactions[first]
+	secondReaction, secondShot := round.reactions[second]
+	switch {
+	case firstShot && (!secondShot || firstReaction < secondReaction):
+		return []int{second}
+	case secondShot && (!firstShot || secondReaction < firstReaction):
+		return []int{first}
+	case firstShot && secondShot:
+		return []int{first, second}
+	}
+	return nil
+}
+
+// applyHits снимает здоровье игрокам, в которых попали. Если оба игрока погибли в одном раунде,
+// дуэль продолжается до последнего патрона
+func applyHits(players []int, health map[int]int, hits []int) {
+	for _, userID := range hits {
+		health[userID]--
+	}
+	if health[players[0]] <= 0 && health[players[1]] <= 0 {
+		health[players[0]], health[players[1]] = 1, 1
+	}
+}
+
+// reactionMillis переводит время реакции в миллисекунды для события результата раунда
+func reactionMillis(reactions map[int]time.Duration) map[int]int64 {
+	if len(reactions) == 0 {
+		return nil
+	}
+	result := make(map[int]int64, len(reactions))
+	for userID, value := range reactions {
+		result[userID] = value.Milliseconds()
+	}
+	return result
+}
+
+// duelWinner возвращает игрока с большим здоровьем, nil при ничьей
+func duelWinner(players []int, health map[int]int) *int {
+	first, second := players[0], players[1]
+	switch {
+	case health[first] > health[second]:
+		return &first
+	case health[second] > health[first]:
+		return &second
+	}
+	return nil
+}
+
+func copyHealth(health map[int]int) map[int]int {
+	result := make(map[int]int, len(health))
+	for userID, value := range health {
+		result[userID] = value
+	}
+	return result
+}
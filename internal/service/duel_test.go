@@ -0,0 +1,308 @@
+package service
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+	"wildwest/internal/model/gunfight"
+	"wildwest/pkg/settings"
+)
+
+// fakeClock — часы дуэли, которые идут только по Advance
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	timers  []fakeTimer
+	waiting chan struct{}
+}
+
+type fakeTimer struct {
+	at     time.Time
+	events chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{
+		now:     time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		waiting: make(chan struct{}, 16),
+	}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	timer := fakeTimer{at: c.now.Add(d), events: make(chan time.Time, 1)}
+	c.timers = append(c.timers, timer)
+	select {
+	case c.waiting <- struct{}{}:
+	default:
+	}
+	return timer.events
+}
+
+// Advance переводит часы и срабатывает таймеры, срок которых наступил
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	pending := c.timers[:0]
+	for _, timer := range c.timers {
+		if timer.at.After(c.now) {
+			pending = append(pending, timer)
+			continue
+		}
+		timer.events <- c.now
+	}
+	c.timers = pending
+}
+
+// waitTimer ждет, пока дуэль заведет таймер
+func (c *fakeClock) waitTimer(t *testing.T) {
+	t.Helper()
+	select {
+	case <-c.waiting:
+	case <-time.After(time.Second):
+		t.Fatal("duel did not start a timer")
+	}
+}
+
+const (
+	testReconnectGrace = 30 * time.Second
+	testSpectatorDelay = 2 * time.Second
+)
+
+func newTestDuel(clock Clock) *duel {
+	game := &gunfight.Game{ID: 1, User1ID: 1, User2ID: 2}
+	cfg := &settings.Config{}
+	cfg.Gunfight.ReconnectGrace = testReconnectGrace
+	cfg.Gunfight.SpectatorLimit = 10
+	cfg.Gunfight.SpectatorDelay = testSpectatorDelay
+	return newDuel(game, cfg, clock, nil)
+}
+
+func shot(userID int, at time.Time, rtt time.Duration) duelMove {
+	return duelMove{userID: userID, command: gunfight.DuelCommand{Round: 1}, at: at, rtt: rtt}
+}
+
+// playDraw собирает выстрелы раунда после сигнала в drawAt и возвращает игроков, в которых попали
+func playDraw(d *duel, drawAt time.Time, moves ...duelMove) []int {
+	for _, move := range moves {
+		d.moves <- move
+	}
+	outcome := d.collect(1, drawAt)
+	return resolveDraw(d.players(), bySide(outcome, d.sides))
+}
+
+func TestDuelFoulBeforeSignal(t *testing.T) {
+	clock := newFakeClock()
+	d := newTestDuel(clock)
+
+	d.moves <- shot(2, clock.Now().Add(time.Second), 0)
+	outcome := d.steady(1, 3*time.Second)
+
+	if outcome.foul != 2 {
+		t.Fatalf("foul = %d, want 2", outcome.foul)
+	}
+	if hits := resolveDraw(d.players(), bySide(outcome, d.sides)); !slices.Equal(hits, []int{2}) {
+		t.Fatalf("hits = %v, want the fouling player hit", hits)
+	}
+}
+
+func TestDuelFastestShotWins(t *testing.T) {
+	clock := newFakeClock()
+	d := newTestDuel(clock)
+	drawAt := clock.Now()
+
+	hits := playDraw(d, drawAt, shot(1, drawAt.Add(300*time.Millisecond), 0), shot(2, drawAt.Add(200*time.Millisecond), 0))
+	if !slices.Equal(hits, []int{1}) {
+		t.Fatalf("hits = %v, want player 1 hit by the faster player 2", hits)
+	}
+}
+
+func TestDuelRTTAdjustment(t *testing.T) {
+	clock := newFakeClock()
+	drawAt := clock.Now()
+
+	// Выстрел пришел позже, но сигнал и выстрел шли по медленному соединению: RTT вычитается целиком
+	d := newTestDuel(clock)
+	hits := playDraw(d, drawAt, shot(1, drawAt.Add(230*time.Millisecond), 30*time.Millisecond), shot(2, drawAt.Add(210*time.Millisecond), 0))
+	if !slices.Equal(hits, []int{2}) {
+		t.Fatalf("hits = %v, want player 2 hit: 230ms - 30ms beats 210ms", hits)
+	}
+
+	// Поправка не больше maxRTTCompensation, как бы велик ни был RTT
+	d = newTestDuel(clock)
+	hits = playDraw(d, drawAt, shot(1, drawAt.Add(300*time.Millisecond), 2*time.Second), shot(2, drawAt.Add(200*time.Millisecond), 0))
+	if !slices.Equal(hits, []int{1}) {
+		t.Fatalf("hits = %v, want player 1 hit: compensation is capped at %v", hits, maxRTTCompensation)
+	}
+}
+
+func TestDuelDelayedPongsGiveNoAdvantage(t *testing.T) {
+	clock := newFakeClock()
+	drawAt := clock.Now()
+
+	// У обоих игроков соединение с RTT 80ms и одинаковая реакция, поэтому выстрелы приходят одновременно.
+	// Игрок 1 задерживает понги на 300ms, но поправка уже упирается в потолок и выигрыша не дает
+	d := newTestDuel(clock)
+	hits := playDraw(d, drawAt, shot(1, drawAt.Add(280*time.Millisecond), 380*time.Millisecond), shot(2, drawAt.Add(280*time.Millisecond), 80*time.Millisecond))
+	if !slices.Equal(hits, []int{1, 2}) {
+		t.Fatalf("hits = %v, want both players hit: delayed pongs must not speed up the reaction", hits)
+	}
+
+	honest := reaction(drawAt, shot(1, drawAt.Add(280*time.Millisecond), 80*time.Millisecond))
+	delayed := reaction(drawAt, shot(1, drawAt.Add(280*time.Millisecond), 380*time.Millisecond))
+	if delayed != honest {
+		t.Fatalf("reaction with delayed pongs = %v, honest = %v", delayed, honest)
+	}
+}
+
+func TestDuelTie(t *testing.T) {
+	clock := newFakeClock()
+	d := newTestDuel(clock)
+	drawAt := clock.Now()
+
+	hits := playDraw(d, drawAt, shot(1, drawAt.Add(230*time.Millisecond), 30*time.Millisecond), shot(2, drawAt.Add(200*time.Millisecond), 0))
+	if !slices.Equal(hits, []int{1, 2}) {
+		t.Fatalf("hits = %v, want both players hit on equal reactions", hits)
+	}
+}
+
+func TestDuelRoundTimeout(t *testing.T) {
+	clock := newFakeClock()
+	d := newTestDuel(clock)
+	drawAt := clock.Now()
+
+	result := make(chan drawRound, 1)
+	go func() { result <- d.collect(1, drawAt) }()
+	clock.waitTimer(t)
+
+	d.moves <- shot(1, drawAt.Add(400*time.Millisecond), 0)
+	for len(d.moves) > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	clock.Advance(duelRoundTimeout)
+
+	var outcome drawRound
+	select {
+	case outcome = <-result:
+	case <-time.After(time.Second):
+		t.Fatal("round did not time out")
+	}
+
+	if _, ok := outcome.reactions[2]; ok || len(outcome.reactions) != 1 {
+		t.Fatalf("reactions = %v, want only player 1", outcome.reactions)
+	}
+	if hits := resolveDraw(d.players(), bySide(outcome, d.sides)); !slices.Equal(hits, []int{2}) {
+		t.Fatalf("hits = %v, want player 2 hit for not shooting", hits)
+	}
+}
+
+// seatBoth сажает за дуэль обоих игроков, чтобы она началась, и возвращает канал событий игрока 1
+func seatBoth(t *testing.T, d *duel) <-chan gunfight.DuelEvent {
+	t.Helper()
+	events, _, err := d.join(1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := d.join(2, ""); err != nil {
+		t.Fatal(err)
+	}
+	return events
+}
+
+func TestDuelMissedReconnectGraceForfeits(t *testing.T) {
+	clock := newFakeClock()
+	d := newTestDuel(clock)
+	defer d.close()
+
+	d.leave(1, seatBoth(t, d))
+	clock.Advance(testReconnectGrace - time.Second)
+	select {
+	case userID := <-d.forfeits:
+		t.Fatalf("player %d forfeited before the grace period ended", userID)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	clock.Advance(time.Second)
+	select {
+	case userID := <-d.forfeits:
+		if userID != 1 {
+			t.Fatalf("player %d forfeited, want 1", userID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("player who did not come back did not forfeit")
+	}
+}
+
+func TestDuelReconnectWithinGrace(t *testing.T) {
+	clock := newFakeClock()
+	d := newTestDuel(clock)
+	defer d.close()
+
+	d.leave(1, seatBoth(t, d))
+	clock.Advance(testReconnectGrace / 2)
+	if _, _, err := d.join(1, ""); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(testReconnectGrace)
+
+	select {
+	case userID := <-d.forfeits:
+		t.Fatalf("player %d forfeited after reconnecting", userID)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestDuelSpectatorDelay(t *testing.T) {
+	clock := newFakeClock()
+	d := newTestDuel(clock)
+	go d.feedSpectators()
+
+	_, events, err := d.watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock.waitTimer(t)
+
+	select {
+	case event := <-events:
+		t.Fatalf("spectator got %s before the delay", event.Type)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	clock.Advance(testSpectatorDelay)
+	select {
+	case event := <-events:
+		if event.Type != gunfight.DuelEventSpectators {
+			t.Fatalf("spectator got %s, want %s", event.Type, gunfight.DuelEventSpectators)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("spectator did not get the event after the delay")
+	}
+
+	// Событие об окончании дуэли не ждет задержки, а закрытие дуэли не ждет зрителей
+	d.broadcast(gunfight.DuelEvent{Type: gunfight.DuelEventFinished})
+	clock.waitTimer(t)
+	d.close()
+	select {
+	case event := <-events:
+		if event.Type != gunfight.DuelEventFinished {
+			t.Fatalf("spectator got %s, want %s", event.Type, gunfight.DuelEventFinished)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("spectator did not get the last event when the duel closed")
+	}
+	if _, ok := <-events; ok {
+		t.Fatal("spectator channel is not closed after the duel")
+	}
+}
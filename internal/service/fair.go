@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+	"wildwest/internal/model/gunfight"
+)
+
+// drawDelayTolerance — на сколько сигнал может запоздать относительно выведенной паузы из-за планировщика.
+// Пауза меряется от момента, когда ее завели, до сигнала, поэтому запись событий в Redis в нее не входит;
+// более поздний сигнал значит, что паузу подменили
+const drawDelayTolerance = 100 * time.Millisecond
+
+// fairDrawDelay выводит паузу перед сигналом раунда из сида игры и сидов игроков: HMAC-SHA256 с ключом serverSeed
+// от сидов игроков через ":" и номера раунда, первые 8 байт по модулю диапазона пауз. Смещение от модуля
+// пренебрежимо мало: диапазон — 3·10⁹ нс против 2⁶⁴
+func fairDrawDelay(serverSeed string, clientSeeds []string, round int) time.Duration {
+	mac := hmac.New(sha256.New, []byte(serverSeed))
+	fmt.Fprintf(mac, "%s:%d", strings.Join(clientSeeds, ":"), round)
+	value := binary.BigEndian.Uint64(mac.Sum(nil))
+	return duelDrawMinDelay + time.Duration(value%uint64(duelDrawMaxDelay-duelDrawMinDelay))
+}
+
+// clientSeeds возвращает сиды игроков в порядке состава команд
+func clientSeeds(game *gunfight.Game) []string {
+	seeds := make([]string, 0, len(game.Players))
+	for _, player := range game.Players {
+		seeds = append(seeds, player.ClientSeed)
+	}
+	return seeds
+}
+
+// VerifyDraws заново выводит паузы перед сигналом из раскрытого сида игры и сидов игроков и сверяет их с журналом:
+// сигнал не может прийти раньше паузы и позже нее больше чем на drawDelayTolerance. В game.Players должен быть состав команд с сидами игроков.
+// Возвращает раунды, проверенные до первого расхождения
+func VerifyDraws(game *gunfight.Game, events []gunfight.Event) ([]gunfight.FairDraw, error) {
+	if game.ServerSeed == "" {
+		return nil, fmt.Errorf("gunfight %d was played before provably fair draws", game.ID)
+	}
+	if gunfight.SeedHash(game.ServerSeed) != game.SeedHash {
+		return nil, fmt.Errorf("gunfight %d: server seed does not match the published hash", game.ID)
+	}
+
+	seeds := clientSeeds(game)
+	var draws []gunfight.FairDraw
+	var roundAt time.Time
+	for _, event := range events {
+		switch event.Type {
+		case gunfight.DuelEventRound:
+			roundAt = event.CreatedAt
+			delay := fairDrawDelay(game.ServerSeed, seeds, event.Round)
+			draws = append(draws, gunfight.FairDraw{Round: event.Round, Delay: delay.Milliseconds()})
+		case gunfight.EventArmed:
+			// Пауза идет с момента, когда ее завели; в журналах без этой записи — от события раунда
+			if len(draws) > 0 && draws[len(draws)-1].Round == event.Round {
+				roundAt = event.CreatedAt
+			}
+		case gunfight.DuelEventDraw:
+			if len(draws) == 0 || draws[len(draws)-1].Round != event.Round {
+				return draws, fmt.Errorf("round %d: draw without a round event", event.Round)
+			}
+			draw := &draws[len(draws)-1]
+			observed := event.CreatedAt.Sub(roundAt)
+			millis := observed.Milliseconds()
+			draw.Observed = &millis
+
+			// Время событий в журнале хранится с точностью до микросекунды
+			delay := fairDrawDelay(game.ServerSeed, seeds, event.Round)
+			if observed+time.Microsecond < delay || observed > delay+drawDelayTolerance {
+				return draws, fmt.Errorf("round %d: draw came after %v, the seeds give %v", event.Round, observed, delay)
+			}
+		}
+	}
+	return draws, nil
+}
+
+// GetFairness возвращает данные для проверки случайных пауз игры. Хэш сида доступен с момента создания игры,
+// сам сид и проверка пауз — только после ее окончания
+func (s *gunfightService) GetFairness(ctx context.Context, gunfightID int) (*gunfight.FairnessResponse, error) {
+	game, err := s.gunfightRepo.Get(ctx, gunfightID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting gunfight: %w", err)
+	}
+	if game.SeedHash == "" {
+		return nil, fmt.Errorf("gunfight %d was played before provably fair draws", gunfightID)
+	}
+
+	game.Players, err = s.gunfightRepo.GetPlayers(ctx, gunfightID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting gunfight players: %w", err)
+	}
+
+	response := &gunfight.FairnessResponse{
+		GunfightID:  game.ID,
+		SeedHash:    game.SeedHash,
+		ClientSeeds: make([]gunfight.ClientSeedItem, 0, len(game.Players)),
+	}
+	for _, player := range game.Players {
+		response.ClientSeeds = append(response.ClientSeeds, gunfight.ClientSeedItem{UserID: player.UserID, Seed: player.ClientSeed})
+	}
+	if game.EndDate == nil {
+		return response, nil
+	}
+
+	events, err := s.gunfightRepo.GetEvents(ctx, gunfightID)
+	if err != nil {
+		return nil, err
+	}
+
+	response.ServerSeed = game.ServerSeed
+	response.Draws, err = VerifyDraws(game, events)
+	response.Verified = err == nil
+	if err != nil {
+		response.Message = err.Error()
+	}
+	return response, nil
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"testing"
+	"time"
+	"wildwest/internal/model/gunfight"
+)
+
+func fairTestGame() *gunfight.Game {
+	serverSeed := "server-seed"
+	return &gunfight.Game{
+		ID:         1,
+		User1ID:    1,
+		User2ID:    2,
+		ServerSeed: serverSeed,
+		SeedHash:   gunfight.SeedHash(serverSeed),
+		Players:    []gunfight.Player{{UserID: 1, Team: 1, ClientSeed: "a"}, {UserID: 2, Team: 2, ClientSeed: "b"}},
+	}
+}
+
+// drawLog — журнал раунда: событие раунда сохранялось roundLatency, после чего завели паузу, а сигнал пришел
+// через delay после этого
+func drawLog(roundAt time.Time, roundLatency, delay time.Duration) []gunfight.Event {
+	armedAt := roundAt.Add(roundLatency)
+	return []gunfight.Event{
+		{Type: gunfight.DuelEventRound, Round: 1, CreatedAt: roundAt},
+		{Type: gunfight.EventArmed, Round: 1, CreatedAt: armedAt},
+		{Type: gunfight.DuelEventDraw, Round: 1, CreatedAt: armedAt.Add(delay)},
+	}
+}
+
+func TestVerifyDraws(t *testing.T) {
+	game := fairTestGame()
+	delay := fairDrawDelay(game.ServerSeed, clientSeeds(game), 1)
+	roundAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		events []gunfight.Event
+		valid  bool
+	}{
+		{name: "exact delay", events: drawLog(roundAt, 0, delay), valid: true},
+		{name: "slow redis before the pause", events: drawLog(roundAt, 500*time.Millisecond, delay+5*time.Millisecond), valid: true},
+		{name: "draw too early", events: drawLog(roundAt, 0, delay-10*time.Millisecond)},
+		{name: "draw too late", events: drawLog(roundAt, 0, delay+drawDelayTolerance+time.Millisecond)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := VerifyDraws(game, test.events)
+			if (err == nil) != test.valid {
+				t.Fatalf("VerifyDraws error = %v, want valid %v", err, test.valid)
+			}
+		})
+	}
+}
@@ -2,80 +2,895 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"strconv"
+	"github.com/google/uuid"
+	"math"
+	"slices"
+	"sync"
 	"time"
 	"wildwest/internal/model/gunfight"
 	"wildwest/internal/repository"
+	"wildwest/pkg/settings"
 )
 
 type gunfightService struct {
 	gunfightRepo  repository.GunfightPostgresRepository
 	gunfightRedis repository.GunfightRedisRepository
+	userRepo      repository.UserPostgresRepository
+	moneyRepo     repository.MoneyPostgresRepository
+	cfg           *settings.Config
+	clock         Clock
+	duelsMu       sync.Mutex
+	duels         map[int]*duel
+	// instanceID — владелец аренды дуэлей этого инстанса в Redis: дуэль идет только на одном инстансе
+	instanceID string
 }
 
-func NewGunfightService(gunfightRepo repository.GunfightPostgresRepository, gunfightRedis repository.GunfightRedisRepository) GunfightService {
-	return &gunfightService{gunfightRepo: gunfightRepo, gunfightRedis: gunfightRedis}
+func NewGunfightService(gunfightRepo repository.GunfightPostgresRepository, gunfightRedis repository.GunfightRedisRepository, userRepo repository.UserPostgresRepository, moneyRepo repository.MoneyPostgresRepository, cfg *settings.Config) GunfightService {
+	return &gunfightService{
+		gunfightRepo:  gunfightRepo,
+		gunfightRedis: gunfightRedis,
+		userRepo:      userRepo,
+		moneyRepo:     moneyRepo,
+		cfg:           cfg,
+		clock:         systemClock{},
+		duels:         make(map[int]*duel),
+		instanceID:    uuid.New().String(),
+	}
 }
 
-func (s *gunfightService) FindGunfight(ctx context.Context, userID int) (gunfight.QueueResponse, error) {
-	var response gunfight.QueueResponse
+// FindGunfight ищет соперника в очереди queueName, onSearching вызывается при каждом расширении диапазона поиска,
+// onQueue — каждые queueStatusInterval, пока игрок ждет
+func (s *gunfightService) FindGunfight(ctx context.Context, userID int, queueName string, onSearching func(gunfight.SearchingPayload), onQueue func(gunfight.QueueStatus)) (response gunfight.QueueResponse, err error) {
+	queue, err := s.queue(queueName)
+	if err != nil {
+		return response, err
+	}
 
-	opponentID, err := s.gunfightRedis.FindOpponent(ctx, 100)
+	// Время ожидания найденных матчей с живыми соперниками нужно для оценки ожидания в очереди
+	startedAt := time.Now()
+	defer func() {
+		s.countSearch(context.WithoutCancel(ctx), queue.Name, response, err)
+		if err == nil && response.GunfightID != 0 && response.Bot == "" {
+			s.gunfightRedis.RecordMatchWait(context.WithoutCancel(ctx), queue.Name, time.Since(startedAt))
+		}
+	}()
+
+	cooldown, err := s.gunfightRedis.GetCooldown(ctx, userID)
 	if err != nil {
+		return response, fmt.Errorf("error getting matchmaking cooldown: %w", err)
+	}
+	if cooldown > 0 {
+		return response, fmt.Errorf("matchmaking is locked for %s after leaving a gunfight", cooldown.Round(time.Second))
+	}
+
+	if queue.MinBalance > 0 {
+		balance, err := s.moneyRepo.Get(ctx, userID)
+		if err != nil {
+			return response, fmt.Errorf("error getting balance: %w", err)
+		}
+		if balance.Gold < queue.MinBalance {
+			return response, fmt.Errorf("queue %s requires a balance of at least %d gold", queue.Name, queue.MinBalance)
+		}
+	}
+
+	if queue.Stake > 0 {
+		if err := s.gunfightRepo.HoldStake(ctx, userID, queue.Stake); err != nil {
+			return response, fmt.Errorf("error holding stake: %w", err)
+		}
+	}
+
+	// Подписываемся до постановки в очередь, чтобы не пропустить уведомление о матче
+	subCtx, unsubscribe := context.WithCancel(ctx)
+	defer unsubscribe()
+
+	matches, err := s.gunfightRedis.SubscribeMatch(subCtx, userID)
+	if err != nil {
+		s.gunfightRepo.ReleaseStake(context.WithoutCancel(ctx), userID)
+		return response, fmt.Errorf("error subscribing to match notifications: %w", err)
+	}
+
+	rating, err := s.gunfightRepo.GetRating(ctx, userID)
+	if err != nil {
+		s.gunfightRepo.ReleaseStake(context.WithoutCancel(ctx), userID)
+		return response, fmt.Errorf("error getting rating: %w", err)
+	}
+
+	band := searchBand(rating.Rating, queue.Bands[0])
+	onSearching(gunfight.SearchingPayload{Band: band})
+
+	opponentID, err := s.gunfightRedis.MatchOrEnqueue(ctx, userID, queue.Name, rating.Rating, band)
+	if err != nil {
+		s.gunfightRepo.ReleaseStake(context.WithoutCancel(ctx), userID)
 		return response, fmt.Errorf("error finding opponent: %w", err)
 	}
 
 	if opponentID != 0 {
-		return s.handleFoundOpponent(ctx, userID, opponentID)
+		response, err = s.handleFoundOpponent(ctx, userID, opponentID, queue)
+		if err != nil {
+			s.gunfightRepo.ReleaseStake(context.WithoutCancel(ctx), userID)
+		}
+		return response, err
 	}
 
-	return s.handleQueueAddition(ctx, userID)
+	return s.waitForOpponent(ctx, userID, queue, rating.Rating, matches, onSearching, onQueue)
 }
 
-func (s *gunfightService) handleFoundOpponent(ctx context.Context, userID, opponentID int) (gunfight.QueueResponse, error) {
+func (s *gunfightService) handleFoundOpponent(ctx context.Context, userID, opponentID int, queue settings.GunfightQueue) (gunfight.QueueResponse, error) {
 	var response gunfight.QueueResponse
-	gunfightData := &gunfight.Game{User1ID: userID, User2ID: opponentID}
+	gunfightData := &gunfight.Game{User1ID: userID, User2ID: opponentID, Stake: queue.Stake, Ranked: queue.Ranked, Queue: queue.Name}
 	gunfightID, err := s.gunfightRepo.Create(ctx, gunfightData)
 	if err != nil {
 		return response, fmt.Errorf("error creating gunfight: %w", err)
 	}
 
-	if err := s.gunfightRedis.RemovePlayerFromQueue(ctx, opponentID); err != nil {
-		return response, fmt.Errorf("error removing opponent from queue: %w", err)
+	if err := s.gunfightRedis.PublishMatch(ctx, opponentID, gunfight.Match{GunfightID: gunfightID, OpponentID: userID}); err != nil {
+		return response, fmt.Errorf("error notifying opponent: %w", err)
 	}
 
-	response = gunfight.QueueResponse{OpponentID: opponentID, Message: strconv.Itoa(gunfightID)}
+	response = gunfight.QueueResponse{OpponentID: opponentID, GunfightID: gunfightID}
 	return response, nil
 }
 
-func (s *gunfightService) handleQueueAddition(ctx context.Context, userID int) (gunfight.QueueResponse, error) {
-	var response gunfight.QueueResponse
-	err := s.gunfightRedis.AddPlayerToQueue(ctx, userID, 100)
-	if err != nil {
-		return response, fmt.Errorf("error adding player to queue: %w", err)
+// waitForOpponent ждет, пока игрока заберет другой игрок, и на каждом шаге сам повторяет поиск,
+// расширяя диапазон рейтинга по расписанию очереди
+func (s *gunfightService) waitForOpponent(ctx context.Context, userID int, queue settings.GunfightQueue, rating int, matches <-chan gunfight.Match, onSearching func(gunfight.SearchingPayload), onQueue func(gunfight.QueueStatus)) (gunfight.QueueResponse, error) {
+	timer := time.NewTimer(queue.Timeout)
+	defer timer.Stop()
+
+	ticker := time.NewTicker(queue.BandStep)
+	defer ticker.Stop()
+
+	statusTicker := time.NewTicker(queueStatusInterval)
+	defer statusTicker.Stop()
+
+	pushStatus := func(band gunfight.Band) {
+		if status, err := s.queueStatus(ctx, userID, queue, band); err == nil {
+			onQueue(*status)
+		}
+	}
+	pushStatus(searchBand(rating, queue.Bands[0]))
+
+	// Если бот выключен, канал остается nil и никогда не сработает
+	var botTimer <-chan time.Time
+	if s.cfg.Gunfight.BotWait > 0 {
+		botTimer = time.After(s.cfg.Gunfight.BotWait)
 	}
 
-	resultChan := make(chan gunfight.QueueResponse)
-	go func() {
+	step := 0
+	for {
 		select {
-		case <-time.After(1 * time.Minute):
-			s.gunfightRedis.RemovePlayerFromQueue(ctx, userID)
-			resultChan <- gunfight.QueueResponse{Message: "No opponent found within the time limit"}
+		case match, ok := <-matches:
+			if !ok {
+				s.RemovePlayerFromQueue(context.WithoutCancel(ctx), userID)
+				return gunfight.QueueResponse{}, fmt.Errorf("match notifications closed")
+			}
+			return gunfight.QueueResponse{OpponentID: match.OpponentID, GunfightID: match.GunfightID}, nil
+		case <-ticker.C:
+			if step < len(queue.Bands)-1 {
+				step++
+				onSearching(gunfight.SearchingPayload{Band: searchBand(rating, queue.Bands[step])})
+			}
+
+			opponentID, err := s.gunfightRedis.MatchWaiting(ctx, userID, queue.Name, rating, searchBand(rating, queue.Bands[step]))
+			if err != nil {
+				s.RemovePlayerFromQueue(context.WithoutCancel(ctx), userID)
+				return gunfight.QueueResponse{}, fmt.Errorf("error finding opponent: %w", err)
+			}
+
+			if opponentID != 0 {
+				response, err := s.handleFoundOpponent(ctx, userID, opponentID, queue)
+				if err != nil {
+					s.gunfightRepo.ReleaseStake(context.WithoutCancel(ctx), userID)
+				}
+				return response, err
+			}
+		case <-statusTicker.C:
+			pushStatus(searchBand(rating, queue.Bands[step]))
+		case <-botTimer:
+			removed, err := s.gunfightRedis.RemovePlayerFromQueue(ctx, userID)
+			if err != nil {
+				s.RemovePlayerFromQueue(context.WithoutCancel(ctx), userID)
+				return gunfight.QueueResponse{}, fmt.Errorf("error leaving queue for a bot: %w", err)
+			}
+			// Игрока уже забрал соперник, уведомление о матче вот-вот придет
+			if !removed {
+				botTimer = nil
+				continue
+			}
+			return s.matchBot(ctx, userID, queue, rating)
+		case <-timer.C:
+			// Игрока мог забрать соперник перед самым окончанием поиска: игра уже создана, уведомление
+			// о матче вот-вот придет
+			removed, err := s.gunfightRedis.RemovePlayerFromQueue(ctx, userID)
+			if err == nil && !removed {
+				select {
+				case match, ok := <-matches:
+					if ok {
+						return gunfight.QueueResponse{OpponentID: match.OpponentID, GunfightID: match.GunfightID}, nil
+					}
+				case <-time.After(challengeReplyWait):
+				case <-ctx.Done():
+					return gunfight.QueueResponse{}, ctx.Err()
+				}
+			}
+			s.RemovePlayerFromQueue(context.WithoutCancel(ctx), userID)
+			return gunfight.QueueResponse{Message: "No opponent found within the time limit"}, nil
 		case <-ctx.Done():
-			s.gunfightRedis.RemovePlayerFromQueue(ctx, userID)
-			resultChan <- gunfight.QueueResponse{}
+			s.RemovePlayerFromQueue(context.WithoutCancel(ctx), userID)
+			return gunfight.QueueResponse{}, ctx.Err()
 		}
-	}()
+	}
+}
 
-	select {
-	case result := <-resultChan:
-		return result, nil
-	case <-ctx.Done():
-		return response, ctx.Err()
+// queue возвращает настройки очереди по имени
+func (s *gunfightService) queue(name string) (settings.GunfightQueue, error) {
+	for _, queue := range s.cfg.Gunfight.Queues {
+		if queue.Name == name {
+			return queue, nil
+		}
+	}
+
+	names := make([]string, 0, len(s.cfg.Gunfight.Queues))
+	for _, queue := range s.cfg.Gunfight.Queues {
+		names = append(names, queue.Name)
 	}
+	return settings.GunfightQueue{}, fmt.Errorf("queue must be one of %v", names)
 }
 
+// countSearch засчитывает очереди исход поиска; ошибки поиска, кроме отмены игроком, не считаются
+func (s *gunfightService) countSearch(ctx context.Context, queue string, response gunfight.QueueResponse, err error) {
+	counter := ""
+	switch {
+	case errors.Is(err, context.Canceled):
+		counter = gunfight.QueueCancelled
+	case err != nil:
+		return
+	case response.Bot != "":
+		counter = gunfight.QueueBot
+	case response.GunfightID != 0:
+		counter = gunfight.QueueMatched
+	default:
+		counter = gunfight.QueueTimeout
+	}
+	s.gunfightRedis.IncrementQueueCounter(ctx, queue, counter)
+}
+
+// QueueStatus возвращает загрузку очереди queueName для диапазона рейтинга, с которого игрок начнет поиск
+func (s *gunfightService) QueueStatus(ctx context.Context, userID int, queueName string) (*gunfight.QueueStatus, error) {
+	queue, err := s.queue(queueName)
+	if err != nil {
+		return nil, err
+	}
+
+	rating, err := s.gunfightRepo.GetRating(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting rating: %w", err)
+	}
+
+	return s.queueStatus(ctx, userID, queue, searchBand(rating.Rating, queue.Bands[0]))
+}
+
+// queueStatus считает игроков в очереди и оценивает ожидание медианой недавних ожиданий
+func (s *gunfightService) queueStatus(ctx context.Context, userID int, queue settings.GunfightQueue, band gunfight.Band) (*gunfight.QueueStatus, error) {
+	inBand, total, err := s.gunfightRedis.GetQueueStatus(ctx, userID, queue.Name, band)
+	if err != nil {
+		return nil, fmt.Errorf("error getting queue status: %w", err)
+	}
+
+	estimate, err := s.medianWait(ctx, queue.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &gunfight.QueueStatus{
+		Queue:         queue.Name,
+		Stake:         queue.Stake,
+		Band:          band,
+		InBand:        inBand,
+		Total:         total,
+		EstimatedWait: estimate,
+	}, nil
+}
+
+// GetQueueMetrics возвращает настройки и счетчики всех очередей в порядке конфигурации
+func (s *gunfightService) GetQueueMetrics(ctx context.Context) ([]gunfight.QueueMetrics, error) {
+	metrics := make([]gunfight.QueueMetrics, 0, len(s.cfg.Gunfight.Queues))
+	for _, queue := range s.cfg.Gunfight.Queues {
+		waiting, counters, err := s.gunfightRedis.GetQueueCounters(ctx, queue.Name)
+		if err != nil {
+			return nil, fmt.Errorf("error getting %s queue counters: %w", queue.Name, err)
+		}
+
+		medianWait, err := s.medianWait(ctx, queue.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		metrics = append(metrics, gunfight.QueueMetrics{
+			Name:       queue.Name,
+			Stake:      queue.Stake,
+			Ranked:     queue.Ranked,
+			MinBalance: queue.MinBalance,
+			HouseFee:   queue.HouseFee,
+			Timeout:    int(queue.Timeout.Seconds()),
+			Waiting:    waiting,
+			Matched:    counters[gunfight.QueueMatched],
+			Bots:       counters[gunfight.QueueBot],
+			Timeouts:   counters[gunfight.QueueTimeout],
+			Cancelled:  counters[gunfight.QueueCancelled],
+			MedianWait: medianWait,
+		})
+	}
+	return metrics, nil
+}
+
+// medianWait возвращает медиану недавних ожиданий соперника в секундах, nil — если матчей не было
+func (s *gunfightService) medianWait(ctx context.Context, queue string) (*int, error) {
+	waits, err := s.gunfightRedis.GetMatchWaits(ctx, queue)
+	if err != nil {
+		return nil, fmt.Errorf("error getting recent match waits: %w", err)
+	}
+	if len(waits) == 0 {
+		return nil, nil
+	}
+
+	slices.Sort(waits)
+	median := int(math.Ceil(waits[len(waits)/2].Seconds()))
+	return &median, nil
+}
+
+// matchBot сажает против игрока бота по силе очереди и рейтинга игрока. Игра с ботом бесплатная и не рейтинговая:
+// ставка возвращается игроку
+func (s *gunfightService) matchBot(ctx context.Context, userID int, queue settings.GunfightQueue, rating int) (gunfight.QueueResponse, error) {
+	if err := s.gunfightRepo.ReleaseStake(context.WithoutCancel(ctx), userID); err != nil {
+		return gunfight.QueueResponse{}, fmt.Errorf("error releasing stake: %w", err)
+	}
+
+	tier := slices.IndexFunc(s.cfg.Gunfight.Queues, func(q settings.GunfightQueue) bool { return q.Name == queue.Name })
+	profile := pickBot(tier, len(s.cfg.Gunfight.Queues), rating)
+	gunfightID, err := s.gunfightRepo.Create(ctx, &gunfight.Game{User1ID: userID, User2ID: profile.userID, Bot: profile.name, Queue: queue.Name})
+	if err != nil {
+		return gunfight.QueueResponse{}, fmt.Errorf("error creating gunfight: %w", err)
+	}
+
+	return gunfight.QueueResponse{OpponentID: profile.userID, GunfightID: gunfightID, Bot: profile.name}, nil
+}
+
+// RemovePlayerFromQueue убирает игрока из очереди и возвращает ставку, если игра для него так и не была создана
 func (s *gunfightService) RemovePlayerFromQueue(ctx context.Context, userID int) error {
-	return s.gunfightRedis.RemovePlayerFromQueue(ctx, userID)
+	if _, err := s.gunfightRedis.RemovePlayerFromQueue(ctx, userID); err != nil {
+		return err
+	}
+	return s.gunfightRepo.ReleaseStake(ctx, userID)
+}
+
+func (s *gunfightService) GetRating(ctx context.Context, userID int) (*gunfight.RatingResponse, error) {
+	rating, err := s.gunfightRepo.GetRating(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &gunfight.RatingResponse{Rating: rating.Rating, Games: rating.Games}, nil
+}
+
+const (
+	historyDefaultLimit = 20
+	historyMaxLimit     = 100
+
+	penaltyWindow  = 24 * time.Hour
+	sweepBatchSize = 100
+
+	queueStatusInterval = 5 * time.Second
+)
+
+func (s *gunfightService) GetHistory(ctx context.Context, userID int, cursor int, limit int) (*gunfight.HistoryResponse, error) {
+	if limit <= 0 {
+		limit = historyDefaultLimit
+	}
+	limit = min(limit, historyMaxLimit)
+
+	games, err := s.gunfightRepo.GetHistory(ctx, userID, cursor, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &gunfight.HistoryResponse{Items: make([]gunfight.HistoryItem, 0, len(games))}
+	for _, game := range games {
+		captainID, opponentID := game.User1ID, game.User2ID
+		if game.Team == 2 {
+			captainID, opponentID = opponentID, captainID
+		}
+
+		item := gunfight.HistoryItem{
+			ID:         game.ID,
+			OpponentID: opponentID,
+			WinnerID:   game.WinnerID,
+			Result:     gunfight.ResultDraw,
+			Stake:      game.Stake,
+			Bot:        game.Bot,
+			Queue:      game.Queue,
+			TeamSize:   teamSize(&game),
+			RematchOf:  game.RematchOf,
+			StartDate:  game.StartDate,
+			EndDate:    game.EndDate,
+		}
+		if game.WinnerID != nil {
+			item.Result = gunfight.ResultLoss
+			if *game.WinnerID == captainID {
+				item.Result = gunfight.ResultWin
+			}
+		}
+		response.Items = append(response.Items, item)
+	}
+
+	if len(games) == limit {
+		response.NextCursor = games[len(games)-1].ID
+	}
+
+	return response, nil
+}
+
+func (s *gunfightService) GetStats(ctx context.Context, userID int) (*gunfight.StatsResponse, error) {
+	stats, err := s.gunfightRepo.GetStats(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &gunfight.StatsResponse{
+		Wins:                stats.Wins,
+		Losses:              stats.Losses,
+		Draws:               stats.Draws,
+		Streak:              stats.Streak,
+		MostFacedOpponentID: stats.MostFacedOpponentID,
+		MostFacedGames:      stats.MostFacedGames,
+	}
+	if decided := stats.Wins + stats.Losses; decided > 0 {
+		response.WinRate = math.Round(float64(stats.Wins)/float64(decided)*100) / 100
+	}
+
+	return response, nil
+}
+
+// gameResult считает выигрыш и изменение рейтинга игроков, ничья и нерейтинговые игры рейтинг не меняют.
+// В командной игре winnerID — капитан, выигрыш получает каждый игрок команды, а рейтинг считается
+// по среднему рейтингу команд и меняется у всех игроков одинаково
+func (s *gunfightService) gameResult(ctx context.Context, game *gunfight.Game, winnerID *int) (*gunfight.Result, error) {
+	result := &gunfight.Result{WinnerID: winnerID, BetFee: s.cfg.Gunfight.Bets.HouseFee}
+	if winnerID == nil {
+		return result, nil
+	}
+
+	loserID := game.User1ID
+	if loserID == *winnerID {
+		loserID = game.User2ID
+	}
+
+	members, sides := teamSides(game)
+	winners, losers := teamMembers(members, sides, *winnerID), teamMembers(members, sides, loserID)
+	result.Winners = winners
+	result.Prize = s.prize(game)
+	if !game.Ranked {
+		return result, nil
+	}
+
+	winnerRating, err := s.teamRating(ctx, winners)
+	if err != nil {
+		return nil, err
+	}
+	loserRating, err := s.teamRating(ctx, losers)
+	if err != nil {
+		return nil, err
+	}
+
+	delta := eloDelta(winnerRating, loserRating)
+	result.RatingChanges = make(map[int]int, len(members))
+	for _, userID := range winners {
+		result.RatingChanges[userID] = delta
+	}
+	for _, userID := range losers {
+		result.RatingChanges[userID] = -delta
+	}
+	return result, nil
+}
+
+// teamRating возвращает средний рейтинг игроков команды
+func (s *gunfightService) teamRating(ctx context.Context, team []int) (int, error) {
+	total := 0
+	for _, userID := range team {
+		rating, err := s.gunfightRepo.GetRating(ctx, userID)
+		if err != nil {
+			return 0, err
+		}
+		total += rating.Rating
+	}
+	return total / max(len(team), 1), nil
+}
+
+// prize возвращает выигрыш каждого победителя: его долю банка, то есть две ставки, за вычетом комиссии заведения,
+// комиссия берется из очереди, через которую была найдена игра
+func (s *gunfightService) prize(game *gunfight.Game) int {
+	fee := s.cfg.Gunfight.HouseFee
+	if queue, err := s.queue(game.Queue); err == nil {
+		fee = queue.HouseFee
+	}
+
+	pot := 2 * game.Stake
+	return pot - pot*fee/100
+}
+
+// JoinGunfight сажает игрока за дуэль. Переподключившийся игрок передает номер последнего полученного события
+// и получает пропущенные события в DuelSeat.Missed
+func (s *gunfightService) JoinGunfight(ctx context.Context, gunfightID, userID int, lastSeq int64, clientSeed string) (*DuelSeat, error) {
+	game, err := s.participantGame(ctx, gunfightID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if game.EndDate != nil {
+		return nil, fmt.Errorf("gunfight %d is already finished", gunfightID)
+	}
+
+	state, err := s.gunfightRedis.GetDuelState(ctx, gunfightID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting duel state: %w", err)
+	}
+
+	// Дуэль ведет тот инстанс, который первым занял ее аренду; на других инстансах в нее не войти
+	owned, err := s.gunfightRedis.AcquireDuelLease(ctx, gunfightID, s.instanceID, duelLeaseTTL)
+	if err != nil {
+		return nil, fmt.Errorf("error acquiring gunfight: %w", err)
+	}
+	if !owned {
+		return nil, fmt.Errorf("gunfight %d is running on another server", gunfightID)
+	}
+
+	s.duelsMu.Lock()
+	d, ok := s.duels[gunfightID]
+	if !ok {
+		d = newDuel(game, s.cfg, s.clock, s.recordDuelEvent)
+		// Дуэль могла начаться до перезапуска сервера: продолжаем с сохраненного раунда
+		if state != nil {
+			d.resume(*state)
+		}
+		s.duels[gunfightID] = d
+		go s.runDuel(d)
+		go s.keepDuelLease(d)
+		go d.feedSpectators()
+		if profile, ok := botByName(game.Bot); ok {
+			go runBot(d, profile)
+		}
+	}
+	s.duelsMu.Unlock()
+
+	events, seq, err := d.join(userID, clientSeed)
+	if err != nil {
+		return nil, err
+	}
+	context.AfterFunc(ctx, func() { d.leave(userID, events) })
+
+	missed, err := s.missedEvents(ctx, gunfightID, lastSeq, seq)
+	if err != nil {
+		return nil, fmt.Errorf("error getting missed events: %w", err)
+	}
+
+	return &DuelSeat{Missed: missed, Events: events, duel: d, userID: userID}, nil
+}
+
+// participantGame возвращает игру вместе с составом команд, если игрок в ней участвует
+func (s *gunfightService) participantGame(ctx context.Context, gunfightID, userID int) (*gunfight.Game, error) {
+	game, err := s.gunfightRepo.Get(ctx, gunfightID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting gunfight: %w", err)
+	}
+
+	game.Players, err = s.gunfightRepo.GetPlayers(ctx, gunfightID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting gunfight players: %w", err)
+	}
+
+	if !slices.ContainsFunc(game.Players, func(player gunfight.Player) bool { return player.UserID == userID }) {
+		return nil, fmt.Errorf("user %d is not a participant of gunfight %d", userID, gunfightID)
+	}
+	return game, nil
+}
+
+// missedEvents возвращает события дуэли с номерами после after и не больше upTo
+func (s *gunfightService) missedEvents(ctx context.Context, gunfightID int, after, upTo int64) ([]gunfight.DuelEvent, error) {
+	if after >= upTo {
+		return nil, nil
+	}
+
+	messages, err := s.gunfightRedis.GetDuelEvents(ctx, gunfightID)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []gunfight.DuelEvent
+	for _, message := range messages {
+		if message.Seq <= after || message.Seq > upTo {
+			continue
+		}
+		var event gunfight.DuelEvent
+		if err := message.DecodePayload(&event); err != nil {
+			return nil, err
+		}
+		event.Type, event.Seq = message.Type, message.Seq
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// recordDuelEvent сохраняет событие и состояние дуэли в Redis. Без Redis дуэль продолжается,
+// но переподключившийся игрок не получит пропущенные события
+func (s *gunfightService) recordDuelEvent(event gunfight.DuelEvent, state gunfight.DuelState) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if message, err := gunfight.EncodeMessage(event.Type, event.Seq, event); err == nil {
+		s.gunfightRedis.AppendDuelEvent(ctx, state.GunfightID, message, duelStateTTL)
+	}
+	s.gunfightRedis.SaveDuelState(ctx, state, duelStateTTL)
+}
+
+// keepDuelLease продлевает аренду дуэли, пока дуэль идет
+func (s *gunfightService) keepDuelLease(d *duel) {
+	ticker := time.NewTicker(duelLeaseTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			s.gunfightRedis.AcquireDuelLease(ctx, d.game.ID, s.instanceID, duelLeaseTTL)
+			cancel()
+		case <-d.done:
+			return
+		}
+	}
+}
+
+// duelRunning проверяет, идет ли дуэль на этом или другом инстансе
+func (s *gunfightService) duelRunning(ctx context.Context, gunfightID int) (bool, error) {
+	s.duelsMu.Lock()
+	_, running := s.duels[gunfightID]
+	s.duelsMu.Unlock()
+	if running {
+		return true, nil
+	}
+	return s.gunfightRedis.HasDuelLease(ctx, gunfightID)
+}
+
+// flushJournal пишет накопленный журнал дуэли в Postgres. Если запись не удалась, журнал остается в памяти
+// и уйдет со следующей записью или с итогом игры
+func (s *gunfightService) flushJournal(d *duel) {
+	if len(d.journal) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.gunfightRepo.AppendEvents(ctx, d.journal); err == nil {
+		d.journal = nil
+	}
+}
+
+// GetReplay возвращает журнал дуэли одному из ее участников
+func (s *gunfightService) GetReplay(ctx context.Context, gunfightID, userID int) (*gunfight.ReplayResponse, error) {
+	game, err := s.participantGame(ctx, gunfightID, userID)
+	if err != nil {
+		return nil, err
+	}
+	members, _ := teamSides(game)
+
+	events, err := s.gunfightRepo.GetEvents(ctx, gunfightID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &gunfight.ReplayResponse{
+		GunfightID: game.ID,
+		Players:    members,
+		WinnerID:   game.WinnerID,
+		Events:     make([]gunfight.ReplayEvent, 0, len(events)),
+	}
+	for _, event := range events {
+		response.Events = append(response.Events, gunfight.ReplayEvent{
+			Seq:     event.Seq,
+			Type:    event.Type,
+			UserID:  event.UserID,
+			Round:   event.Round,
+			RTT:     event.RTT,
+			Payload: event.Payload,
+			At:      event.CreatedAt,
+		})
+	}
+
+	return response, nil
+}
+
+// WatchGunfight подключает зрителя к идущей дуэли, канал событий закрывается по окончании дуэли
+func (s *gunfightService) WatchGunfight(ctx context.Context, gunfightID int) (<-chan gunfight.DuelEvent, error) {
+	s.duelsMu.Lock()
+	d, ok := s.duels[gunfightID]
+	s.duelsMu.Unlock()
+	if !ok {
+		if remote, err := s.gunfightRedis.HasDuelLease(ctx, gunfightID); err == nil && remote {
+			return nil, fmt.Errorf("gunfight %d is running on another server", gunfightID)
+		}
+		return nil, fmt.Errorf("gunfight %d is not running", gunfightID)
+	}
+
+	spectatorID, events, err := d.watch()
+	if err != nil {
+		return nil, err
+	}
+	context.AfterFunc(ctx, func() { d.unwatch(spectatorID) })
+
+	return events, nil
+}
+
+func (s *gunfightService) runDuel(d *duel) {
+	defer s.removeDuel(d)
+
+	ctx := context.Background()
+	players := d.players()
+	health := copyHealth(d.state.Health)
+
+	select {
+	case <-d.ready:
+	case <-d.clock.After(duelJoinTimeout):
+		// Победа достается игроку, который пришел на дуэль, если соперник так и не появился
+		if absent := d.absent(); absent != 0 {
+			s.forfeitDuel(ctx, d, absent, health)
+			return
+		}
+		s.gunfightRepo.Finish(ctx, d.game.ID, &gunfight.Result{})
+		d.broadcast(gunfight.DuelEvent{Type: gunfight.DuelEventCancelled, Message: "Opponent did not join the gunfight"})
+		return
+	}
+
+	// Сиды игроков записываются, а прием ставок зрителей закрывается до первого раунда;
+	// после перезапуска это уже сделано
+	seeds := d.seeds()
+	if !d.resumed {
+		err := s.gunfightRepo.CloseBets(ctx, d.game.ID)
+		if err == nil {
+			err = s.gunfightRepo.SetClientSeeds(ctx, d.game.ID, seeds)
+		}
+		if err != nil {
+			s.gunfightRepo.Finish(ctx, d.game.ID, &gunfight.Result{})
+			d.broadcast(gunfight.DuelEvent{Type: gunfight.DuelEventCancelled, Message: err.Error()})
+			return
+		}
+	}
+	clientSeeds := make([]string, 0, len(d.members))
+	for _, userID := range d.members {
+		clientSeeds = append(clientSeeds, seeds[userID])
+	}
+
+	for round := d.state.Round + 1; round <= duelMaxRounds && health[players[0]] > 0 && health[players[1]] > 0; round++ {
+		d.broadcast(gunfight.DuelEvent{Type: gunfight.DuelEventRound, Round: round, Health: copyHealth(health), SeedHash: d.game.SeedHash})
+
+		outcome := d.steady(round, drawDelay(d.game.ServerSeed, clientSeeds, round))
+		if outcome.foul == 0 && outcome.forfeited == 0 {
+			drawAt := d.broadcast(gunfight.DuelEvent{Type: gunfight.DuelEventDraw, Round: round})
+			outcome = d.collect(round, drawAt)
+		}
+		if outcome.forfeited != 0 {
+			s.forfeitDuel(ctx, d, outcome.forfeited, health)
+			return
+		}
+
+		hits := resolveDraw(players, bySide(outcome, d.sides))
+		applyHits(players, health, hits)
+
+		for _, userID := range hits {
+			if err := s.gunfightRepo.UpdateHealth(ctx, d.game.ID, userID, health[userID]); err != nil {
+				s.gunfightRepo.Finish(ctx, d.game.ID, &gunfight.Result{})
+				d.broadcast(gunfight.DuelEvent{Type: gunfight.DuelEventCancelled, Message: err.Error()})
+				return
+			}
+		}
+
+		d.update(round, health)
+		d.broadcast(gunfight.DuelEvent{
+			Type:      gunfight.DuelEventResult,
+			Round:     round,
+			Health:    copyHealth(health),
+			Hits:      hits,
+			Foul:      outcome.foul,
+			Reactions: reactionMillis(outcome.reactions),
+		})
+		s.flushJournal(d)
+	}
+
+	s.finishDuel(ctx, d, duelWinner(players, health), health, "", false)
+}
+
+// forfeitDuel засчитывает поражение игроку, который не пришел или не вернулся в дуэль, и его команде,
+// наказан бывает только сам игрок. Дуэль не доиграна, поэтому ставки зрителей возвращаются
+func (s *gunfightService) forfeitDuel(ctx context.Context, d *duel, forfeitedID int, health map[int]int) {
+	winnerID := d.game.User1ID
+	if winnerID == d.sides[forfeitedID] {
+		winnerID = d.game.User2ID
+	}
+	d.logForfeit(forfeitedID)
+	if s.finishDuel(ctx, d, &winnerID, health, fmt.Sprintf("Player %d left the gunfight", forfeitedID), true) {
+		s.penalize(ctx, forfeitedID)
+	}
+}
+
+// penalize закрывает игроку поиск соперника; каждый уход за окно penaltyWindow удваивает время ожидания
+func (s *gunfightService) penalize(ctx context.Context, userID int) error {
+	count, err := s.gunfightRedis.AddPenalty(ctx, userID, penaltyWindow)
+	if err != nil {
+		return err
+	}
+
+	cooldown := s.cfg.Gunfight.CooldownBase
+	for i := 1; i < count && cooldown < s.cfg.Gunfight.CooldownMax; i++ {
+		cooldown *= 2
+	}
+	return s.gunfightRedis.SetCooldown(ctx, userID, min(cooldown, s.cfg.Gunfight.CooldownMax))
+}
+
+// SweepStaleGames закрывает игры, которые так и не закончились, например из-за перезапуска сервера:
+// победителя нет, ставки возвращаются игрокам. Возвращает число закрытых игр
+func (s *gunfightService) SweepStaleGames(ctx context.Context) (int, error) {
+	games, err := s.gunfightRepo.GetStale(ctx, time.Now().Add(-s.cfg.Gunfight.StaleAfter), sweepBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	closed := 0
+	for _, game := range games {
+		if running, err := s.duelRunning(ctx, game.ID); err != nil || running {
+			continue
+		}
+
+		// Игру мог уже закрыть другой инстанс, тогда Finish вернет ошибку и игра пропускается
+		if err := s.gunfightRepo.Finish(ctx, game.ID, &gunfight.Result{}); err == nil {
+			closed++
+		}
+	}
+	return closed, nil
+}
+
+// finishDuel записывает итог дуэли вместе с остатком журнала и событием об окончании, поэтому журнал
+// законченной игры всегда полный. Игроки получают событие только после записи итога.
+// Возвращает false, если итог записать не удалось, например игру уже закрыл другой инстанс
+func (s *gunfightService) finishDuel(ctx context.Context, d *duel, winnerID *int, health map[int]int, message string, refundBets bool) bool {
+	finished := gunfight.DuelEvent{Type: gunfight.DuelEventFinished, Health: copyHealth(health), Message: message}
+	if winnerID != nil {
+		finished.WinnerID = *winnerID
+	}
+	finished, at := d.stamp(finished)
+
+	result, err := s.gameResult(ctx, d.game, winnerID)
+	if err == nil {
+		result.RefundBets = refundBets
+		result.Journal = slices.Concat(d.journal, []gunfight.Event{d.journalEvent(finished, at)})
+		err = s.gunfightRepo.Finish(ctx, d.game.ID, result)
+	}
+	if err != nil {
+		d.broadcast(gunfight.DuelEvent{Type: gunfight.DuelEventCancelled, Message: err.Error()})
+		return false
+	}
+
+	d.journal = nil
+	d.publish(finished)
+	return true
+}
+
+func (s *gunfightService) removeDuel(d *duel) {
+	s.flushJournal(d)
+	defer s.gunfightRedis.ReleaseDuelLease(context.Background(), d.game.ID, s.instanceID)
+
+	s.duelsMu.Lock()
+	delete(s.duels, d.game.ID)
+	s.duelsMu.Unlock()
+
+	d.close()
 }
@@ -3,17 +3,27 @@ package service
 import (
 	"context"
 	"fmt"
+	"github.com/google/uuid"
 	"math"
+	"time"
 	"wildwest/internal/model/horse"
 	"wildwest/internal/repository"
+	"wildwest/pkg/settings"
 )
 
 type horseService struct {
-	horsRepo repository.HorsePostgresRepository
+	horsRepo   repository.HorsePostgresRepository
+	horseRedis repository.HorseRedisRepository
+	cfg        *settings.Config
 }
 
-func NewHorseService(horsRepo repository.HorsePostgresRepository) HorseService {
-	return &horseService{horsRepo: horsRepo}
+func NewHorseService(horsRepo repository.HorsePostgresRepository, horseRedis repository.HorseRedisRepository, cfg *settings.Config) HorseService {
+	return &horseService{horsRepo: horsRepo, horseRedis: horseRedis, cfg: cfg}
+}
+
+// horseSpeed возвращает скорость лошади — дистанцию, которую она пробегает за секунду
+func horseSpeed(level int) int {
+	return level * 20
 }
 
 func (s *horseService) GetHorse(ctx context.Context, userID int) (*horse.BaseResponse, error) {
@@ -22,7 +32,7 @@ func (s *horseService) GetHorse(ctx context.Context, userID int) (*horse.BaseRes
 		return nil, err
 	}
 
-	speed := data.Level * 20
+	speed := horseSpeed(data.Level)
 
 	horseInfo := &horse.BaseResponse{
 		Level:    data.Level,
@@ -82,36 +92,66 @@ func calculateUpgradeCost(level int) (int, error) {
 	return cost, nil
 }
 
-func (s *horseService) GameHorse(ctx context.Context, userID int, gameRequest horse.GameRequest) (*horse.GameResponse, error) {
+// StartRace начинает забег: запоминает в Redis время старта по часам сервера и скорость лошади
+func (s *horseService) StartRace(ctx context.Context, userID int) (*horse.RaceResponse, error) {
 	horseData, err := s.horsRepo.GetHorse(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 
-	moneyData, err := s.horsRepo.GetMoney(ctx, userID)
-	if err != nil {
-		return nil, err
+	race := horse.Race{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Speed:     horseSpeed(horseData.Level),
+		StartedAt: time.Now(),
+	}
+	if err := s.horseRedis.StartRace(ctx, race, s.cfg.Horse.RaceTTL); err != nil {
+		return nil, fmt.Errorf("error starting race: %w", err)
 	}
 
-	earned := gameRequest.Distance
-	moneyData.Silver += earned
+	return &horse.RaceResponse{
+		RaceID:    race.ID,
+		Speed:     race.Speed,
+		StartedAt: race.StartedAt,
+		ExpiresAt: race.StartedAt.Add(s.cfg.Horse.RaceTTL),
+	}, nil
+}
 
-	newRecord := false
-	if gameRequest.Distance > horseData.Distance {
-		newRecord = true
-		horseData.Distance = gameRequest.Distance
+// GameHorse заканчивает забег, начатый StartRace. Сессия забега используется один раз, а дистанция
+// засчитывается не больше, чем лошадь пробежала бы со скоростью на старте за время от старта до финиша
+func (s *horseService) GameHorse(ctx context.Context, userID int, gameRequest horse.GameRequest) (*horse.GameResponse, error) {
+	if gameRequest.Distance < 0 {
+		return nil, fmt.Errorf("distance can not be negative")
 	}
 
-	err = s.horsRepo.Update(ctx, userID, horseData, moneyData)
+	race, err := s.horseRedis.FinishRace(ctx, userID, gameRequest.RaceID)
+	if err != nil {
+		return nil, fmt.Errorf("error finishing race: %w", err)
+	}
+	if race == nil {
+		return nil, fmt.Errorf("race %s not found: it expired or is already finished", gameRequest.RaceID)
+	}
+
+	distance := raceDistance(gameRequest.Distance, race.Speed, time.Since(race.StartedAt), s.cfg.Horse.RaceTTL)
+
+	earned := distance
+	newRecord, err := s.horsRepo.AddRaceResult(ctx, userID, distance, earned)
 	if err != nil {
 		return nil, err
 	}
 
 	GameResponse := &horse.GameResponse{
 		Earned:   earned,
-		Distance: gameRequest.Distance,
+		Distance: distance,
 		Record:   newRecord,
 	}
 
 	return GameResponse, nil
 }
+
+// raceDistance возвращает засчитанную дистанцию забега: не больше, чем лошадь со скоростью speed
+// пробежала бы за elapsed, но не дольше ttl сессии забега. Отрицательные значения дают ноль
+func raceDistance(distance, speed int, elapsed, ttl time.Duration) int {
+	elapsed = min(max(elapsed, 0), ttl)
+	return max(min(distance, int(float64(speed)*elapsed.Seconds())), 0)
+}
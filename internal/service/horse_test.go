@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wildwest/internal/model/horse"
+	"wildwest/internal/repository"
+	"wildwest/pkg/settings"
+)
+
+func TestRaceDistance(t *testing.T) {
+	const ttl = 5 * time.Minute
+
+	tests := []struct {
+		name     string
+		distance int
+		speed    int
+		elapsed  time.Duration
+		want     int
+	}{
+		{name: "within the speed", distance: 150, speed: 20, elapsed: 10 * time.Second, want: 150},
+		{name: "faster than the horse", distance: 1000, speed: 20, elapsed: 10 * time.Second, want: 200},
+		{name: "fraction of a second", distance: 1000, speed: 20, elapsed: 1500 * time.Millisecond, want: 30},
+		{name: "elapsed beyond the TTL", distance: 100000, speed: 20, elapsed: time.Hour, want: 6000},
+		{name: "negative distance", distance: -50, speed: 20, elapsed: 10 * time.Second, want: 0},
+		{name: "clock went back", distance: 100, speed: 20, elapsed: -time.Second, want: 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := raceDistance(test.distance, test.speed, test.elapsed, ttl); got != test.want {
+				t.Fatalf("raceDistance = %d, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+// fakeHorseRedis отдает забег один раз, как FinishRace в Redis
+type fakeHorseRedis struct {
+	repository.HorseRedisRepository
+	race *horse.Race
+}
+
+func (r *fakeHorseRedis) FinishRace(ctx context.Context, userID int, raceID string) (*horse.Race, error) {
+	if r.race == nil || r.race.ID != raceID || r.race.UserID != userID {
+		return nil, nil
+	}
+	race := r.race
+	r.race = nil
+	return race, nil
+}
+
+type fakeHorseRepo struct {
+	repository.HorsePostgresRepository
+	distances []int
+}
+
+func (r *fakeHorseRepo) AddRaceResult(ctx context.Context, userID int, distance int, silver int) (bool, error) {
+	r.distances = append(r.distances, distance)
+	return false, nil
+}
+
+func TestGameHorse(t *testing.T) {
+	cfg := &settings.Config{}
+	cfg.Horse.RaceTTL = 5 * time.Minute
+	race := &horse.Race{ID: "race", UserID: 1, Speed: 20, StartedAt: time.Now().Add(-10 * time.Second)}
+	repo, redis := &fakeHorseRepo{}, &fakeHorseRedis{race: race}
+	s := &horseService{horsRepo: repo, horseRedis: redis, cfg: cfg}
+	ctx := context.Background()
+
+	// Отрицательная дистанция отклоняется и не тратит сессию забега
+	if _, err := s.GameHorse(ctx, 1, horse.GameRequest{RaceID: "race", Distance: -1}); err == nil {
+		t.Fatal("negative distance accepted")
+	}
+
+	response, err := s.GameHorse(ctx, 1, horse.GameRequest{RaceID: "race", Distance: 100000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Distance < 200 || response.Distance > 220 {
+		t.Fatalf("distance = %d, want about 10 seconds at speed 20", response.Distance)
+	}
+
+	if _, err := s.GameHorse(ctx, 1, horse.GameRequest{RaceID: "race", Distance: 100}); err == nil {
+		t.Fatal("reused race ID accepted")
+	}
+	if len(repo.distances) != 1 {
+		t.Fatalf("%d race results recorded, want 1", len(repo.distances))
+	}
+}
@@ -0,0 +1,378 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"time"
+	"wildwest/internal/model/gunfight"
+	"wildwest/pkg/settings"
+)
+
+// partyTTL — сколько живет группа без изменений состава
+const partyTTL = 2 * time.Hour
+
+// InviteToParty приглашает игрока в группу лидера; если лидер еще не в группе, группа создается
+func (s *gunfightService) InviteToParty(ctx context.Context, userID, targetID int) (*gunfight.Party, error) {
+	if targetID == userID {
+		return nil, fmt.Errorf("can not invite yourself")
+	}
+	if _, err := s.userRepo.Get(ctx, targetID); err != nil {
+		return nil, fmt.Errorf("error getting user: %w", err)
+	}
+
+	party, err := s.gunfightRedis.GetPartyOf(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting party: %w", err)
+	}
+	if party == nil {
+		party = &gunfight.Party{ID: uuid.New().String(), LeaderID: userID, Members: []int{userID}}
+		created, err := s.gunfightRedis.CreateParty(ctx, *party, partyTTL)
+		if err != nil {
+			return nil, fmt.Errorf("error creating party: %w", err)
+		}
+		// Группу только что создал другой запрос того же игрока
+		if !created {
+			if party, err = s.gunfightRedis.GetPartyOf(ctx, userID); err != nil || party == nil {
+				return nil, fmt.Errorf("error getting party: %w", err)
+			}
+		}
+	}
+
+	if party.LeaderID != userID {
+		return nil, fmt.Errorf("only the party leader can invite players")
+	}
+	if len(party.Members) >= gunfight.TeamSize {
+		return nil, fmt.Errorf("party is full")
+	}
+
+	if err := s.gunfightRedis.AddPartyInvite(ctx, targetID, party.ID, time.Now().Add(s.cfg.Gunfight.PartyInviteTTL)); err != nil {
+		return nil, fmt.Errorf("error inviting player: %w", err)
+	}
+	return party, nil
+}
+
+// AcceptPartyInvite принимает приглашение в группу
+func (s *gunfightService) AcceptPartyInvite(ctx context.Context, userID int, partyID string) (*gunfight.Party, error) {
+	status, err := s.gunfightRedis.JoinParty(ctx, userID, partyID, gunfight.TeamSize, partyTTL)
+	if err != nil {
+		return nil, fmt.Errorf("error joining party: %w", err)
+	}
+
+	switch status {
+	case gunfight.PartyJoined:
+	case gunfight.PartyInParty:
+		return nil, fmt.Errorf("user %d is already in a party", userID)
+	case gunfight.PartyNoInvite:
+		return nil, fmt.Errorf("invite to party %s not found or expired", partyID)
+	case gunfight.PartyGone:
+		return nil, fmt.Errorf("party %s no longer exists", partyID)
+	case gunfight.PartySearching:
+		return nil, fmt.Errorf("party %s is searching for a gunfight", partyID)
+	case gunfight.PartyFull:
+		return nil, fmt.Errorf("party %s is full", partyID)
+	default:
+		return nil, fmt.Errorf("unexpected party join status %q", status)
+	}
+
+	party, err := s.gunfightRedis.GetParty(ctx, partyID)
+	if err != nil || party == nil {
+		return nil, fmt.Errorf("error getting party: %w", err)
+	}
+	return party, nil
+}
+
+// LeaveParty выводит игрока из группы, уход лидера распускает группу. Пока группа ищет соперников, выйти нельзя
+func (s *gunfightService) LeaveParty(ctx context.Context, userID int) error {
+	status, err := s.gunfightRedis.LeaveParty(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("error leaving party: %w", err)
+	}
+
+	switch status {
+	case gunfight.PartyLeft, gunfight.PartyDisbanded:
+		return nil
+	case gunfight.PartyNotInParty:
+		return fmt.Errorf("user %d is not in a party", userID)
+	case gunfight.PartySearching:
+		return fmt.Errorf("party is searching for a gunfight")
+	}
+	return fmt.Errorf("unexpected party leave status %q", status)
+}
+
+// GetParty возвращает группу игрока и приглашения, которые он получил
+func (s *gunfightService) GetParty(ctx context.Context, userID int) (*gunfight.PartyResponse, error) {
+	party, err := s.gunfightRedis.GetPartyOf(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting party: %w", err)
+	}
+
+	invites, err := s.gunfightRedis.GetPartyInvites(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting party invites: %w", err)
+	}
+
+	response := &gunfight.PartyResponse{Party: party, Invites: make([]gunfight.PartyInvite, 0, len(invites))}
+	for partyID, expiresAt := range invites {
+		invited, err := s.gunfightRedis.GetParty(ctx, partyID)
+		if err != nil {
+			return nil, fmt.Errorf("error getting party: %w", err)
+		}
+		// Группа, в которую пригласили игрока, уже распущена
+		if invited == nil {
+			continue
+		}
+		response.Invites = append(response.Invites, gunfight.PartyInvite{PartyID: partyID, LeaderID: invited.LeaderID, ExpiresAt: expiresAt})
+	}
+	return response, nil
+}
+
+// FindTeamGunfight ищет группе игрока соперников для командной дуэли в очереди queueName. Поиск ведет лидер:
+// он удерживает ставки всех игроков группы и ищет по среднему рейтингу группы, остальные игроки ждут матча,
+// поэтому им нужно подключиться до того, как лидер начнет поиск
+func (s *gunfightService) FindTeamGunfight(ctx context.Context, userID int, queueName string, onSearching func(gunfight.SearchingPayload)) (response gunfight.QueueResponse, err error) {
+	queue, err := s.queue(queueName)
+	if err != nil {
+		return response, err
+	}
+
+	party, err := s.gunfightRedis.GetPartyOf(ctx, userID)
+	if err != nil {
+		return response, fmt.Errorf("error getting party: %w", err)
+	}
+	if party == nil {
+		return response, fmt.Errorf("user %d is not in a party", userID)
+	}
+
+	// Подписываемся до начала поиска, чтобы не пропустить уведомление о матче
+	subCtx, unsubscribe := context.WithCancel(ctx)
+	defer unsubscribe()
+
+	matches, err := s.gunfightRedis.SubscribeMatch(subCtx, userID)
+	if err != nil {
+		return response, fmt.Errorf("error subscribing to match notifications: %w", err)
+	}
+
+	if party.LeaderID != userID {
+		return s.waitForLeader(ctx, queue, matches)
+	}
+
+	if len(party.Members) != gunfight.TeamSize {
+		return response, fmt.Errorf("party needs %d players to search for a team gunfight", gunfight.TeamSize)
+	}
+	if err := s.checkParty(ctx, party, queue); err != nil {
+		return response, err
+	}
+
+	defer func() {
+		s.countSearch(context.WithoutCancel(ctx), queue.Name, response, err)
+	}()
+
+	if err := s.gunfightRedis.SetPartySearching(ctx, party.ID, queue.Timeout+challengeReplyWait); err != nil {
+		return response, fmt.Errorf("error starting party search: %w", err)
+	}
+	defer s.gunfightRedis.ClearPartySearching(context.WithoutCancel(ctx), party.ID)
+
+	if err := s.holdPartyStakes(ctx, party, queue.Stake); err != nil {
+		return response, err
+	}
+
+	rating, err := s.teamRating(ctx, party.Members)
+	if err != nil {
+		s.releasePartySearch(context.WithoutCancel(ctx), party)
+		return response, fmt.Errorf("error getting rating: %w", err)
+	}
+
+	band := searchBand(rating, queue.Bands[0])
+	onSearching(gunfight.SearchingPayload{Band: band})
+
+	opponentPartyID, err := s.gunfightRedis.MatchTeamOrEnqueue(ctx, party.ID, queue.Name, rating, band)
+	if err != nil {
+		s.releasePartySearch(context.WithoutCancel(ctx), party)
+		return response, fmt.Errorf("error finding opponents: %w", err)
+	}
+	if opponentPartyID != "" {
+		return s.handleFoundParty(ctx, party, opponentPartyID, queue)
+	}
+
+	return s.waitForParty(ctx, party, queue, rating, matches, onSearching)
+}
+
+// checkParty проверяет, что каждый игрок группы может искать соперников в очереди
+func (s *gunfightService) checkParty(ctx context.Context, party *gunfight.Party, queue settings.GunfightQueue) error {
+	for _, memberID := range party.Members {
+		cooldown, err := s.gunfightRedis.GetCooldown(ctx, memberID)
+		if err != nil {
+			return fmt.Errorf("error getting matchmaking cooldown: %w", err)
+		}
+		if cooldown > 0 {
+			return fmt.Errorf("matchmaking is locked for player %d for %s after leaving a gunfight", memberID, cooldown.Round(time.Second))
+		}
+
+		if queue.MinBalance > 0 {
+			balance, err := s.moneyRepo.Get(ctx, memberID)
+			if err != nil {
+				return fmt.Errorf("error getting balance: %w", err)
+			}
+			if balance.Gold < queue.MinBalance {
+				return fmt.Errorf("queue %s requires a balance of at least %d gold from player %d", queue.Name, queue.MinBalance, memberID)
+			}
+		}
+	}
+	return nil
+}
+
+// holdPartyStakes удерживает ставку с каждого игрока группы, если кто-то не может ее покрыть, ставки возвращаются
+func (s *gunfightService) holdPartyStakes(ctx context.Context, party *gunfight.Party, stake int) error {
+	if stake == 0 {
+		return nil
+	}
+
+	for i, memberID := range party.Members {
+		if err := s.gunfightRepo.HoldStake(ctx, memberID, stake); err != nil {
+			for _, heldID := range party.Members[:i] {
+				s.gunfightRepo.ReleaseStake(context.WithoutCancel(ctx), heldID)
+			}
+			return fmt.Errorf("player %d can not cover the stake: %w", memberID, err)
+		}
+	}
+	return nil
+}
+
+// releasePartySearch убирает группу из очереди, возвращает ставки игрокам, для которых игра так и не была создана,
+// и сообщает остальным игрокам группы, что поиск закончился
+func (s *gunfightService) releasePartySearch(ctx context.Context, party *gunfight.Party) {
+	s.gunfightRedis.RemovePartyFromQueue(ctx, party.ID)
+	for _, memberID := range party.Members {
+		s.gunfightRepo.ReleaseStake(ctx, memberID)
+		if memberID != party.LeaderID {
+			s.gunfightRedis.PublishMatch(ctx, memberID, gunfight.Match{})
+		}
+	}
+}
+
+// handleFoundParty создает командную игру: группа лидера — первая команда, группа соперников — вторая.
+// Уведомление о матче получают все игроки, кроме самого лидера
+func (s *gunfightService) handleFoundParty(ctx context.Context, party *gunfight.Party, opponentPartyID string, queue settings.GunfightQueue) (gunfight.QueueResponse, error) {
+	var response gunfight.QueueResponse
+
+	opponents, err := s.gunfightRedis.GetParty(ctx, opponentPartyID)
+	if err != nil || opponents == nil {
+		s.releasePartySearch(context.WithoutCancel(ctx), party)
+		return response, fmt.Errorf("error getting opponent party: %w", err)
+	}
+
+	game := &gunfight.Game{
+		User1ID:  party.LeaderID,
+		User2ID:  opponents.LeaderID,
+		Stake:    queue.Stake,
+		Ranked:   queue.Ranked,
+		Queue:    queue.Name,
+		TeamSize: gunfight.TeamSize,
+	}
+	for _, memberID := range party.Members {
+		game.Players = append(game.Players, gunfight.Player{UserID: memberID, Team: 1})
+	}
+	for _, memberID := range opponents.Members {
+		game.Players = append(game.Players, gunfight.Player{UserID: memberID, Team: 2})
+	}
+
+	gunfightID, err := s.gunfightRepo.Create(ctx, game)
+	if err != nil {
+		s.releasePartySearch(context.WithoutCancel(ctx), party)
+		return response, fmt.Errorf("error creating gunfight: %w", err)
+	}
+
+	for _, memberID := range party.Members {
+		if memberID == party.LeaderID {
+			continue
+		}
+		match := gunfight.Match{GunfightID: gunfightID, OpponentID: opponents.LeaderID, Teammates: party.Members, Opponents: opponents.Members}
+		if err := s.gunfightRedis.PublishMatch(ctx, memberID, match); err != nil {
+			return response, fmt.Errorf("error notifying teammate: %w", err)
+		}
+	}
+	for _, memberID := range opponents.Members {
+		match := gunfight.Match{GunfightID: gunfightID, OpponentID: party.LeaderID, Teammates: opponents.Members, Opponents: party.Members}
+		if err := s.gunfightRedis.PublishMatch(ctx, memberID, match); err != nil {
+			return response, fmt.Errorf("error notifying opponents: %w", err)
+		}
+	}
+
+	response = gunfight.QueueResponse{
+		OpponentID: opponents.LeaderID,
+		GunfightID: gunfightID,
+		Teammates:  party.Members,
+		Opponents:  opponents.Members,
+	}
+	return response, nil
+}
+
+// waitForParty ждет, пока группу заберут другие игроки, и на каждом шаге сама повторяет поиск,
+// расширяя диапазон рейтинга по расписанию очереди. Ботов в командных дуэлях нет
+func (s *gunfightService) waitForParty(ctx context.Context, party *gunfight.Party, queue settings.GunfightQueue, rating int, matches <-chan gunfight.Match, onSearching func(gunfight.SearchingPayload)) (gunfight.QueueResponse, error) {
+	timer := time.NewTimer(queue.Timeout)
+	defer timer.Stop()
+
+	ticker := time.NewTicker(queue.BandStep)
+	defer ticker.Stop()
+
+	step, expired := 0, false
+	for {
+		select {
+		case match, ok := <-matches:
+			if !ok {
+				s.releasePartySearch(context.WithoutCancel(ctx), party)
+				return gunfight.QueueResponse{}, fmt.Errorf("match notifications closed")
+			}
+			return gunfight.QueueResponse{OpponentID: match.OpponentID, GunfightID: match.GunfightID, Teammates: match.Teammates, Opponents: match.Opponents}, nil
+		case <-ticker.C:
+			if step < len(queue.Bands)-1 {
+				step++
+				onSearching(gunfight.SearchingPayload{Band: searchBand(rating, queue.Bands[step])})
+			}
+
+			opponentPartyID, err := s.gunfightRedis.MatchTeamWaiting(ctx, party.ID, queue.Name, rating, searchBand(rating, queue.Bands[step]))
+			if err != nil {
+				s.releasePartySearch(context.WithoutCancel(ctx), party)
+				return gunfight.QueueResponse{}, fmt.Errorf("error finding opponents: %w", err)
+			}
+			if opponentPartyID != "" {
+				return s.handleFoundParty(ctx, party, opponentPartyID, queue)
+			}
+		case <-timer.C:
+			// Группу только что забрали соперники, уведомление о матче вот-вот придет
+			if removed, err := s.gunfightRedis.RemovePartyFromQueue(ctx, party.ID); err == nil && !removed && !expired {
+				expired = true
+				timer.Reset(challengeReplyWait)
+				continue
+			}
+			s.releasePartySearch(context.WithoutCancel(ctx), party)
+			return gunfight.QueueResponse{Message: "No opponents found within the time limit"}, nil
+		case <-ctx.Done():
+			s.releasePartySearch(context.WithoutCancel(ctx), party)
+			return gunfight.QueueResponse{}, ctx.Err()
+		}
+	}
+}
+
+// waitForLeader ждет матча, который найдет лидер группы; пустой матч означает, что лидер закончил поиск без игры
+func (s *gunfightService) waitForLeader(ctx context.Context, queue settings.GunfightQueue, matches <-chan gunfight.Match) (gunfight.QueueResponse, error) {
+	timer := time.NewTimer(queue.Timeout + challengeReplyWait)
+	defer timer.Stop()
+
+	select {
+	case match, ok := <-matches:
+		if !ok {
+			return gunfight.QueueResponse{}, fmt.Errorf("match notifications closed")
+		}
+		if match.GunfightID == 0 {
+			return gunfight.QueueResponse{Message: "The party leader stopped the search"}, nil
+		}
+		return gunfight.QueueResponse{OpponentID: match.OpponentID, GunfightID: match.GunfightID, Teammates: match.Teammates, Opponents: match.Opponents}, nil
+	case <-timer.C:
+		return gunfight.QueueResponse{Message: "No opponents found within the time limit"}, nil
+	case <-ctx.Done():
+		return gunfight.QueueResponse{}, ctx.Err()
+	}
+}
@@ -0,0 +1,24 @@
+package service
+
+import (
+	"math"
+	"wildwest/internal/model/gunfight"
+)
+
+// eloK определяет, насколько сильно одна игра меняет рейтинг
+const eloK = 32
+
+// eloDelta возвращает изменение рейтинга победителя по Эло, проигравший теряет столько же
+func eloDelta(winnerRating, loserRating int) int {
+	expected := 1 / (1 + math.Pow(10, float64(loserRating-winnerRating)/400))
+	return int(math.Round(eloK * (1 - expected)))
+}
+
+// searchBand возвращает диапазон рейтинга ±percent процентов, в котором ищется соперник
+func searchBand(rating, percent int) gunfight.Band {
+	return gunfight.Band{
+		Percent:   percent,
+		MinRating: rating * (100 - percent) / 100,
+		MaxRating: rating * (100 + percent) / 100,
+	}
+}
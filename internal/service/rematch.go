@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"wildwest/internal/model/gunfight"
+)
+
+// Rematch ведет голосование за реванш после завершенной дуэли, пока не закончится окно реванша.
+// onOffer вызывается при подключении и когда соперник просит реванш, голоса игрока приходят из votes:
+// true — игрок просит реванш или соглашается на него, false — отказывается. Возвращает исход голосования
+func (s *gunfightService) Rematch(ctx context.Context, gunfightID, userID int, votes <-chan bool, onOffer func(gunfight.RematchOffer)) (gunfight.RematchReply, error) {
+	reply := gunfight.RematchReply{GunfightID: gunfightID, Status: gunfight.RematchExpired}
+
+	game, err := s.gunfightRepo.Get(ctx, gunfightID)
+	if err != nil {
+		return reply, fmt.Errorf("error getting gunfight: %w", err)
+	}
+	if game.User1ID != userID && game.User2ID != userID {
+		return reply, fmt.Errorf("user %d is not a participant of gunfight %d", userID, gunfightID)
+	}
+	if game.TeamSize > 1 {
+		return reply, fmt.Errorf("rematch is not available for team gunfights")
+	}
+	if game.EndDate == nil {
+		return reply, fmt.Errorf("gunfight %d is not finished", gunfightID)
+	}
+	if game.Bot != "" {
+		return reply, fmt.Errorf("rematch is not available against a bot")
+	}
+
+	deadline := game.EndDate.Add(s.cfg.Gunfight.RematchWindow)
+	if !time.Now().Before(deadline) {
+		return reply, nil
+	}
+
+	// Подписываемся до чтения голосов, чтобы не пропустить ответ соперника
+	subCtx, unsubscribe := context.WithCancel(ctx)
+	defer unsubscribe()
+
+	replies, err := s.gunfightRedis.SubscribeRematch(subCtx, gunfightID)
+	if err != nil {
+		return reply, fmt.Errorf("error subscribing to rematch: %w", err)
+	}
+
+	cast, status, err := s.gunfightRedis.GetRematchVotes(ctx, gunfightID)
+	if err != nil {
+		return reply, fmt.Errorf("error getting rematch votes: %w", err)
+	}
+	if status != "" {
+		return s.rematchOutcome(ctx, gunfightID, status)
+	}
+
+	offer := gunfight.RematchOffer{GunfightID: gunfightID, OpponentID: game.User1ID, Stake: game.Stake, ExpiresAt: deadline}
+	if offer.OpponentID == userID {
+		offer.OpponentID = game.User2ID
+	}
+	offer.Requested = cast[offer.OpponentID] == gunfight.RematchAccepted
+	onOffer(offer)
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+
+	expired := false
+	for {
+		select {
+		case accept, ok := <-votes:
+			if !ok {
+				votes = nil
+				continue
+			}
+			vote := gunfight.RematchDeclined
+			if accept {
+				vote = gunfight.RematchAccepted
+			}
+			if err := s.castRematch(ctx, game, userID, vote, deadline); err != nil {
+				return reply, err
+			}
+		case published, ok := <-replies:
+			if !ok {
+				return reply, fmt.Errorf("rematch replies closed")
+			}
+			if published.Status != gunfight.RematchRequested {
+				return published, nil
+			}
+			if published.UserID == offer.OpponentID && !offer.Requested {
+				offer.Requested = true
+				onOffer(offer)
+			}
+		case <-timer.C:
+			if expired {
+				return reply, nil
+			}
+
+			// Закрываем голосование; если исход уже решен, ответ вот-вот придет
+			if err := s.castRematch(ctx, game, userID, gunfight.RematchExpired, deadline); err != nil {
+				return reply, err
+			}
+			expired = true
+			timer.Reset(challengeReplyWait)
+		case <-ctx.Done():
+			return reply, ctx.Err()
+		}
+	}
+}
+
+// castRematch записывает голос игрока. Голос, который решил исход, рассылается обоим игрокам,
+// а если оба согласились, сначала создается игра-реванш
+func (s *gunfightService) castRematch(ctx context.Context, game *gunfight.Game, userID int, vote string, deadline time.Time) error {
+	status, decided, err := s.gunfightRedis.VoteRematch(ctx, game.ID, userID, vote, deadline.Add(challengeReplyWait))
+	if err != nil {
+		return fmt.Errorf("error voting for rematch: %w", err)
+	}
+	if !decided && status != gunfight.RematchRequested {
+		return nil
+	}
+
+	reply := gunfight.RematchReply{GunfightID: game.ID, Status: status}
+	switch status {
+	case gunfight.RematchRequested, gunfight.RematchDeclined:
+		reply.UserID = userID
+	case gunfight.RematchAccepted:
+		rematchID, err := s.createRematch(ctx, game)
+		if err != nil {
+			reply.Status = gunfight.RematchDeclined
+			reply.Message = err.Error()
+			break
+		}
+		reply.RematchID = rematchID
+	}
+
+	if err := s.gunfightRedis.PublishRematch(ctx, reply); err != nil {
+		return fmt.Errorf("error notifying players: %w", err)
+	}
+	return nil
+}
+
+// createRematch удерживает ставки обоих игроков и создает реванш с той же ставкой, минуя очередь поиска.
+// Реванш, как и вызов, не рейтинговый: иначе двое договорившихся игроков накручивали бы рейтинг друг о друга
+func (s *gunfightService) createRematch(ctx context.Context, game *gunfight.Game) (int, error) {
+	rematch := &gunfight.Game{
+		User1ID:   game.User1ID,
+		User2ID:   game.User2ID,
+		Stake:     game.Stake,
+		Queue:     game.Queue,
+		RematchOf: &game.ID,
+	}
+	players := []int{game.User1ID, game.User2ID}
+
+	if rematch.Stake > 0 {
+		for i, userID := range players {
+			if err := s.gunfightRepo.HoldStake(ctx, userID, rematch.Stake); err != nil {
+				for _, heldID := range players[:i] {
+					s.gunfightRepo.ReleaseStake(context.WithoutCancel(ctx), heldID)
+				}
+				return 0, fmt.Errorf("player %d can not cover the stake: %w", userID, err)
+			}
+		}
+	}
+
+	rematchID, err := s.gunfightRepo.Create(ctx, rematch)
+	if err != nil {
+		if rematch.Stake > 0 {
+			for _, userID := range players {
+				s.gunfightRepo.ReleaseStake(context.WithoutCancel(ctx), userID)
+			}
+		}
+		return 0, fmt.Errorf("error creating gunfight: %w", err)
+	}
+	return rematchID, nil
+}
+
+// rematchOutcome восстанавливает исход уже закрытого голосования для игрока, который подключился позже
+func (s *gunfightService) rematchOutcome(ctx context.Context, gunfightID int, status string) (gunfight.RematchReply, error) {
+	reply := gunfight.RematchReply{GunfightID: gunfightID, Status: status}
+	if status != gunfight.RematchAccepted {
+		return reply, nil
+	}
+
+	// Оба согласились, но игру создать не удалось
+	rematch, err := s.gunfightRepo.GetRematch(ctx, gunfightID)
+	if err != nil {
+		reply.Status = gunfight.RematchDeclined
+		return reply, nil
+	}
+	reply.RematchID = rematch.ID
+	return reply, nil
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+	"wildwest/internal/model/gunfight"
+)
+
+// ReplayDuel прогоняет журнал дуэли через правила движка и возвращает победителя, nil — ничья или отмена.
+// Для командной игры в game.Players должен быть состав команд, победитель — капитан команды.
+// Здоровье после каждого раунда сверяется с записанным, расхождение возвращается ошибкой
+func ReplayDuel(game *gunfight.Game, events []gunfight.Event) (*int, error) {
+	players := []int{game.User1ID, game.User2ID}
+	_, sides := teamSides(game)
+	health := map[int]int{players[0]: duelHealth * teamSize(game), players[1]: duelHealth * teamSize(game)}
+
+	var round drawRound
+	var drawAt *time.Time
+	for _, event := range events {
+		switch event.Type {
+		case gunfight.DuelEventRound:
+			round = drawRound{reactions: make(map[int]time.Duration, 2)}
+			drawAt = nil
+		case gunfight.DuelEventDraw:
+			at := event.CreatedAt
+			drawAt = &at
+		case gunfight.EventShot:
+			if event.UserID == nil {
+				return nil, fmt.Errorf("shot %d has no user", event.ID)
+			}
+			move := duelMove{userID: *event.UserID, at: event.CreatedAt, rtt: time.Duration(event.RTT) * time.Microsecond}
+			if drawAt == nil || move.at.Before(*drawAt) {
+				round.foul = move.userID
+			} else if _, ok := round.reactions[move.userID]; !ok {
+				round.reactions[move.userID] = reaction(*drawAt, move)
+			}
+		case gunfight.DuelEventResult:
+			applyHits(players, health, resolveDraw(players, bySide(round, sides)))
+
+			recorded, err := decodeDuelEvent(event)
+			if err != nil {
+				return nil, err
+			}
+			for _, userID := range players {
+				if recorded.Health[userID] != health[userID] {
+					return nil, fmt.Errorf("round %d: recorded health %v, replayed %v", event.Round, recorded.Health, health)
+				}
+			}
+		case gunfight.EventForfeit:
+			if event.UserID == nil {
+				return nil, fmt.Errorf("forfeit %d has no user", event.ID)
+			}
+			winnerID := players[0]
+			if winnerID == sides[*event.UserID] {
+				winnerID = players[1]
+			}
+			return &winnerID, nil
+		case gunfight.DuelEventCancelled:
+			return nil, nil
+		case gunfight.DuelEventFinished:
+			return duelWinner(players, health), nil
+		}
+	}
+
+	return nil, fmt.Errorf("gunfight %d log has no finished event", game.ID)
+}
+
+func decodeDuelEvent(event gunfight.Event) (gunfight.DuelEvent, error) {
+	var duelEvent gunfight.DuelEvent
+	if err := json.Unmarshal(event.Payload, &duelEvent); err != nil {
+		return duelEvent, fmt.Errorf("error decoding %s event %d: %w", event.Type, event.ID, err)
+	}
+	return duelEvent, nil
+}
@@ -9,13 +9,43 @@ import (
 )
 
 type GunfightService interface {
-	FindGunfight(ctx context.Context, userID int) (gunfight.QueueResponse, error)
+	FindGunfight(ctx context.Context, userID int, queue string, onSearching func(gunfight.SearchingPayload), onQueue func(gunfight.QueueStatus)) (gunfight.QueueResponse, error)
+	QueueStatus(ctx context.Context, userID int, queue string) (*gunfight.QueueStatus, error)
+	GetQueueMetrics(ctx context.Context) ([]gunfight.QueueMetrics, error)
 	RemovePlayerFromQueue(ctx context.Context, userID int) error
+	InviteToParty(ctx context.Context, userID, targetID int) (*gunfight.Party, error)
+	AcceptPartyInvite(ctx context.Context, userID int, partyID string) (*gunfight.Party, error)
+	LeaveParty(ctx context.Context, userID int) error
+	GetParty(ctx context.Context, userID int) (*gunfight.PartyResponse, error)
+	FindTeamGunfight(ctx context.Context, userID int, queue string, onSearching func(gunfight.SearchingPayload)) (gunfight.QueueResponse, error)
+	Challenge(ctx context.Context, userID int, target gunfight.ChallengeTarget, onSent func(gunfight.Challenge)) (gunfight.ChallengeReply, error)
+	ListenChallenges(ctx context.Context, userID int, onChallenge func(gunfight.Challenge)) error
+	RespondChallenge(ctx context.Context, userID int, challengeID string, accept bool) (gunfight.ChallengeReply, error)
+	JoinGunfight(ctx context.Context, gunfightID, userID int, lastSeq int64, clientSeed string) (*DuelSeat, error)
+	Rematch(ctx context.Context, gunfightID, userID int, votes <-chan bool, onOffer func(gunfight.RematchOffer)) (gunfight.RematchReply, error)
+	WatchGunfight(ctx context.Context, gunfightID int) (<-chan gunfight.DuelEvent, error)
+	GetReplay(ctx context.Context, gunfightID, userID int) (*gunfight.ReplayResponse, error)
+	GetFairness(ctx context.Context, gunfightID int) (*gunfight.FairnessResponse, error)
+	GetRating(ctx context.Context, userID int) (*gunfight.RatingResponse, error)
+	GetHistory(ctx context.Context, userID int, cursor int, limit int) (*gunfight.HistoryResponse, error)
+	GetStats(ctx context.Context, userID int) (*gunfight.StatsResponse, error)
+	SweepStaleGames(ctx context.Context) (int, error)
+	PlaceBet(ctx context.Context, userID, gunfightID, side, silver int) (*gunfight.BetItem, error)
+	GetBetPool(ctx context.Context, userID, gunfightID int) (*gunfight.BetPoolResponse, error)
+	PostBounty(ctx context.Context, userID, targetID, gold int) (*gunfight.BountyItem, error)
+	GetWanted(ctx context.Context) ([]gunfight.BountyItem, error)
+	ExpireBounties(ctx context.Context) (int, error)
+	GetTournaments(ctx context.Context) ([]gunfight.TournamentItem, error)
+	GetBracket(ctx context.Context, tournamentID int) (*gunfight.BracketResponse, error)
+	RegisterTournament(ctx context.Context, tournamentID, userID int) error
+	UnregisterTournament(ctx context.Context, tournamentID, userID int) error
+	AdvanceTournaments(ctx context.Context) error
 }
 
 type HorseService interface {
 	GetHorse(ctx context.Context, userID int) (*horse.BaseResponse, error)
 	UpgradeHorse(ctx context.Context, userID int) (int, error)
+	StartRace(ctx context.Context, userID int) (*horse.RaceResponse, error)
 	GameHorse(ctx context.Context, userID int, gameRequest horse.GameRequest) (*horse.GameResponse, error)
 }
 
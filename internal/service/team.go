@@ -0,0 +1,55 @@
+package service
+
+import (
+	"time"
+	"wildwest/internal/model/gunfight"
+)
+
+// teamSize возвращает число игроков в команде, у игр до командных дуэлей размер команды не записан
+func teamSize(game *gunfight.Game) int {
+	return max(game.TeamSize, 1)
+}
+
+// teamSides возвращает всех игроков дуэли и сторону каждого из них. Сторона — капитан команды:
+// user_1_id для первой команды и user_2_id для второй, в дуэли один на один сторона — сам игрок
+func teamSides(game *gunfight.Game) ([]int, map[int]int) {
+	if len(game.Players) == 0 {
+		return []int{game.User1ID, game.User2ID}, map[int]int{game.User1ID: game.User1ID, game.User2ID: game.User2ID}
+	}
+
+	captains := map[int]int{1: game.User1ID, 2: game.User2ID}
+	members := make([]int, 0, len(game.Players))
+	sides := make(map[int]int, len(game.Players))
+	for _, player := range game.Players {
+		members = append(members, player.UserID)
+		sides[player.UserID] = captains[player.Team]
+	}
+	return members, sides
+}
+
+// teamMembers возвращает игроков стороны captain
+func teamMembers(members []int, sides map[int]int, captain int) []int {
+	var team []int
+	for _, userID := range members {
+		if sides[userID] == captain {
+			team = append(team, userID)
+		}
+	}
+	return team
+}
+
+// bySide сводит итог раунда к сторонам дуэли: фальстарт стоит попадания всей команде нарушителя,
+// а время реакции команды — лучшее время ее игроков
+func bySide(round drawRound, sides map[int]int) drawRound {
+	result := drawRound{forfeited: round.forfeited, reactions: make(map[int]time.Duration, 2)}
+	if round.foul != 0 {
+		result.foul = sides[round.foul]
+	}
+	for userID, value := range round.reactions {
+		side := sides[userID]
+		if best, ok := result.reactions[side]; !ok || value < best {
+			result.reactions[side] = value
+		}
+	}
+	return result
+}
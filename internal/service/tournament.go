@@ -0,0 +1,383 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"math/bits"
+	"slices"
+	"time"
+	"wildwest/internal/model/gunfight"
+)
+
+const (
+	tournamentListLimit = 50
+
+	// tournamentMatchWait — сколько ждать игроков матча турнира: если за это время дуэль так и не началась,
+	// из матча никто не проходит дальше
+	tournamentMatchWait = 5 * time.Minute
+)
+
+// GetTournaments возвращает турниры, на которые открыта регистрация, и идущие турниры
+func (s *gunfightService) GetTournaments(ctx context.Context) ([]gunfight.TournamentItem, error) {
+	tournaments, err := s.gunfightRepo.GetTournaments(ctx, []string{gunfight.TournamentRegistration, gunfight.TournamentRunning}, tournamentListLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]gunfight.TournamentItem, 0, len(tournaments))
+	for _, tournament := range tournaments {
+		items = append(items, tournamentItem(tournament))
+	}
+	return items, nil
+}
+
+// GetBracket возвращает турнир, его участников и матчи всех начатых раундов
+func (s *gunfightService) GetBracket(ctx context.Context, tournamentID int) (*gunfight.BracketResponse, error) {
+	tournament, err := s.gunfightRepo.GetTournament(ctx, tournamentID)
+	if err != nil {
+		return nil, err
+	}
+
+	players, err := s.gunfightRepo.GetTournamentPlayers(ctx, tournamentID)
+	if err != nil {
+		return nil, err
+	}
+
+	matches, err := s.gunfightRepo.GetTournamentMatches(ctx, tournamentID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &gunfight.BracketResponse{
+		Tournament: tournamentItem(*tournament),
+		Players:    make([]gunfight.TournamentPlayerItem, 0, len(players)),
+		Rounds:     make([][]gunfight.TournamentMatchItem, tournament.Round),
+	}
+	for _, player := range players {
+		response.Players = append(response.Players, gunfight.TournamentPlayerItem{
+			UserID: player.UserID,
+			Seed:   player.Seed,
+			Rating: player.Rating,
+			Prize:  player.Prize,
+		})
+	}
+	for _, match := range matches {
+		if match.Round < 1 || match.Round > len(response.Rounds) {
+			continue
+		}
+		response.Rounds[match.Round-1] = append(response.Rounds[match.Round-1], gunfight.TournamentMatchItem{
+			Position:   match.Position,
+			User1ID:    match.User1ID,
+			User2ID:    match.User2ID,
+			GunfightID: match.GunfightID,
+			WinnerID:   match.WinnerID,
+			Walkover:   match.Walkover,
+			Decided:    match.Decided,
+		})
+	}
+	return response, nil
+}
+
+// RegisterTournament регистрирует игрока на турнир, взнос списывается сразу
+func (s *gunfightService) RegisterTournament(ctx context.Context, tournamentID, userID int) error {
+	if err := s.gunfightRepo.RegisterTournament(ctx, tournamentID, userID); err != nil {
+		return fmt.Errorf("error registering for tournament %d: %w", tournamentID, err)
+	}
+	return nil
+}
+
+// UnregisterTournament снимает игрока с турнира до старта и возвращает взнос
+func (s *gunfightService) UnregisterTournament(ctx context.Context, tournamentID, userID int) error {
+	if err := s.gunfightRepo.UnregisterTournament(ctx, tournamentID, userID); err != nil {
+		return fmt.Errorf("error leaving tournament %d: %w", tournamentID, err)
+	}
+	return nil
+}
+
+// AdvanceTournaments — шаг планировщика турниров: создает турнир на следующее время старта по расписанию,
+// начинает турниры, время старта которых пришло, и переводит идущие турниры в следующий раунд
+func (s *gunfightService) AdvanceTournaments(ctx context.Context) error {
+	cfg := s.cfg.Gunfight.Tournament
+	if cfg.Interval > 0 {
+		tournament := &gunfight.Tournament{
+			Size:      cfg.Size,
+			EntryFee:  cfg.EntryFee,
+			Status:    gunfight.TournamentRegistration,
+			StartDate: time.Now().Truncate(cfg.Interval).Add(cfg.Interval),
+		}
+		if _, err := s.gunfightRepo.CreateTournament(ctx, tournament); err != nil {
+			return fmt.Errorf("error scheduling tournament: %w", err)
+		}
+	}
+
+	tournaments, err := s.gunfightRepo.GetTournaments(ctx, []string{gunfight.TournamentRegistration, gunfight.TournamentRunning}, tournamentListLimit)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, tournament := range tournaments {
+		switch {
+		case tournament.Status == gunfight.TournamentRunning:
+			err = s.advanceTournament(ctx, &tournament)
+		case !tournament.StartDate.After(time.Now()):
+			err = s.startTournament(ctx, &tournament)
+		default:
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("tournament %d: %w", tournament.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// startTournament сеет игроков по рейтингу и начинает первый раунд; турнир меньше чем на двух игроков отменяется
+func (s *gunfightService) startTournament(ctx context.Context, tournament *gunfight.Tournament) error {
+	players, err := s.gunfightRepo.GetTournamentPlayers(ctx, tournament.ID)
+	if err != nil {
+		return err
+	}
+	if len(players) < 2 {
+		return s.gunfightRepo.CancelTournament(ctx, tournament.ID)
+	}
+
+	for i := range players {
+		rating, err := s.gunfightRepo.GetRating(ctx, players[i].UserID)
+		if err != nil {
+			return fmt.Errorf("error getting rating: %w", err)
+		}
+		players[i].Rating = &rating.Rating
+	}
+
+	// При равном рейтинге выше посев у того, кто раньше зарегистрировался
+	slices.SortStableFunc(players, func(a, b gunfight.TournamentPlayer) int {
+		return cmp.Compare(*b.Rating, *a.Rating)
+	})
+	for i := range players {
+		seed := i + 1
+		players[i].Seed = &seed
+	}
+
+	slots := bracketSeeds(tournament.Size)
+	matches := make([]gunfight.TournamentMatch, 0, len(slots)/2)
+	for position := 0; position < len(slots)/2; position++ {
+		matches = append(matches, tournamentMatch(tournament.ID, 1, position,
+			seededPlayer(players, slots[2*position]), seededPlayer(players, slots[2*position+1])))
+	}
+
+	_, err = s.gunfightRepo.StartTournamentRound(ctx, tournament.ID, 1, players, matches)
+	return err
+}
+
+// advanceTournament записывает итоги сыгранных матчей текущего раунда и, когда решены все матчи,
+// начинает следующий раунд или завершает турнир
+func (s *gunfightService) advanceTournament(ctx context.Context, tournament *gunfight.Tournament) error {
+	players, err := s.gunfightRepo.GetTournamentPlayers(ctx, tournament.ID)
+	if err != nil {
+		return err
+	}
+	seeds := make(map[int]int, len(players))
+	for _, player := range players {
+		if player.Seed != nil {
+			seeds[player.UserID] = *player.Seed
+		}
+	}
+
+	matches, err := s.gunfightRepo.GetTournamentMatches(ctx, tournament.ID)
+	if err != nil {
+		return err
+	}
+
+	// Матчи отсортированы по раунду и позиции, поэтому матчи текущего раунда идут подряд
+	first := slices.IndexFunc(matches, func(match gunfight.TournamentMatch) bool { return match.Round == tournament.Round })
+	if first < 0 {
+		return nil
+	}
+	last := first
+	for last < len(matches) && matches[last].Round == tournament.Round {
+		last++
+	}
+	round := matches[first:last]
+
+	decided := true
+	for i := range round {
+		if !round[i].Decided {
+			if err := s.decideTournamentMatch(ctx, &round[i], seeds); err != nil {
+				return err
+			}
+		}
+		decided = decided && round[i].Decided
+	}
+	if !decided {
+		return nil
+	}
+
+	rounds := bits.Len(uint(tournament.Size)) - 1
+	if tournament.Round < rounds {
+		next := make([]gunfight.TournamentMatch, 0, len(round)/2)
+		for position := 0; position < len(round)/2; position++ {
+			next = append(next, tournamentMatch(tournament.ID, tournament.Round+1, position,
+				round[2*position].WinnerID, round[2*position+1].WinnerID))
+		}
+		_, err := s.gunfightRepo.StartTournamentRound(ctx, tournament.ID, tournament.Round+1, nil, next)
+		return err
+	}
+
+	return s.gunfightRepo.FinishTournament(ctx, tournament.ID, round[0].WinnerID, s.tournamentPrizes(tournament, len(players), matches, rounds))
+}
+
+// decideTournamentMatch записывает итог законченной игры матча. Ничья или прерванная дуэль отдают матч
+// игроку с более высоким посевом. Если дуэль так и не началась, потому что на нее никто не пришел или
+// за tournamentMatchWait ее никто не запустил, дальше не проходит никто
+func (s *gunfightService) decideTournamentMatch(ctx context.Context, match *gunfight.TournamentMatch, seeds map[int]int) error {
+	if match.GunfightID == nil {
+		return nil
+	}
+
+	game, err := s.gunfightRepo.Get(ctx, *match.GunfightID)
+	if err != nil {
+		return fmt.Errorf("error getting gunfight: %w", err)
+	}
+
+	if game.EndDate == nil {
+		if time.Since(game.StartDate) < tournamentMatchWait {
+			return nil
+		}
+
+		if running, err := s.duelRunning(ctx, game.ID); err != nil || running {
+			return err
+		}
+		if state, err := s.gunfightRedis.GetDuelState(ctx, game.ID); err != nil || state != nil {
+			return err
+		}
+
+		// Игру мог уже закрыть другой инстанс, тогда итог запишется на следующем шаге
+		if err := s.gunfightRepo.Finish(ctx, game.ID, &gunfight.Result{}); err != nil {
+			return nil
+		}
+		if err := s.gunfightRepo.DecideTournamentMatch(ctx, match.ID, nil, true); err != nil {
+			return err
+		}
+		match.Decided, match.Walkover = true, true
+		return nil
+	}
+
+	// Ставки закрываются, когда за дуэль сели оба игрока: игра без победителя с открытыми ставками
+	// закончилась неявкой обоих
+	if game.WinnerID == nil && !game.BetsClosed {
+		if err := s.gunfightRepo.DecideTournamentMatch(ctx, match.ID, nil, true); err != nil {
+			return err
+		}
+		match.Decided, match.Walkover = true, true
+		return nil
+	}
+
+	winnerID := game.WinnerID
+	if winnerID == nil {
+		winnerID = &game.User1ID
+		if seeds[game.User2ID] < seeds[game.User1ID] {
+			winnerID = &game.User2ID
+		}
+	}
+	if err := s.gunfightRepo.DecideTournamentMatch(ctx, match.ID, winnerID, false); err != nil {
+		return err
+	}
+	match.Decided, match.WinnerID = true, winnerID
+	return nil
+}
+
+// tournamentPrizes делит банк за вычетом комиссии заведения по долям из настроек: первая доля — победителю,
+// вторая — проигравшему в финале, дальше поровну между проигравшими в полуфинале и так далее.
+// Доля места, которое никто не занял, и остаток от деления остаются заведению
+func (s *gunfightService) tournamentPrizes(tournament *gunfight.Tournament, players int, matches []gunfight.TournamentMatch, rounds int) map[int]int {
+	pot := tournament.EntryFee * players
+	pot -= pot * s.cfg.Gunfight.HouseFee / 100
+
+	places := make(map[int][]int)
+	for _, match := range matches {
+		if !match.Decided || match.WinnerID == nil {
+			continue
+		}
+		if match.Round == rounds {
+			places[0] = append(places[0], *match.WinnerID)
+		}
+		for _, userID := range []*int{match.User1ID, match.User2ID} {
+			if userID != nil && *userID != *match.WinnerID {
+				place := rounds - match.Round + 1
+				places[place] = append(places[place], *userID)
+			}
+		}
+	}
+
+	prizes := make(map[int]int)
+	for place, share := range s.cfg.Gunfight.Tournament.Prizes {
+		if len(places[place]) == 0 {
+			continue
+		}
+		prize := pot * share / 100 / len(places[place])
+		if prize == 0 {
+			continue
+		}
+		for _, userID := range places[place] {
+			prizes[userID] = prize
+		}
+	}
+	return prizes
+}
+
+// bracketSeeds возвращает посев игрока для каждой позиции первого раунда сетки на size игроков:
+// соседние позиции — пары первого раунда, а сильнейшие посевы встречаются как можно позже
+func bracketSeeds(size int) []int {
+	seeds := []int{1, 2}
+	for len(seeds) < size {
+		next := make([]int, 0, 2*len(seeds))
+		for _, seed := range seeds {
+			next = append(next, seed, 2*len(seeds)+1-seed)
+		}
+		seeds = next
+	}
+	return seeds
+}
+
+// seededPlayer возвращает игрока с посевом seed, nil — если игроков меньше, чем мест в сетке
+func seededPlayer(players []gunfight.TournamentPlayer, seed int) *int {
+	if seed > len(players) {
+		return nil
+	}
+	return &players[seed-1].UserID
+}
+
+// tournamentMatch создает матч сетки: без соперника игрок проходит дальше технической победой,
+// матч без игроков сразу решен и дальше не проходит никто
+func tournamentMatch(tournamentID, round, position int, user1ID, user2ID *int) gunfight.TournamentMatch {
+	match := gunfight.TournamentMatch{
+		TournamentID: tournamentID,
+		Round:        round,
+		Position:     position,
+		User1ID:      user1ID,
+		User2ID:      user2ID,
+	}
+	if user1ID == nil || user2ID == nil {
+		match.Walkover, match.Decided = true, true
+		match.WinnerID = cmp.Or(user1ID, user2ID)
+	}
+	return match
+}
+
+func tournamentItem(tournament gunfight.Tournament) gunfight.TournamentItem {
+	return gunfight.TournamentItem{
+		ID:        tournament.ID,
+		Size:      tournament.Size,
+		EntryFee:  tournament.EntryFee,
+		Status:    tournament.Status,
+		Players:   tournament.Players,
+		Pot:       tournament.EntryFee * tournament.Players,
+		Round:     tournament.Round,
+		WinnerID:  tournament.WinnerID,
+		StartDate: tournament.StartDate,
+		EndDate:   tournament.EndDate,
+	}
+}
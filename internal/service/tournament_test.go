@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+	"wildwest/internal/model/gunfight"
+	"wildwest/pkg/settings"
+)
+
+func TestBracketSeeds(t *testing.T) {
+	if seeds := bracketSeeds(8); !slices.Equal(seeds, []int{1, 8, 4, 5, 2, 7, 3, 6}) {
+		t.Fatalf("bracketSeeds(8) = %v", seeds)
+	}
+
+	for _, size := range []int{8, 16, 32} {
+		seeds := bracketSeeds(size)
+		sorted := slices.Clone(seeds)
+		slices.Sort(sorted)
+		if len(seeds) != size || sorted[0] != 1 || sorted[size-1] != size || len(slices.Compact(sorted)) != size {
+			t.Fatalf("bracketSeeds(%d) = %v, want every seed from 1 to %d once", size, seeds, size)
+		}
+
+		// В первом раунде сильнейший посев играет со слабейшим
+		for i := 0; i < size; i += 2 {
+			if seeds[i]+seeds[i+1] != size+1 {
+				t.Fatalf("bracketSeeds(%d): pair %d-%d, want seeds summing to %d", size, seeds[i], seeds[i+1], size+1)
+			}
+		}
+
+		// Посевы 1..k попадают в разные части сетки из size/k позиций, поэтому встречаются не раньше,
+		// чем останется k игроков
+		for k := 2; k <= size/2; k *= 2 {
+			parts := make(map[int]bool)
+			for position, seed := range seeds {
+				if seed <= k {
+					parts[position/(size/k)] = true
+				}
+			}
+			if len(parts) != k {
+				t.Fatalf("bracketSeeds(%d) = %v: top %d seeds share a part of the bracket", size, seeds, k)
+			}
+		}
+	}
+}
+
+// testBracket — сетка на 8 игроков, где побеждают посевы 1 и 2, а проигрывают в полуфинале 3 и 4
+func testBracket() []gunfight.TournamentMatch {
+	match := func(round, user1ID, user2ID int) gunfight.TournamentMatch {
+		return gunfight.TournamentMatch{Round: round, User1ID: &user1ID, User2ID: &user2ID, WinnerID: &user1ID, Decided: true}
+	}
+	return []gunfight.TournamentMatch{
+		match(1, 1, 8), match(1, 4, 5), match(1, 2, 7), match(1, 3, 6),
+		match(2, 1, 4), match(2, 2, 3),
+		match(3, 1, 2),
+	}
+}
+
+func TestTournamentPrizes(t *testing.T) {
+	tests := []struct {
+		name     string
+		entryFee int
+		houseFee int
+		prizes   []int
+		want     map[int]int
+	}{
+		{
+			// Банк 800 - 10% = 720, доли 50/30/10 в сумме дают 90%, оставшиеся 72 остаются заведению
+			name:     "shares below 100 percent and the fee",
+			entryFee: 100,
+			houseFee: 10,
+			prizes:   []int{50, 30, 10},
+			want:     map[int]int{1: 360, 2: 216, 3: 36, 4: 36},
+		},
+		{
+			// Банк 56: 28, 16 из 16.8, 8 из 8.4 на двоих; остаток от деления не раздается
+			name:     "rounding down",
+			entryFee: 7,
+			prizes:   []int{50, 30, 15},
+			want:     map[int]int{1: 28, 2: 16, 3: 4, 4: 4},
+		},
+		{
+			name:     "places without a share get nothing",
+			entryFee: 100,
+			prizes:   []int{100},
+			want:     map[int]int{1: 800},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := &settings.Config{}
+			cfg.Gunfight.HouseFee = test.houseFee
+			cfg.Gunfight.Tournament.Prizes = test.prizes
+			s := &gunfightService{cfg: cfg}
+
+			tournament := &gunfight.Tournament{Size: 8, EntryFee: test.entryFee}
+			prizes := s.tournamentPrizes(tournament, 8, testBracket(), 3)
+			if !maps.Equal(prizes, test.want) {
+				t.Fatalf("prizes = %v, want %v", prizes, test.want)
+			}
+
+			paid := 0
+			for _, prize := range prizes {
+				paid += prize
+			}
+			if pot := test.entryFee * 8; paid > pot-pot*test.houseFee/100 {
+				t.Fatalf("paid %d out of a pot of %d", paid, pot)
+			}
+		})
+	}
+}
+
+func TestDecideTournamentMatch(t *testing.T) {
+	ended := time.Now()
+	gunfightID, winnerID := 7, 1
+	seeds := map[int]int{1: 2, 2: 1}
+
+	tests := []struct {
+		name     string
+		game     gunfight.Game
+		want     decidedMatch
+		winnerID int
+	}{
+		{
+			// Никто не сел за дуэль: ставки не закрылись, победителя нет
+			name: "double no-show",
+			game: gunfight.Game{EndDate: &ended},
+			want: decidedMatch{matchID: 3, walkover: true},
+		},
+		{
+			name:     "draw goes to the higher seed",
+			game:     gunfight.Game{EndDate: &ended, BetsClosed: true},
+			want:     decidedMatch{matchID: 3},
+			winnerID: 2,
+		},
+		{
+			name:     "winner",
+			game:     gunfight.Game{EndDate: &ended, BetsClosed: true, WinnerID: &winnerID},
+			want:     decidedMatch{matchID: 3},
+			winnerID: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			game := test.game
+			game.ID, game.User1ID, game.User2ID = gunfightID, 1, 2
+			repo := &fakeGunfightRepo{game: &game}
+			s := &gunfightService{gunfightRepo: repo, cfg: &settings.Config{}}
+
+			match := &gunfight.TournamentMatch{ID: 3, GunfightID: &gunfightID}
+			if err := s.decideTournamentMatch(context.Background(), match, seeds); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(repo.decided) != 1 {
+				t.Fatalf("decided %d times, want once", len(repo.decided))
+			}
+			decided := repo.decided[0]
+			if decided.matchID != test.want.matchID || decided.walkover != test.want.walkover {
+				t.Fatalf("decided = %+v, want %+v", decided, test.want)
+			}
+			if test.winnerID == 0 {
+				if decided.winnerID != nil || match.WinnerID != nil {
+					t.Fatalf("winner = %v, match winner = %v, want nobody to advance", decided.winnerID, match.WinnerID)
+				}
+			} else if decided.winnerID == nil || *decided.winnerID != test.winnerID || match.WinnerID == nil || *match.WinnerID != test.winnerID {
+				t.Fatalf("winner = %v, want %d", decided.winnerID, test.winnerID)
+			}
+			if !match.Decided || match.Walkover != test.want.walkover {
+				t.Fatalf("match = %+v, want it decided", match)
+			}
+		})
+	}
+}
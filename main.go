@@ -1,11 +1,13 @@
 package main
 
 import (
+	"context"
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
 	httpSwagger "github.com/swaggo/http-swagger"
 	"log"
 	"net/http"
+	"time"
 	_ "wildwest/docs" // Этот импорт необходим для работы Swaggo
 	"wildwest/internal/handler"
 	"wildwest/internal/repository/postgres"
@@ -57,23 +59,27 @@ func main() {
 	apiRouter := r.PathPrefix("/api/v1").Subrouter()
 	apiRouter.Use(corsHandler.Handler)
 
+	userRepo := postgres.NewUserRepository(postgresClient)
+	moneyRepo := postgres.NewMoneyRepository(postgresClient)
+
 	gunfightRedis := redis.NewGunfightRedis(redisClient)
 	gunfightPostgres := postgres.NewGunfightRepository(postgresClient)
-	gunfightService := service.NewGunfightService(gunfightPostgres, gunfightRedis)
+	gunfightService := service.NewGunfightService(gunfightPostgres, gunfightRedis, userRepo, moneyRepo, &config)
 	gunfightHandler := handler.NewGunfightHandler(gunfightService, logger)
+	go sweepStaleGunfights(gunfightService, logger, config.Gunfight.SweepInterval)
+	go runTournaments(gunfightService, logger, config.Gunfight.Tournament.Tick)
 	router.NewGunfightRouter(apiRouter, gunfightHandler, &config)
 
 	horseRepo := postgres.NewHorseRepository(postgresClient)
-	horseService := service.NewHorseService(horseRepo)
+	horseRedis := redis.NewHorseRedis(redisClient)
+	horseService := service.NewHorseService(horseRepo, horseRedis, &config)
 	horseHandler := handler.NewHorseHandler(horseService, logger)
 	router.NewHorseRouter(apiRouter, horseHandler, &config)
 
-	moneyRepo := postgres.NewMoneyRepository(postgresClient)
 	moneyService := service.NewMoneyService(moneyRepo)
 	moneyHandler := handler.NewMoneyHandler(moneyService, logger)
 	router.NewMoneyRouter(apiRouter, moneyHandler, &config)
 
-	userRepo := postgres.NewUserRepository(postgresClient)
 	userService := service.NewUserService(userRepo)
 	userHandler := handler.NewUserHandler(userService, logger)
 	router.NewUserRouter(apiRouter, userHandler, &config)
@@ -84,3 +90,37 @@ func main() {
 
 	log.Fatal(http.ListenAndServe(config.API.Port, r))
 }
+
+// sweepStaleGunfights периодически закрывает зависшие игры и возвращает золото истекших наград
+func sweepStaleGunfights(gunfightService service.GunfightService, logger logging.Logger, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		closed, err := gunfightService.SweepStaleGames(context.Background())
+		if err != nil {
+			logger.Error("Error sweeping stale gunfights: ", err)
+		} else if closed > 0 {
+			logger.Infof("Closed %d stale gunfights", closed)
+		}
+
+		expired, err := gunfightService.ExpireBounties(context.Background())
+		if err != nil {
+			logger.Error("Error expiring bounties: ", err)
+		} else if expired > 0 {
+			logger.Infof("Refunded %d expired bounties", expired)
+		}
+	}
+}
+
+// runTournaments периодически создает турниры по расписанию и переводит идущие турниры по раундам
+func runTournaments(gunfightService service.GunfightService, logger logging.Logger, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := gunfightService.AdvanceTournaments(context.Background()); err != nil {
+			logger.Error("Error advancing tournaments: ", err)
+		}
+	}
+}
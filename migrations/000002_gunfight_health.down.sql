@@ -0,0 +1,4 @@
+ALTER TABLE gunfight_health DROP CONSTRAINT gunfight_health_gunfight_user_unique;
+ALTER TABLE gunfight_health ALTER COLUMN gunfight_id DROP NOT NULL;
+ALTER TABLE gunfight_health ADD CONSTRAINT gunfight_health_user_id_unique UNIQUE (user_id);
+ALTER TABLE gunfight_health ADD CONSTRAINT gunfight_health_gunfight_id_unique UNIQUE (gunfight_id);
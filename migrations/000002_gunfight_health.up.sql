@@ -0,0 +1,4 @@
+ALTER TABLE gunfight_health DROP CONSTRAINT gunfight_health_gunfight_id_unique;
+ALTER TABLE gunfight_health DROP CONSTRAINT gunfight_health_user_id_unique;
+ALTER TABLE gunfight_health ALTER COLUMN gunfight_id SET NOT NULL;
+ALTER TABLE gunfight_health ADD CONSTRAINT gunfight_health_gunfight_user_unique UNIQUE (gunfight_id, user_id);
@@ -0,0 +1,3 @@
+DROP TABLE gunfight_escrow;
+ALTER TABLE money DROP CONSTRAINT chk_money_gold;
+ALTER TABLE gunfight DROP COLUMN stake;
@@ -0,0 +1,15 @@
+ALTER TABLE gunfight ADD COLUMN stake INT NOT NULL DEFAULT 0;
+
+ALTER TABLE money ADD CONSTRAINT chk_money_gold CHECK (gold >= 0);
+
+CREATE TABLE gunfight_escrow (
+  id SERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL,
+  gunfight_id INT,
+  gold INT NOT NULL,
+  CONSTRAINT gunfight_escrow_user_id_fk FOREIGN KEY (user_id) REFERENCES users(id),
+  CONSTRAINT gunfight_escrow_gunfight_id_fk FOREIGN KEY (gunfight_id) REFERENCES gunfight(id),
+  CONSTRAINT chk_gunfight_escrow_gold CHECK (gold > 0)
+);
+
+CREATE UNIQUE INDEX gunfight_escrow_queued_user_unique ON gunfight_escrow (user_id) WHERE gunfight_id IS NULL;
@@ -0,0 +1,7 @@
+CREATE TABLE gunfight_rating (
+  user_id BIGINT NOT NULL,
+  rating INT NOT NULL DEFAULT 1000,
+  games INT NOT NULL DEFAULT 0,
+  CONSTRAINT pk_gunfight_rating PRIMARY KEY (user_id),
+  CONSTRAINT gunfight_rating_user_id_fk FOREIGN KEY (user_id) REFERENCES users(id)
+);
@@ -0,0 +1,2 @@
+DROP INDEX gunfight_user_2_id_idx;
+DROP INDEX gunfight_user_1_id_idx;
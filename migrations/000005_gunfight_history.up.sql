@@ -0,0 +1,2 @@
+CREATE INDEX gunfight_user_1_id_idx ON gunfight (user_1_id, id DESC);
+CREATE INDEX gunfight_user_2_id_idx ON gunfight (user_2_id, id DESC);
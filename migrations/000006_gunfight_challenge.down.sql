@@ -0,0 +1 @@
+ALTER TABLE gunfight DROP COLUMN ranked;
@@ -0,0 +1,2 @@
+ALTER TABLE gunfight ADD COLUMN ranked BOOLEAN NOT NULL DEFAULT FALSE;
+UPDATE gunfight SET ranked = TRUE;
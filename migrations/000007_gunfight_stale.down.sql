@@ -0,0 +1 @@
+DROP INDEX gunfight_unfinished_idx;
@@ -0,0 +1 @@
+CREATE INDEX gunfight_unfinished_idx ON gunfight (start_date) WHERE end_date IS NULL;
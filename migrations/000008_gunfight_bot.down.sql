@@ -0,0 +1,4 @@
+DELETE FROM gunfight_health WHERE user_id < 0;
+DELETE FROM gunfight WHERE user_2_id < 0;
+DELETE FROM users WHERE id < 0;
+ALTER TABLE gunfight DROP COLUMN bot;
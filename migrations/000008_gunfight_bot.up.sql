@@ -0,0 +1,7 @@
+ALTER TABLE gunfight ADD COLUMN bot VARCHAR(32) NOT NULL DEFAULT '';
+
+-- Боты играют от имени служебных пользователей с отрицательными id, которые не пересекаются с id Telegram
+INSERT INTO users (id, username, first_name) VALUES
+  (-1, 'greenhorn', 'Greenhorn'),
+  (-2, 'deputy', 'Deputy'),
+  (-3, 'outlaw', 'Outlaw');
@@ -0,0 +1,2 @@
+DROP TABLE gunfight_events;
+DROP FUNCTION gunfight_events_append_only;
@@ -0,0 +1,26 @@
+CREATE TABLE gunfight_events (
+  id BIGSERIAL PRIMARY KEY,
+  gunfight_id INT NOT NULL,
+  seq BIGINT NOT NULL DEFAULT 0,
+  type VARCHAR(32) NOT NULL,
+  user_id BIGINT,
+  round INT NOT NULL DEFAULT 0,
+  rtt BIGINT NOT NULL DEFAULT 0,
+  payload JSONB,
+  created_at TIMESTAMP(6) NOT NULL,
+  CONSTRAINT gunfight_events_gunfight_id_fk FOREIGN KEY (gunfight_id) REFERENCES gunfight(id),
+  CONSTRAINT gunfight_events_user_id_fk FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE INDEX gunfight_events_gunfight_id_idx ON gunfight_events (gunfight_id, id);
+
+-- Журнал дуэлей только дополняется: изменить или удалить запись нельзя
+CREATE FUNCTION gunfight_events_append_only() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'gunfight_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER gunfight_events_append_only
+  BEFORE UPDATE OR DELETE ON gunfight_events
+  FOR EACH ROW EXECUTE FUNCTION gunfight_events_append_only();
@@ -0,0 +1,3 @@
+DROP INDEX gunfight_rematch_of_idx;
+ALTER TABLE gunfight DROP CONSTRAINT gunfight_rematch_of_fk;
+ALTER TABLE gunfight DROP COLUMN rematch_of;
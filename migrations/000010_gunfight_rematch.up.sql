@@ -0,0 +1,5 @@
+ALTER TABLE gunfight ADD COLUMN rematch_of INT;
+ALTER TABLE gunfight ADD CONSTRAINT gunfight_rematch_of_fk FOREIGN KEY (rematch_of) REFERENCES gunfight(id);
+
+-- У игры может быть только один реванш, так цепочки реваншей не ветвятся
+CREATE UNIQUE INDEX gunfight_rematch_of_idx ON gunfight (rematch_of) WHERE rematch_of IS NOT NULL;
@@ -0,0 +1,4 @@
+ALTER TABLE gunfight ADD COLUMN queue VARCHAR(32) NOT NULL DEFAULT '';
+
+-- До появления очередей все игры со ставкой шли через рейтинговый поиск
+UPDATE gunfight SET queue = 'ranked' WHERE ranked;
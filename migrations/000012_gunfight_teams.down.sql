@@ -0,0 +1,2 @@
+DROP TABLE gunfight_players;
+ALTER TABLE gunfight DROP COLUMN team_size;
@@ -0,0 +1,19 @@
+ALTER TABLE gunfight ADD COLUMN team_size SMALLINT NOT NULL DEFAULT 1;
+
+-- Состав команд игры. Капитаны команд записаны в user_1_id и user_2_id, в дуэли один на один команда — сам игрок
+CREATE TABLE gunfight_players (
+  gunfight_id INT NOT NULL,
+  user_id BIGINT NOT NULL,
+  team SMALLINT NOT NULL,
+  CONSTRAINT pk_gunfight_players PRIMARY KEY (gunfight_id, user_id),
+  CONSTRAINT gunfight_players_gunfight_id_fk FOREIGN KEY (gunfight_id) REFERENCES gunfight(id),
+  CONSTRAINT gunfight_players_user_id_fk FOREIGN KEY (user_id) REFERENCES users(id),
+  CONSTRAINT chk_gunfight_players_team CHECK (team IN (1, 2))
+);
+
+CREATE INDEX gunfight_players_user_id_idx ON gunfight_players (user_id, gunfight_id);
+
+INSERT INTO gunfight_players (gunfight_id, user_id, team)
+SELECT id, user_1_id, 1 FROM gunfight
+UNION ALL
+SELECT id, user_2_id, 2 FROM gunfight;
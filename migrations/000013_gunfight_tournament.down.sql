@@ -0,0 +1,3 @@
+DROP TABLE gunfight_tournament_matches;
+DROP TABLE gunfight_tournament_players;
+DROP TABLE gunfight_tournament;
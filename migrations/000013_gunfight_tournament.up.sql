@@ -0,0 +1,51 @@
+CREATE TABLE gunfight_tournament (
+  id SERIAL PRIMARY KEY,
+  size SMALLINT NOT NULL,
+  entry_fee INT NOT NULL DEFAULT 0,
+  status VARCHAR(16) NOT NULL DEFAULT 'registration',
+  round SMALLINT NOT NULL DEFAULT 0,
+  winner_id BIGINT,
+  start_date TIMESTAMP NOT NULL,
+  end_date TIMESTAMP,
+  CONSTRAINT gunfight_tournament_winner_id_fk FOREIGN KEY (winner_id) REFERENCES users(id),
+  CONSTRAINT chk_gunfight_tournament_size CHECK (size IN (8, 16, 32)),
+  CONSTRAINT chk_gunfight_tournament_status CHECK (status IN ('registration', 'running', 'finished', 'cancelled'))
+);
+
+-- Планировщик создает не больше одного турнира на время старта, даже если инстансов несколько
+CREATE UNIQUE INDEX gunfight_tournament_start_date_idx ON gunfight_tournament (start_date);
+CREATE INDEX gunfight_tournament_status_idx ON gunfight_tournament (status, start_date);
+
+-- Участники турнира: взнос списывается при регистрации, посев по рейтингу назначается на старте
+CREATE TABLE gunfight_tournament_players (
+  tournament_id INT NOT NULL,
+  user_id BIGINT NOT NULL,
+  seed SMALLINT,
+  rating INT,
+  prize INT NOT NULL DEFAULT 0,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  CONSTRAINT pk_gunfight_tournament_players PRIMARY KEY (tournament_id, user_id),
+  CONSTRAINT gunfight_tournament_players_tournament_id_fk FOREIGN KEY (tournament_id) REFERENCES gunfight_tournament(id),
+  CONSTRAINT gunfight_tournament_players_user_id_fk FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+-- Матчи сетки: position — номер матча в раунде, победители матчей 2k и 2k+1 встречаются в матче k следующего раунда.
+-- Матч без игры — техническая победа единственного игрока или пустой матч, если игроков нет
+CREATE TABLE gunfight_tournament_matches (
+  id SERIAL PRIMARY KEY,
+  tournament_id INT NOT NULL,
+  round SMALLINT NOT NULL,
+  position SMALLINT NOT NULL,
+  user_1_id BIGINT,
+  user_2_id BIGINT,
+  gunfight_id INT,
+  winner_id BIGINT,
+  walkover BOOLEAN NOT NULL DEFAULT FALSE,
+  decided BOOLEAN NOT NULL DEFAULT FALSE,
+  CONSTRAINT gunfight_tournament_matches_tournament_id_fk FOREIGN KEY (tournament_id) REFERENCES gunfight_tournament(id),
+  CONSTRAINT gunfight_tournament_matches_user_1_id_fk FOREIGN KEY (user_1_id) REFERENCES users(id),
+  CONSTRAINT gunfight_tournament_matches_user_2_id_fk FOREIGN KEY (user_2_id) REFERENCES users(id),
+  CONSTRAINT gunfight_tournament_matches_gunfight_id_fk FOREIGN KEY (gunfight_id) REFERENCES gunfight(id),
+  CONSTRAINT gunfight_tournament_matches_winner_id_fk FOREIGN KEY (winner_id) REFERENCES users(id),
+  CONSTRAINT gunfight_tournament_matches_position_unique UNIQUE (tournament_id, round, position)
+);
@@ -0,0 +1,4 @@
+ALTER TABLE gunfight_players DROP COLUMN client_seed;
+
+ALTER TABLE gunfight DROP COLUMN seed_hash;
+ALTER TABLE gunfight DROP COLUMN server_seed;
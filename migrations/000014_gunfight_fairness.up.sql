@@ -0,0 +1,7 @@
+-- Сид игры: seed_hash публикуется с момента создания игры, server_seed раскрывается после ее окончания.
+-- У игр до проверяемых розыгрышей сида нет
+ALTER TABLE gunfight ADD COLUMN server_seed VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE gunfight ADD COLUMN seed_hash VARCHAR(64) NOT NULL DEFAULT '';
+
+-- Сид игрока, который он передал при входе в дуэль; записывается, когда все игроки сели за дуэль
+ALTER TABLE gunfight_players ADD COLUMN client_seed VARCHAR(64) NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+DROP TABLE gunfight_bounty;
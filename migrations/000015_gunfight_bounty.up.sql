@@ -0,0 +1,25 @@
+-- Награда за игрока: золото списывается с заказчика при объявлении и хранится в награде,
+-- пока ее не заберет победитель цели или она не истечет
+CREATE TABLE gunfight_bounty (
+  id SERIAL PRIMARY KEY,
+  poster_id BIGINT NOT NULL,
+  target_id BIGINT NOT NULL,
+  gold INT NOT NULL,
+  status VARCHAR(16) NOT NULL DEFAULT 'open',
+  hunter_id BIGINT,
+  gunfight_id INT,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  expires_at TIMESTAMP NOT NULL,
+  end_date TIMESTAMP,
+  CONSTRAINT gunfight_bounty_poster_id_fk FOREIGN KEY (poster_id) REFERENCES users(id),
+  CONSTRAINT gunfight_bounty_target_id_fk FOREIGN KEY (target_id) REFERENCES users(id),
+  CONSTRAINT gunfight_bounty_hunter_id_fk FOREIGN KEY (hunter_id) REFERENCES users(id),
+  CONSTRAINT gunfight_bounty_gunfight_id_fk FOREIGN KEY (gunfight_id) REFERENCES gunfight(id),
+  CONSTRAINT chk_gunfight_bounty_gold CHECK (gold > 0),
+  CONSTRAINT chk_gunfight_bounty_target CHECK (target_id <> poster_id),
+  CONSTRAINT chk_gunfight_bounty_status CHECK (status IN ('open', 'claimed', 'expired'))
+);
+
+CREATE INDEX gunfight_bounty_target_id_idx ON gunfight_bounty (target_id) WHERE status = 'open';
+CREATE INDEX gunfight_bounty_gold_idx ON gunfight_bounty (gold DESC, id) WHERE status = 'open';
+CREATE INDEX gunfight_bounty_expires_at_idx ON gunfight_bounty (expires_at) WHERE status = 'open';
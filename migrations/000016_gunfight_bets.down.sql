@@ -0,0 +1,3 @@
+DROP TABLE gunfight_bets;
+
+ALTER TABLE gunfight DROP COLUMN bets_closed;
@@ -0,0 +1,26 @@
+-- Прием ставок закрывается перед первым раундом дуэли
+ALTER TABLE gunfight ADD COLUMN bets_closed BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Ставки зрителей в серебре на сторону дуэли: side — игрок или капитан команды. Серебро списывается при ставке,
+-- выплата записывается, когда у игры появляется победитель, без победителя ставки возвращаются
+CREATE TABLE gunfight_bets (
+  id SERIAL PRIMARY KEY,
+  gunfight_id INT NOT NULL,
+  user_id BIGINT NOT NULL,
+  side BIGINT NOT NULL,
+  silver INT NOT NULL,
+  status VARCHAR(16) NOT NULL DEFAULT 'open',
+  payout INT NOT NULL DEFAULT 0,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  settled_at TIMESTAMP,
+  CONSTRAINT gunfight_bets_gunfight_id_fk FOREIGN KEY (gunfight_id) REFERENCES gunfight(id),
+  CONSTRAINT gunfight_bets_user_id_fk FOREIGN KEY (user_id) REFERENCES users(id),
+  CONSTRAINT gunfight_bets_gunfight_user_unique UNIQUE (gunfight_id, user_id),
+  CONSTRAINT chk_gunfight_bets_silver CHECK (silver > 0),
+  CONSTRAINT chk_gunfight_bets_status CHECK (status IN ('open', 'won', 'lost', 'refunded'))
+);
+
+CREATE INDEX gunfight_bets_user_id_idx ON gunfight_bets (user_id, created_at);
+
+-- Игры до ставок зрителей уже закрыты для ставок
+UPDATE gunfight SET bets_closed = TRUE;
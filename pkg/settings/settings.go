@@ -4,9 +4,33 @@ import (
 	"fmt"
 	"github.com/joho/godotenv"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 )
 
+// GunfightQueue — очередь поиска соперника со своими условиями: ставка, рейтинговая ли игра, минимальный
+// баланс для входа, расписание расширения диапазона рейтинга, время ожидания соперника и комиссия заведения
+type GunfightQueue struct {
+	Name       string
+	Stake      int
+	Ranked     bool
+	MinBalance int
+	Bands      []int
+	BandStep   time.Duration
+	Timeout    time.Duration
+	HouseFee   int
+}
+
+// defaultGunfightQueues — настройки очередей по умолчанию, их переопределяют переменные GUNFIGHT_QUEUE_<ИМЯ>_<НАСТРОЙКА>.
+// Незаданные диапазоны, шаг расширения и комиссия берутся из общих настроек дуэлей
+var defaultGunfightQueues = map[string]map[string]string{
+	"casual":      {"STAKE": "0", "RANKED": "false", "BANDS": "100"},
+	"ranked":      {"STAKE": "100", "RANKED": "true"},
+	"high_stakes": {"STAKE": "1000", "RANKED": "true", "MIN_BALANCE": "5000", "TIMEOUT": "120"},
+}
+
 type Config struct {
 	Database struct {
 		Host     string
@@ -29,6 +53,41 @@ type Config struct {
 	Logging struct {
 		Level string
 	}
+	Horse struct {
+		RaceTTL time.Duration
+	}
+	Gunfight struct {
+		Queues         []GunfightQueue
+		HouseFee       int
+		Bands          []int
+		BandStep       time.Duration
+		ChallengeTTL   time.Duration
+		SpectatorLimit int
+		SpectatorDelay time.Duration
+		ReconnectGrace time.Duration
+		CooldownBase   time.Duration
+		CooldownMax    time.Duration
+		StaleAfter     time.Duration
+		SweepInterval  time.Duration
+		BotWait        time.Duration
+		RematchWindow  time.Duration
+		PartyInviteTTL time.Duration
+		BountyTTL      time.Duration
+		BountyMin      int
+		Bets           struct {
+			HouseFee   int
+			Min        int
+			Max        int
+			DailyLimit int
+		}
+		Tournament struct {
+			Interval time.Duration
+			Size     int
+			EntryFee int
+			Prizes   []int
+			Tick     time.Duration
+		}
+	}
 }
 
 func (c *Config) ReadConfig() error {
@@ -60,5 +119,244 @@ func (c *Config) ReadConfig() error {
 
 	c.Logging.Level = os.Getenv("LOG_LEVEL")
 
+	// Сколько живет сессия забега: дистанция забега засчитывается не больше, чем лошадь пробежит за это время
+	raceTTL, err := strconv.Atoi(getEnv("HORSE_RACE_TTL", "600"))
+	if err != nil || raceTTL <= 0 {
+		return fmt.Errorf("invalid HORSE_RACE_TTL: %v", err)
+	}
+	c.Horse.RaceTTL = time.Duration(raceTTL) * time.Second
+
+	c.Gunfight.HouseFee, err = strconv.Atoi(getEnv("GUNFIGHT_HOUSE_FEE", "5"))
+	if err != nil || c.Gunfight.HouseFee < 0 || c.Gunfight.HouseFee > 100 {
+		return fmt.Errorf("invalid GUNFIGHT_HOUSE_FEE: %v", err)
+	}
+
+	c.Gunfight.Bands, err = parseIntList(getEnv("GUNFIGHT_BANDS", "5,15,30"))
+	if err != nil {
+		return fmt.Errorf("invalid GUNFIGHT_BANDS: %v", err)
+	}
+	bandStep, err := strconv.Atoi(getEnv("GUNFIGHT_BAND_STEP", "15"))
+	if err != nil || bandStep <= 0 {
+		return fmt.Errorf("invalid GUNFIGHT_BAND_STEP: %v", err)
+	}
+	c.Gunfight.BandStep = time.Duration(bandStep) * time.Second
+
+	for _, name := range strings.Split(getEnv("GUNFIGHT_QUEUES", "casual,ranked,high_stakes"), ",") {
+		queue, err := readGunfightQueue(strings.TrimSpace(name), c.Gunfight.Bands, c.Gunfight.BandStep, c.Gunfight.HouseFee)
+		if err != nil {
+			return err
+		}
+		if slices.ContainsFunc(c.Gunfight.Queues, func(q GunfightQueue) bool { return q.Name == queue.Name }) {
+			return fmt.Errorf("invalid GUNFIGHT_QUEUES: duplicate queue %s", queue.Name)
+		}
+		c.Gunfight.Queues = append(c.Gunfight.Queues, queue)
+	}
+
+	challengeTTL, err := strconv.Atoi(getEnv("GUNFIGHT_CHALLENGE_TTL", "60"))
+	if err != nil || challengeTTL <= 0 {
+		return fmt.Errorf("invalid GUNFIGHT_CHALLENGE_TTL: %v", err)
+	}
+	c.Gunfight.ChallengeTTL = time.Duration(challengeTTL) * time.Second
+
+	c.Gunfight.SpectatorLimit, err = strconv.Atoi(getEnv("GUNFIGHT_SPECTATOR_LIMIT", "50"))
+	if err != nil || c.Gunfight.SpectatorLimit < 0 {
+		return fmt.Errorf("invalid GUNFIGHT_SPECTATOR_LIMIT: %v", err)
+	}
+	spectatorDelay, err := strconv.Atoi(getEnv("GUNFIGHT_SPECTATOR_DELAY", "2"))
+	if err != nil || spectatorDelay < 0 {
+		return fmt.Errorf("invalid GUNFIGHT_SPECTATOR_DELAY: %v", err)
+	}
+	c.Gunfight.SpectatorDelay = time.Duration(spectatorDelay) * time.Second
+
+	reconnectGrace, err := strconv.Atoi(getEnv("GUNFIGHT_RECONNECT_GRACE", "30"))
+	if err != nil || reconnectGrace <= 0 {
+		return fmt.Errorf("invalid GUNFIGHT_RECONNECT_GRACE: %v", err)
+	}
+	c.Gunfight.ReconnectGrace = time.Duration(reconnectGrace) * time.Second
+
+	cooldownBase, err := strconv.Atoi(getEnv("GUNFIGHT_COOLDOWN_BASE", "60"))
+	if err != nil || cooldownBase <= 0 {
+		return fmt.Errorf("invalid GUNFIGHT_COOLDOWN_BASE: %v", err)
+	}
+	c.Gunfight.CooldownBase = time.Duration(cooldownBase) * time.Second
+	cooldownMax, err := strconv.Atoi(getEnv("GUNFIGHT_COOLDOWN_MAX", "1800"))
+	if err != nil || cooldownMax < cooldownBase {
+		return fmt.Errorf("invalid GUNFIGHT_COOLDOWN_MAX: %v", err)
+	}
+	c.Gunfight.CooldownMax = time.Duration(cooldownMax) * time.Second
+
+	staleAfter, err := strconv.Atoi(getEnv("GUNFIGHT_STALE_AFTER", "1800"))
+	if err != nil || staleAfter <= 0 {
+		return fmt.Errorf("invalid GUNFIGHT_STALE_AFTER: %v", err)
+	}
+	c.Gunfight.StaleAfter = time.Duration(staleAfter) * time.Second
+	sweepInterval, err := strconv.Atoi(getEnv("GUNFIGHT_SWEEP_INTERVAL", "300"))
+	if err != nil || sweepInterval <= 0 {
+		return fmt.Errorf("invalid GUNFIGHT_SWEEP_INTERVAL: %v", err)
+	}
+	c.Gunfight.SweepInterval = time.Duration(sweepInterval) * time.Second
+
+	// 0 выключает ботов
+	botWait, err := strconv.Atoi(getEnv("GUNFIGHT_BOT_WAIT", "0"))
+	if err != nil || botWait < 0 {
+		return fmt.Errorf("invalid GUNFIGHT_BOT_WAIT: %v", err)
+	}
+	c.Gunfight.BotWait = time.Duration(botWait) * time.Second
+
+	rematchWindow, err := strconv.Atoi(getEnv("GUNFIGHT_REMATCH_WINDOW", "30"))
+	if err != nil || rematchWindow <= 0 {
+		return fmt.Errorf("invalid GUNFIGHT_REMATCH_WINDOW: %v", err)
+	}
+	c.Gunfight.RematchWindow = time.Duration(rematchWindow) * time.Second
+
+	partyInviteTTL, err := strconv.Atoi(getEnv("GUNFIGHT_PARTY_INVITE_TTL", "120"))
+	if err != nil || partyInviteTTL <= 0 {
+		return fmt.Errorf("invalid GUNFIGHT_PARTY_INVITE_TTL: %v", err)
+	}
+	c.Gunfight.PartyInviteTTL = time.Duration(partyInviteTTL) * time.Second
+
+	bountyTTL, err := strconv.Atoi(getEnv("GUNFIGHT_BOUNTY_TTL", "86400"))
+	if err != nil || bountyTTL <= 0 {
+		return fmt.Errorf("invalid GUNFIGHT_BOUNTY_TTL: %v", err)
+	}
+	c.Gunfight.BountyTTL = time.Duration(bountyTTL) * time.Second
+
+	c.Gunfight.BountyMin, err = strconv.Atoi(getEnv("GUNFIGHT_BOUNTY_MIN", "10"))
+	if err != nil || c.Gunfight.BountyMin <= 0 {
+		return fmt.Errorf("invalid GUNFIGHT_BOUNTY_MIN: %v", err)
+	}
+
+	// Ставки зрителей в серебре: комиссия заведения с общего банка в процентах, пределы одной ставки
+	// и сумма ставок игрока за сутки
+	c.Gunfight.Bets.HouseFee, err = strconv.Atoi(getEnv("GUNFIGHT_BET_HOUSE_FEE", "5"))
+	if err != nil || c.Gunfight.Bets.HouseFee < 0 || c.Gunfight.Bets.HouseFee >= 100 {
+		return fmt.Errorf("invalid GUNFIGHT_BET_HOUSE_FEE: must be from 0 to 99")
+	}
+	c.Gunfight.Bets.Min, err = strconv.Atoi(getEnv("GUNFIGHT_BET_MIN", "10"))
+	if err != nil || c.Gunfight.Bets.Min <= 0 {
+		return fmt.Errorf("invalid GUNFIGHT_BET_MIN: %v", err)
+	}
+	c.Gunfight.Bets.Max, err = strconv.Atoi(getEnv("GUNFIGHT_BET_MAX", "500"))
+	if err != nil || c.Gunfight.Bets.Max < c.Gunfight.Bets.Min {
+		return fmt.Errorf("invalid GUNFIGHT_BET_MAX: must be at least GUNFIGHT_BET_MIN")
+	}
+	c.Gunfight.Bets.DailyLimit, err = strconv.Atoi(getEnv("GUNFIGHT_BET_DAILY_LIMIT", "2000"))
+	if err != nil || c.Gunfight.Bets.DailyLimit < c.Gunfight.Bets.Max {
+		return fmt.Errorf("invalid GUNFIGHT_BET_DAILY_LIMIT: must be at least GUNFIGHT_BET_MAX")
+	}
+
+	// 0 выключает турниры по расписанию
+	tournamentInterval, err := strconv.Atoi(getEnv("GUNFIGHT_TOURNAMENT_INTERVAL", "21600"))
+	if err != nil || tournamentInterval < 0 {
+		return fmt.Errorf("invalid GUNFIGHT_TOURNAMENT_INTERVAL: %v", err)
+	}
+	c.Gunfight.Tournament.Interval = time.Duration(tournamentInterval) * time.Second
+
+	c.Gunfight.Tournament.Size, err = strconv.Atoi(getEnv("GUNFIGHT_TOURNAMENT_SIZE", "16"))
+	if err != nil || !slices.Contains([]int{8, 16, 32}, c.Gunfight.Tournament.Size) {
+		return fmt.Errorf("invalid GUNFIGHT_TOURNAMENT_SIZE: must be 8, 16 or 32")
+	}
+	c.Gunfight.Tournament.EntryFee, err = strconv.Atoi(getEnv("GUNFIGHT_TOURNAMENT_ENTRY_FEE", "100"))
+	if err != nil || c.Gunfight.Tournament.EntryFee < 0 {
+		return fmt.Errorf("invalid GUNFIGHT_TOURNAMENT_ENTRY_FEE: %v", err)
+	}
+
+	// Доли банка в процентах по местам: победитель, финалист, затем поровну между проигравшими в полуфинале и так далее
+	c.Gunfight.Tournament.Prizes, err = parseIntList(getEnv("GUNFIGHT_TOURNAMENT_PRIZES", "60,25,15"))
+	if err != nil {
+		return fmt.Errorf("invalid GUNFIGHT_TOURNAMENT_PRIZES: %v", err)
+	}
+	prizesTotal := 0
+	for _, prize := range c.Gunfight.Tournament.Prizes {
+		prizesTotal += prize
+	}
+	if prizesTotal > 100 {
+		return fmt.Errorf("invalid GUNFIGHT_TOURNAMENT_PRIZES: shares add up to more than 100")
+	}
+
+	tournamentTick, err := strconv.Atoi(getEnv("GUNFIGHT_TOURNAMENT_TICK", "10"))
+	if err != nil || tournamentTick <= 0 {
+		return fmt.Errorf("invalid GUNFIGHT_TOURNAMENT_TICK: %v", err)
+	}
+	c.Gunfight.Tournament.Tick = time.Duration(tournamentTick) * time.Second
+
 	return nil
 }
+
+// readGunfightQueue читает настройки очереди name, bands, bandStep и houseFee — общие значения по умолчанию
+func readGunfightQueue(name string, bands []int, bandStep time.Duration, houseFee int) (GunfightQueue, error) {
+	queue := GunfightQueue{Name: name, Bands: bands, BandStep: bandStep, HouseFee: houseFee}
+	if name == "" || strings.ContainsFunc(name, func(r rune) bool { return (r < 'a' || r > 'z') && r != '_' }) {
+		return queue, fmt.Errorf("invalid GUNFIGHT_QUEUES: queue name %q must contain only a-z and _", name)
+	}
+
+	prefix := "GUNFIGHT_QUEUE_" + strings.ToUpper(name) + "_"
+	env := func(key, fallback string) string {
+		if value, ok := defaultGunfightQueues[name][key]; ok {
+			fallback = value
+		}
+		return getEnv(prefix+key, fallback)
+	}
+
+	var err error
+	queue.Stake, err = strconv.Atoi(env("STAKE", "0"))
+	if err != nil || queue.Stake < 0 {
+		return queue, fmt.Errorf("invalid %sSTAKE: %v", prefix, err)
+	}
+	queue.Ranked, err = strconv.ParseBool(env("RANKED", "false"))
+	if err != nil {
+		return queue, fmt.Errorf("invalid %sRANKED: %v", prefix, err)
+	}
+	queue.MinBalance, err = strconv.Atoi(env("MIN_BALANCE", "0"))
+	if err != nil || queue.MinBalance < 0 {
+		return queue, fmt.Errorf("invalid %sMIN_BALANCE: %v", prefix, err)
+	}
+
+	if value := env("BANDS", ""); value != "" {
+		queue.Bands, err = parseIntList(value)
+		if err != nil {
+			return queue, fmt.Errorf("invalid %sBANDS: %v", prefix, err)
+		}
+	}
+	if value := env("BAND_STEP", ""); value != "" {
+		step, err := strconv.Atoi(value)
+		if err != nil || step <= 0 {
+			return queue, fmt.Errorf("invalid %sBAND_STEP: %v", prefix, err)
+		}
+		queue.BandStep = time.Duration(step) * time.Second
+	}
+
+	timeout, err := strconv.Atoi(env("TIMEOUT", "60"))
+	if err != nil || timeout <= 0 {
+		return queue, fmt.Errorf("invalid %sTIMEOUT: %v", prefix, err)
+	}
+	queue.Timeout = time.Duration(timeout) * time.Second
+
+	if value := env("HOUSE_FEE", ""); value != "" {
+		queue.HouseFee, err = strconv.Atoi(value)
+		if err != nil || queue.HouseFee < 0 || queue.HouseFee > 100 {
+			return queue, fmt.Errorf("invalid %sHOUSE_FEE: %v", prefix, err)
+		}
+	}
+
+	return queue, nil
+}
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func parseIntList(value string) ([]int, error) {
+	var result []int
+	for _, item := range strings.Split(value, ",") {
+		number, err := strconv.Atoi(strings.TrimSpace(item))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, number)
+	}
+	return result, nil
+}
@@ -0,0 +1,215 @@
+package wsconn
+
+import (
+	"encoding/binary"
+	"github.com/gorilla/websocket"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	writeWait      = 10 * time.Second
+	pongWait       = 60 * time.Second
+	maxMessageSize = 4096
+	sendBufferSize = 32
+
+	// pingPeriod is short so that RTT is measured by several recent pings, not by the last one
+	pingPeriod = 5 * time.Second
+	// rttSamples is the number of recent RTT samples RTT takes the minimum of
+	rttSamples = 5
+)
+
+// Conn wraps a websocket connection: all writes go through a single writer goroutine,
+// the peer is pinged every pingPeriod and dropped if it stays silent for pongWait
+type Conn struct {
+	conn      *websocket.Conn
+	send      chan []byte
+	messages  chan []byte
+	closing   chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+	onClose   func()
+
+	// Every ping carries a random nonce, only a pong that echoes an outstanding nonce is an RTT sample
+	rttMu   sync.Mutex
+	pings   map[uint64]time.Time
+	samples []time.Duration
+}
+
+// New starts the reader and writer goroutines, onClose is called once after the connection is closed
+func New(conn *websocket.Conn, onClose func()) *Conn {
+	c := &Conn{
+		conn:     conn,
+		send:     make(chan []byte, sendBufferSize),
+		messages: make(chan []byte),
+		closing:  make(chan struct{}),
+		done:     make(chan struct{}),
+		onClose:  onClose,
+		pings:    make(map[uint64]time.Time),
+	}
+
+	go c.readLoop()
+	go c.writeLoop()
+
+	return c
+}
+
+// Messages returns messages from the peer, the channel is closed when the peer disappears
+func (c *Conn) Messages() <-chan []byte {
+	return c.messages
+}
+
+// Done is closed when the connection is closed
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+// Send queues a text message, a peer that can not keep up with the queue is disconnected
+func (c *Conn) Send(data []byte) bool {
+	select {
+	case <-c.closing:
+		return false
+	default:
+	}
+
+	select {
+	case c.send <- data:
+		return true
+	case <-c.closing:
+		return false
+	default:
+		c.Close()
+		return false
+	}
+}
+
+// RTT returns the smallest round trip time of the last rttSamples pings, 0 until the first pong arrives.
+// A peer can delay its pongs but can not answer a ping before it is sent, so the minimum is the hardest to inflate
+func (c *Conn) RTT() time.Duration {
+	c.rttMu.Lock()
+	defer c.rttMu.Unlock()
+
+	if len(c.samples) == 0 {
+		return 0
+	}
+	return slices.Min(c.samples)
+}
+
+// Close flushes queued messages and closes the connection
+func (c *Conn) Close() {
+	c.closeOnce.Do(func() { close(c.closing) })
+}
+
+func (c *Conn) readLoop() {
+	defer close(c.messages)
+	defer c.Close()
+
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(data string) error {
+		c.pong([]byte(data))
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+
+		select {
+		case c.messages <- data:
+		case <-c.closing:
+			return
+		}
+	}
+}
+
+func (c *Conn) writeLoop() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.Close()
+		c.conn.Close()
+		if c.onClose != nil {
+			c.onClose()
+		}
+		close(c.done)
+	}()
+
+	// The first ping goes out right away so that RTT is known from the start
+	if err := c.ping(); err != nil {
+		return
+	}
+
+	for {
+		select {
+		case data := <-c.send:
+			if err := c.write(websocket.TextMessage, data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := c.ping(); err != nil {
+				return
+			}
+		case <-c.closing:
+			for {
+				select {
+				case data := <-c.send:
+					if err := c.write(websocket.TextMessage, data); err != nil {
+						return
+					}
+				default:
+					c.write(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+					return
+				}
+			}
+		}
+	}
+}
+
+func (c *Conn) ping() error {
+	nonce := rand.Uint64()
+	now := time.Now()
+
+	c.rttMu.Lock()
+	for sent, at := range c.pings {
+		if now.Sub(at) > pongWait {
+			delete(c.pings, sent)
+		}
+	}
+	c.pings[nonce] = now
+	c.rttMu.Unlock()
+
+	return c.write(websocket.PingMessage, binary.BigEndian.AppendUint64(nil, nonce))
+}
+
+// pong records an RTT sample if the pong answers an outstanding ping, unsolicited and repeated pongs are ignored
+func (c *Conn) pong(data []byte) {
+	if len(data) != 8 {
+		return
+	}
+	nonce := binary.BigEndian.Uint64(data)
+
+	c.rttMu.Lock()
+	defer c.rttMu.Unlock()
+
+	sent, ok := c.pings[nonce]
+	if !ok {
+		return
+	}
+	delete(c.pings, nonce)
+
+	c.samples = append(c.samples, time.Since(sent))
+	if len(c.samples) > rttSamples {
+		c.samples = c.samples[1:]
+	}
+}
+
+func (c *Conn) write(messageType int, data []byte) error {
+	c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return c.conn.WriteMessage(messageType, data)
+}